@@ -1,77 +1,309 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/config"
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/handlers"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/middleware"
-	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/migrate"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/notify"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/ratelimit"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/recurring"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/reminders"
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/storage"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/webhooks"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/worker"
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
+// migrationTimeout bounds how long startup waits for migrations, including waiting for the lock
+const migrationTimeout = 10 * time.Minute
+
+// probePaths are requests from health checks and metrics scrapes, which are not logged or measured
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "healthcheck":
+			os.Exit(healthcheck())
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", err)
+	}
+
+	// Write JSON logs at the configured level
+	logger := logging.New(os.Stdout, cfg.LogLevel)
+	slog.SetDefault(logger)
+
+	// Export traces when enabled; the Mongo client was already instrumented by LoadConfig
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		cfg.Close()
+		fatal("Failed to set up tracing", err)
 	}
-	defer cfg.Close()
+
+	// Stop on SIGTERM from docker or Kubernetes, or on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Apply pending migrations; replicas starting together wait for the one holding the lock
+	if cfg.MigrateOnStartup {
+		if err := migrateOnStartup(ctx, cfg); err != nil {
+			cfg.Close()
+			fatal("Failed to migrate database", err)
+		}
+	}
+
+	// Background workers keep running while requests drain and stop before the database connection is closed
+	workers := worker.NewGroup(context.Background())
 
 	// Create repositories
 	userRepo := repositories.NewUserMongoRepository(cfg.DBClient, cfg.DBName)
+	invitationRepo := repositories.NewInvitationMongoRepository(cfg.DBClient, cfg.DBName)
+	apiKeyRepo := repositories.NewAPIKeyMongoRepository(cfg.DBClient, cfg.DBName)
+	notificationRepo := repositories.NewNotificationMongoRepository(cfg.DBClient, cfg.DBName)
+	notificationPreferencesRepo := repositories.NewNotificationPreferencesMongoRepository(cfg.DBClient, cfg.DBName)
+	eventRepo := repositories.NewEventMongoRepository(cfg.DBClient, cfg.DBName)
+	taskRepo := repositories.NewTaskMongoRepository(cfg.DBClient, cfg.DBName)
+	reminderRepo := repositories.NewReminderMongoRepository(cfg.DBClient, cfg.DBName)
+	webhookSubscriptionRepo := repositories.NewWebhookSubscriptionMongoRepository(cfg.DBClient, cfg.DBName)
+	webhookDeliveryRepo := repositories.NewWebhookDeliveryMongoRepository(cfg.DBClient, cfg.DBName)
+	taskCommentRepo := repositories.NewTaskCommentMongoRepository(cfg.DBClient, cfg.DBName)
+	taskAttachmentRepo := repositories.NewTaskAttachmentMongoRepository(cfg.DBClient, cfg.DBName)
+	taskTemplateRepo := repositories.NewTaskTemplateMongoRepository(cfg.DBClient, cfg.DBName)
+
+	// Task attachments are kept on the local disk
+	attachmentStorage, err := storage.NewLocalStorage(cfg.Attachments.Dir)
+	if err != nil {
+		cfg.Close()
+		fatal("Failed to create attachment storage", err)
+	}
+
+	// Create login throttling
+	var userLimiter, ipLimiter ratelimit.Limiter
+	if cfg.RateLimit.Store == "mongo" {
+		userLimiter = ratelimit.NewMongoLimiter(cfg.DBClient, cfg.DBName, "login_attempts_users", cfg.RateLimit.User)
+		ipLimiter = ratelimit.NewMongoLimiter(cfg.DBClient, cfg.DBName, "login_attempts_ips", cfg.RateLimit.IP)
+	} else {
+		userLimiter = ratelimit.NewMemoryLimiter(cfg.RateLimit.User)
+		ipLimiter = ratelimit.NewMemoryLimiter(cfg.RateLimit.IP)
+	}
+	loginThrottle := ratelimit.NewLoginThrottle(userLimiter, ipLimiter)
+
+	// Changes are fanned out to connected clients in process
+	hub := realtime.NewMemoryHub()
+
+	// Notifications are kept in the inbox and delivered to the channels users choose in the background
+	channels := []notify.Channel{notify.NewWebhookChannel(cfg.Notifications), notify.NewChatChannel(cfg.Notifications)}
+	if cfg.Notifications.SMTP.Enabled() {
+		channels = append(channels, notify.NewEmailChannel(cfg.Notifications))
+	}
+	notifier := notify.NewService(notificationRepo, notificationPreferencesRepo, userRepo, hub, channels...)
+	workers.Go("notifications", notifier.Run)
+
+	// Reminders before events and task due dates
+	if cfg.Reminders.Enabled {
+		scheduler := reminders.NewScheduler(cfg.Reminders, eventRepo, taskRepo, reminderRepo, userRepo, notifier)
+		workers.Go("reminders", scheduler.Run)
+	}
+
+	// Tasks are created from recurring templates ahead of their due dates
+	if cfg.TaskTemplates.Enabled {
+		generator := recurring.NewGenerator(cfg.TaskTemplates, taskTemplateRepo, taskRepo, userRepo, hub, notifier)
+		workers.Go("task-templates", generator.Run)
+	}
+
+	// Outgoing webhooks are recorded as deliveries and sent, retried and dead-lettered in the background
+	webhookService := webhooks.NewService(cfg.Webhooks, webhookSubscriptionRepo, webhookDeliveryRepo)
+	workers.Go("webhooks", webhookService.Run)
 
 	// Create handlers
-	userHandler := handlers.NewUserHandler(userRepo, cfg.JWTSecret)
+	tokenIssuer := auth.NewTokenIssuer(cfg.JWTKeys, cfg.Tokens)
+	userHandler := handlers.NewUserHandler(userRepo, tokenIssuer, loginThrottle)
+	rosterHandler := handlers.NewRosterHandler(userRepo, invitationRepo, cfg.InvitationURL, cfg.PDFFontPath)
+	invitationHandler := handlers.NewInvitationHandler(userRepo, invitationRepo)
+	jwksHandler := handlers.NewJWKSHandler(cfg.JWTKeys)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo)
+	healthHandler := handlers.NewHealthHandler(cfg.DBClient)
+	streamHandler := handlers.NewStreamHandler(hub)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo, notificationPreferencesRepo)
+	webhookHandler := handlers.NewWebhookHandler(webhookSubscriptionRepo, webhookDeliveryRepo, webhookService)
+	taskHandler := handlers.NewTaskHandler(taskRepo, taskCommentRepo, taskAttachmentRepo, userRepo,
+		attachmentStorage, cfg.Attachments.MaxFileSize(), hub, notifier)
+	taskTemplateHandler := handlers.NewTaskTemplateHandler(taskTemplateRepo, userRepo)
 
 	// Create Echo instance
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+
+	// Login throttling keys on the client address, so clients must not be able to set it with headers
+	if e.IPExtractor, err = middleware.ClientIP(cfg.TrustedProxies); err != nil {
+		cfg.Close()
+		fatal("Failed to configure trusted proxies", err)
+	}
 
 	// Middleware
-	e.Use(echomiddleware.Logger())
-	e.Use(echomiddleware.Recover())
+	e.Use(otelecho.Middleware(cfg.Tracing.ServiceName, otelecho.WithSkipper(isProbe)))
+	e.Use(middleware.RequestID())
+	e.Use(middleware.RequestLogger(logger, isProbe))
+	e.Use(middleware.Metrics(isProbe))
+	e.Use(echomiddleware.RecoverWithConfig(echomiddleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			ctx := c.Request().Context()
+			logging.FromContext(ctx).ErrorContext(ctx, "panic recovered", "error", err, "stack", string(stack))
+			return err
+		},
+	}))
 	e.Use(echomiddleware.CORSWithConfig(echomiddleware.CORSConfig{
-		AllowOrigins: []string{"https://trusted-domain.com", "https://another-trusted-domain.com"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
-		AllowHeaders: []string{echo.HeaderAuthorization, echo.HeaderContentType},
+		AllowOrigins:  cfg.CORS.AllowedOrigins,
+		AllowMethods:  []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowHeaders:  []string{echo.HeaderAuthorization, echo.HeaderContentType, echo.HeaderXRequestID},
+		ExposeHeaders: []string{echo.HeaderXRequestID},
 	}))
 
 	// Routes
-	e.GET("/", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, map[string]string{"message": "Welcome to FUTO Marching Dashboard API"})
-	})
-
-	// Auth routes
-	e.POST("/api/auth/register", userHandler.Register)
-	e.POST("/api/auth/login", userHandler.Login)
-
-	// API routes
-	api := e.Group("/api")
-	api.Use(middleware.JWTMiddleware(cfg.JWTSecret))
-
-	// User routes
-	api.GET("/users/me", userHandler.GetMe)
-	
-	// Admin routes
-	admin := api.Group("/admin")
-	admin.Use(middleware.RoleMiddleware(models.AdminRole))
-	
-	admin.GET("/users", userHandler.GetAllUsers)
-	admin.GET("/users/:id", userHandler.GetUser)
-	admin.PUT("/users/:id", userHandler.UpdateUser)
-	admin.DELETE("/users/:id", userHandler.DeleteUser)
+	var oidcHandler *handlers.OIDCHandler
+	if cfg.OIDC.Enabled() {
+		provider, err := auth.NewOIDCProvider(ctx, cfg.OIDC)
+		if err != nil {
+			cfg.Close()
+			fatal("Failed to discover OIDC provider", err)
+		}
+		oidcHandler = handlers.NewOIDCHandler(userRepo, tokenIssuer, provider, cfg.OIDC)
+	}
+
+	r := &routes{
+		users:                 userHandler,
+		roster:                rosterHandler,
+		invitations:           invitationHandler,
+		jwks:                  jwksHandler,
+		apiKeys:               apiKeyHandler,
+		health:                healthHandler,
+		stream:                streamHandler,
+		notifications:         notificationHandler,
+		webhooks:              webhookHandler,
+		tasks:                 taskHandler,
+		taskTemplates:         taskTemplateHandler,
+		oidc:                  oidcHandler,
+		apiKeyRepo:            apiKeyRepo,
+		tokens:                tokenIssuer,
+		requireAdminTwoFactor: cfg.RequireAdminTwoFactor,
+	}
+	r.register(e)
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	
-	fmt.Printf("Server running on port %s\n", port)
-	e.Logger.Fatal(e.Start(":" + port))
-}
\ No newline at end of file
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server running", "port", cfg.Port)
+		serverErr <- e.Start(fmt.Sprintf(":%d", cfg.Port))
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server stopped", "error", err)
+		}
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	}
+
+	shutdown(e, healthHandler, hub, workers, cfg, shutdownTracing)
+}
+
+// shutdown ends change streams, drains in-flight requests, stops background workers, closes the
+// database connection and flushes traces, all within the configured shutdown timeout
+func shutdown(e *echo.Echo, healthHandler *handlers.HealthHandler, hub realtime.Hub, workers *worker.Group, cfg *config.Config, shutdownTracing func(context.Context) error) {
+	healthHandler.SetShuttingDown()
+
+	// Streams never finish on their own, so they would hold up draining
+	if err := hub.Close(); err != nil {
+		slog.Error("Failed to close change streams", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		slog.Error("Failed to drain requests", "error", err)
+	}
+	if err := workers.Stop(ctx); err != nil {
+		slog.Error("Failed to stop workers", "error", err)
+	}
+	if err := cfg.Close(); err != nil {
+		slog.Error("Failed to close database connection", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+}
+
+// isProbe reports whether a request is a health check or metrics scrape
+func isProbe(c echo.Context) bool {
+	return probePaths[c.Path()]
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// migrateOnStartup applies all pending migrations before the server accepts requests
+func migrateOnStartup(ctx context.Context, cfg *config.Config) error {
+	runner, err := migrate.NewRunner(cfg.DBClient, cfg.DBName, migrate.All)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, migrationTimeout)
+	defer cancel()
+	return runner.Up(ctx, 0)
+}
+
+// healthcheck probes the liveness endpoint of a running server and returns the exit code.
+// It lets the container image, which has no shell or curl, define a docker healthcheck.
+func healthcheck() int {
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return 1
+	}
+
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/healthz", cfg.Port))
+	if err != nil {
+		slog.Error("Health check failed", "error", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		slog.Error("Health check failed", "status", resp.Status)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/config"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/migrate"
+)
+
+// migrateUsage describes the migrate subcommand
+const migrateUsage = `usage: app migrate [command]
+
+commands:
+  up [version]   apply pending migrations, up to version if given (default)
+  down [steps]   roll back the most recent migrations (default 1)
+  status         list migrations and when they were applied`
+
+// runMigrate runs the migrate subcommand and returns the exit code
+func runMigrate(args []string) int {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	number := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		number = n
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return 1
+	}
+	defer cfg.Close()
+	slog.SetDefault(logging.New(os.Stderr, cfg.LogLevel))
+
+	runner, err := migrate.NewRunner(cfg.DBClient, cfg.DBName, migrate.All)
+	if err != nil {
+		slog.Error("Invalid migrations", "error", err)
+		return 1
+	}
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		err = runner.Up(ctx, number)
+	case "down":
+		if number == 0 {
+			number = 1
+		}
+		err = runner.Down(ctx, number)
+	case "status":
+		err = printMigrationStatus(ctx, runner)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		slog.Error("Migration failed", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// printMigrationStatus prints one line per known migration
+func printMigrationStatus(ctx context.Context, runner *migrate.Runner) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%4d  %-20s  %s\n", s.Version, applied, s.Name)
+	}
+	return nil
+}
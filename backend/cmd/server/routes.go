@@ -0,0 +1,470 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/handlers"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/middleware"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/openapi"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/roster"
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/swaggest/swgui/v5emb"
+)
+
+// routes holds everything the HTTP routes are served by
+type routes struct {
+	users       *handlers.UserHandler
+	roster      *handlers.RosterHandler
+	invitations *handlers.InvitationHandler
+	jwks        *handlers.JWKSHandler
+	apiKeys     *handlers.APIKeyHandler
+	health      *handlers.HealthHandler
+	stream      *handlers.StreamHandler
+	// notifications serves the current user's inbox and notification preferences
+	notifications *handlers.NotificationHandler
+	webhooks      *handlers.WebhookHandler
+	tasks         *handlers.TaskHandler
+	taskTemplates *handlers.TaskTemplateHandler
+	// oidc is nil when single sign-on is disabled
+	oidc *handlers.OIDCHandler
+
+	apiKeyRepo            repositories.APIKeyRepository
+	tokens                *auth.TokenIssuer
+	requireAdminTwoFactor bool
+}
+
+// Response bodies that handlers build from maps, described for the OpenAPI spec
+
+// MessageResponse is the body of the API root
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// StatusResponse is the body of the health probes
+type StatusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LoginResponse is the body of a successful password or single sign-on login.
+// Accounts with 2FA get a challenge token instead of an access token.
+type LoginResponse struct {
+	Token             string `json:"token,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+}
+
+// TwoFactorLoginResponse is the body of a completed 2FA login
+type TwoFactorLoginResponse struct {
+	Token                  string `json:"token"`
+	RecoveryCodesRemaining int    `json:"recoveryCodesRemaining"`
+}
+
+// RecoveryCodesResponse is the body returned when 2FA is enabled
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// CreatedAPIKeyResponse is the body returned when an API key is created; the key is never shown again
+type CreatedAPIKeyResponse struct {
+	APIKey models.APIKey `json:"apiKey"`
+	Key    string        `json:"key"`
+}
+
+// CreatedWebhookResponse is the body returned when a webhook is created; the signing secret is never shown again
+type CreatedWebhookResponse struct {
+	Webhook models.WebhookSubscription `json:"webhook"`
+	Secret  string                     `json:"secret"`
+}
+
+// UnreadCountResponse is the body of the unread notification count
+type UnreadCountResponse struct {
+	Count int64 `json:"count"`
+}
+
+// RosterImportForm is the multipart form of a roster import
+type RosterImportForm struct {
+	File openapi.File `json:"file" validate:"required"`
+	// DryRun defaults to true; only false creates accounts
+	DryRun bool `json:"dryRun"`
+	// Mapping is a JSON object from column header to user field
+	Mapping string `json:"mapping"`
+}
+
+// TaskAttachmentForm is the multipart form of an attachment upload
+type TaskAttachmentForm struct {
+	File openapi.File `json:"file" validate:"required"`
+}
+
+// Common error responses
+var (
+	badRequest   = openapi.Error(http.StatusBadRequest, "Invalid request")
+	unauthorized = openapi.Error(http.StatusUnauthorized, "Missing or invalid credentials")
+	forbidden    = openapi.Error(http.StatusForbidden, "Not allowed for this user or API key")
+	notFound     = openapi.Error(http.StatusNotFound, "Not found")
+	serverError  = openapi.Error(http.StatusInternalServerError, "Internal error")
+	throttled    = openapi.Response{
+		Status: http.StatusTooManyRequests, Description: "Too many failed login attempts", Body: openapi.ErrorResponse{},
+		ContentTypes: []string{echo.MIMEApplicationJSON}, Headers: map[string]string{"Retry-After": "Seconds until the next attempt is allowed"},
+	}
+)
+
+// userListQuery documents the paging, sorting, search and filter parameters of the user listing
+var userListQuery = []openapi.Param{
+	{Name: "q", Description: "Search text matched against username, full name and email"},
+	{Name: "sort", Enum: models.UserSortFields},
+	{Name: "order", Enum: []string{"asc", "desc"}},
+	{Name: "limit", Type: "integer", Description: "Page size, at most 100"},
+	{Name: "cursor", Description: "nextCursor of the previous page"},
+	{Name: "role", Enum: []string{string(models.AdminRole), string(models.GeneralRole)}},
+	{Name: "section"},
+	{Name: "instrument"},
+}
+
+// bodyLimit refuses request bodies over n bytes before the handler parses them, as uploads are read whole
+func bodyLimit(n int64) echo.MiddlewareFunc {
+	return echomiddleware.BodyLimit(strconv.FormatInt(n, 10))
+}
+
+// register adds every route to e and returns the OpenAPI spec that documents them
+func (r *routes) register(e *echo.Echo) *openapi.Spec {
+	spec := openapi.New(openapi.Info{Title: "FUTO Marching Dashboard API", Version: "1.0.0"})
+	bearer := []string{openapi.BearerAuth}
+	bearerOrKey := []string{openapi.BearerAuth, openapi.APIKeyAuth}
+
+	spec.Add(e.GET("/", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, MessageResponse{Message: "Welcome to FUTO Marching Dashboard API"})
+	}), openapi.Operation{ID: "root", Summary: "Welcome message", Tags: []string{"meta"},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "Welcome message", MessageResponse{})}})
+
+	// Liveness and readiness probes
+	spec.Add(e.GET("/healthz", r.health.Healthz), openapi.Operation{Summary: "Liveness probe", Tags: []string{"meta"},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The process is alive", StatusResponse{})}})
+	spec.Add(e.GET("/readyz", r.health.Readyz), openapi.Operation{Summary: "Readiness probe", Tags: []string{"meta"},
+		Responses: []openapi.Response{
+			openapi.JSON(http.StatusOK, "Ready to serve requests", StatusResponse{}),
+			openapi.JSON(http.StatusServiceUnavailable, "Shutting down or the database is unreachable", StatusResponse{}),
+		}})
+
+	// Prometheus metrics
+	spec.Add(e.GET("/metrics", echo.WrapHandler(metrics.Handler())), openapi.Operation{ID: "metrics", Summary: "Prometheus metrics", Tags: []string{"meta"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Description: "Metrics in the Prometheus text format", Body: "", ContentTypes: []string{"text/plain"}}}})
+
+	// Public keys for verifying access tokens
+	spec.Add(e.GET("/.well-known/jwks.json", r.jwks.JWKS), openapi.Operation{ID: "jwks", Summary: "Public keys that verify access tokens", Tags: []string{"auth"},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "JSON Web Key Set", auth.JWKS{})}})
+
+	// API description
+	spec.Add(e.GET("/api/openapi.json", spec.Handler), openapi.Operation{ID: "openapi", Summary: "This OpenAPI document", Tags: []string{"meta"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Description: "OpenAPI 3 document", Body: map[string]interface{}{}, ContentTypes: []string{echo.MIMEApplicationJSON}}}})
+	docs := echo.WrapHandler(v5emb.New("FUTO Marching Dashboard API", "/api/openapi.json", "/api/docs/"))
+	spec.Hide(e.GET("/api/docs", docs))
+	spec.Hide(e.GET("/api/docs/*", docs))
+
+	// Auth routes
+	spec.Add(e.POST("/api/auth/register", r.users.Register), openapi.Operation{Summary: "Register a user", Tags: []string{"auth"},
+		Body:      models.CreateUserInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusCreated, "The created user", models.User{}), badRequest, serverError}})
+	spec.Add(e.POST("/api/auth/login", r.users.Login), openapi.Operation{Summary: "Log in with a username and password", Tags: []string{"auth"},
+		Body: models.LoginInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "An access token, or a challenge token if 2FA is enabled", LoginResponse{}),
+			badRequest, unauthorized, throttled, serverError}})
+	spec.Add(e.POST("/api/auth/login/2fa", r.users.LoginTwoFactor), openapi.Operation{Summary: "Complete a login with a second factor", Tags: []string{"auth"},
+		Body: models.TwoFactorLoginInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "An access token", TwoFactorLoginResponse{}),
+			badRequest, unauthorized, throttled, serverError}})
+	spec.Add(e.POST("/api/auth/invitations/accept", r.invitations.AcceptInvitation), openapi.Operation{Summary: "Set a password from an invitation", Tags: []string{"auth"},
+		Body:      models.AcceptInvitationInput{},
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The password was set"), badRequest, serverError}})
+
+	// Single sign-on routes
+	if r.oidc != nil {
+		spec.Add(e.GET("/api/auth/oidc/login", r.oidc.Login), openapi.Operation{ID: "oidcLogin", Summary: "Start a single sign-on login", Tags: []string{"auth"},
+			Responses: []openapi.Response{{Status: http.StatusFound, Description: "Redirect to the identity provider", Headers: map[string]string{"Location": "Identity provider URL"}}, serverError}})
+		spec.Add(e.GET("/api/auth/oidc/callback", r.oidc.Callback), openapi.Operation{ID: "oidcCallback", Summary: "Finish a single sign-on login", Tags: []string{"auth"},
+			Query: []openapi.Param{{Name: "code"}, {Name: "state"}, {Name: "error"}},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "An access or challenge token when no post-login redirect is configured", LoginResponse{}),
+				{Status: http.StatusFound, Description: "Redirect to the frontend with the token in the fragment", Headers: map[string]string{"Location": "Post-login URL"}},
+				badRequest, unauthorized, forbidden, serverError,
+			}})
+	}
+
+	// API routes
+	// Routes only accept API keys when allowed below with the scope they require
+	apiKeyRoutes := middleware.NewAPIKeyRoutes()
+	api := e.Group("/api")
+	api.Use(middleware.APIKeyMiddleware(r.apiKeyRepo, apiKeyRoutes))
+	api.Use(middleware.JWTMiddleware(r.tokens))
+
+	// User routes
+	spec.Add(api.GET("/users/me", r.users.GetMe), openapi.Operation{Summary: "Get the current user", Tags: []string{"users"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The current user", models.User{}), unauthorized, notFound, serverError}})
+	spec.Add(api.POST("/users/me/2fa/enroll", r.users.EnrollTwoFactor), openapi.Operation{Summary: "Start 2FA enrollment", Tags: []string{"users"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "A new TOTP secret", models.TwoFactorEnrollment{}),
+			unauthorized, openapi.Error(http.StatusConflict, "2FA is already enabled"), serverError}})
+	spec.Add(api.POST("/users/me/2fa/verify", r.users.VerifyTwoFactor), openapi.Operation{Summary: "Enable 2FA by confirming a code", Tags: []string{"users"}, Security: bearer,
+		Body: models.TwoFactorCodeInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "Recovery codes, shown only once", RecoveryCodesResponse{}),
+			badRequest, unauthorized, openapi.Error(http.StatusConflict, "2FA is already enabled or the enrollment was restarted"), throttled, serverError}})
+	spec.Add(api.POST("/users/me/2fa/disable", r.users.DisableTwoFactor), openapi.Operation{Summary: "Disable 2FA", Tags: []string{"users"}, Security: bearer,
+		Body:      models.TwoFactorCodeInput{},
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "2FA was disabled"), badRequest, unauthorized, throttled, serverError}})
+
+	// Notification inbox and preferences
+	spec.Add(api.GET("/notifications", r.notifications.GetNotifications), openapi.Operation{Summary: "List the current user's notifications", Tags: []string{"notifications"}, Security: bearer,
+		Query: []openapi.Param{
+			{Name: "unread", Type: "boolean", Description: "Only list unread notifications"},
+			{Name: "order", Enum: []string{"asc", "desc"}, Description: "Newest first by default"},
+			{Name: "limit", Type: "integer", Description: "Page size, at most 100"},
+			{Name: "cursor", Description: "nextCursor of the previous page"},
+		},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "A page of notifications", models.ListResult[*models.Notification]{}),
+			badRequest, unauthorized, serverError}})
+	spec.Add(api.GET("/notifications/unread-count", r.notifications.GetUnreadCount), openapi.Operation{Summary: "Count unread notifications", Tags: []string{"notifications"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The number of unread notifications", UnreadCountResponse{}), unauthorized, serverError}})
+	spec.Add(api.POST("/notifications/read-all", r.notifications.MarkAllRead), openapi.Operation{Summary: "Mark all notifications as read", Tags: []string{"notifications"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "Every notification is read"), unauthorized, serverError}})
+	spec.Add(api.POST("/notifications/:id/read", r.notifications.MarkRead), openapi.Operation{Summary: "Mark a notification as read", Tags: []string{"notifications"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The notification is read"), unauthorized, notFound, serverError}})
+	notificationTypes := make([]string, len(models.NotificationTypes))
+	for i, t := range models.NotificationTypes {
+		notificationTypes[i] = string(t)
+	}
+	spec.Add(api.GET("/users/me/notification-preferences", r.notifications.GetPreferences), openapi.Operation{Summary: "Get the current user's notification preferences", Tags: []string{"notifications"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The saved preferences, or the defaults", models.NotificationPreferences{}), unauthorized, serverError}})
+	spec.Add(api.PUT("/users/me/notification-preferences", r.notifications.UpdatePreferences), openapi.Operation{Summary: "Replace the current user's notification preferences", Tags: []string{"notifications"}, Security: bearer,
+		Description: "Notification types: " + strings.Join(notificationTypes, ", ") + ". Types left out of channels are only kept in the inbox. Webhook URLs must use https.",
+		Body:        models.UpdateNotificationPreferencesInput{},
+		Responses:   []openapi.Response{openapi.JSON(http.StatusOK, "The saved preferences", models.NotificationPreferences{}), badRequest, unauthorized, serverError}})
+
+	// Tasks, visible to admins, their assignees and their creator
+	spec.Add(api.GET("/tasks", r.tasks.GetTasks), openapi.Operation{Summary: "List tasks", Tags: []string{"tasks"}, Security: bearer,
+		Description: "Admins see every task; other members see the tasks they created and those assigned to them, their section, their role or everyone.",
+		Query: []openapi.Param{
+			{Name: "status", Enum: []string{string(models.TaskStatusTodo), string(models.TaskStatusInProgress), string(models.TaskStatusCompleted)}},
+			{Name: "assignedTo", Description: "User ID of the assignee"},
+			{Name: "templateId", Description: "ID of the recurring task template the tasks were created from"},
+			{Name: "priority", Enum: []string{string(models.TaskPriorityLow), string(models.TaskPriorityMedium), string(models.TaskPriorityHigh), string(models.TaskPriorityUrgent)}},
+			{Name: "label", Description: "Only tasks with this label; repeat to require several labels"},
+			{Name: "q", Description: "Search text matched against title, description and labels"},
+			{Name: "sort", Enum: models.TaskSortFields, Description: "rank with a status filter gives the order of a Kanban column"},
+			{Name: "order", Enum: []string{"asc", "desc"}},
+			{Name: "limit", Type: "integer", Description: "Page size, at most 100"},
+			{Name: "cursor", Description: "nextCursor of the previous page"},
+		},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "A page of tasks", models.ListResult[*models.Task]{}), badRequest, unauthorized, serverError}})
+	spec.Add(api.POST("/tasks", r.tasks.CreateTask), openapi.Operation{Summary: "Create a task", Tags: []string{"tasks"}, Security: bearer,
+		Description: "assigneeType user (the default) needs assignedTo, section needs assignedSection and role needs assignedRole.",
+		Body:        models.CreateTaskInput{},
+		Responses:   []openapi.Response{openapi.JSON(http.StatusCreated, "The created task", models.Task{}), badRequest, unauthorized, serverError}})
+	spec.Add(api.GET("/tasks/:id", r.tasks.GetTask), openapi.Operation{Summary: "Get a task", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The task with its checklist", models.Task{}), unauthorized, notFound, serverError}})
+	spec.Add(api.PUT("/tasks/:id", r.tasks.UpdateTask), openapi.Operation{Summary: "Update a task", Tags: []string{"tasks"}, Security: bearer,
+		Description: "Fields left empty are unchanged. Only the task's creator or an admin can reassign it or change a group task; members mark their part of a group task done through its completion instead. A blocked task can only be moved to in_progress or completed by an admin with overrideBlockers.",
+		Body:        models.UpdateTaskInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The updated task", models.Task{}), badRequest, unauthorized, forbidden, notFound,
+			openapi.Error(http.StatusConflict, "The task is waiting on tasks that are not completed"), serverError}})
+	spec.Add(api.POST("/tasks/:id/move", r.tasks.MoveTask), openapi.Operation{Summary: "Move a task on the Kanban board", Tags: []string{"tasks"}, Security: bearer,
+		Description: "Places the task in the status column between afterId (the task above) and beforeId (the task below). Leave both empty to move it to the bottom of the column. Only the task's creator or an admin can move a group task to another column. A blocked task can only be moved to in_progress or completed by an admin with overrideBlockers.",
+		Body:        models.MoveTaskInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The moved task", models.Task{}), badRequest, unauthorized, forbidden, notFound,
+			openapi.Error(http.StatusConflict, "The neighbouring tasks have moved since the board was loaded, or the task is blocked"), serverError}})
+	spec.Add(api.GET("/tasks/:id/dependencies", r.tasks.GetTaskDependencies), openapi.Operation{Summary: "List the tasks a task waits on and the tasks waiting on it", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The task's dependencies", models.TaskDependencies{}), unauthorized, notFound, serverError}})
+	spec.Add(api.POST("/tasks/:id/dependencies", r.tasks.AddTaskDependency), openapi.Operation{Summary: "Make a task wait on another", Tags: []string{"tasks"}, Security: bearer,
+		Description: "The task is blocked until taskId is completed. Only the task's creator or an admin can change its dependencies.",
+		Body:        models.TaskDependencyInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The task", models.Task{}), badRequest, unauthorized, forbidden, notFound,
+			openapi.Error(http.StatusConflict, "The dependency would create a cycle"), serverError}})
+	spec.Add(api.DELETE("/tasks/:id/dependencies/:blockerId", r.tasks.RemoveTaskDependency), openapi.Operation{Summary: "Stop a task waiting on another", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The task", models.Task{}), unauthorized, forbidden, notFound, serverError}})
+	spec.Add(api.DELETE("/tasks/:id", r.tasks.DeleteTask), openapi.Operation{Summary: "Delete a task with its comments and attachments", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The task was deleted"), unauthorized, forbidden, notFound, serverError}})
+	spec.Add(api.POST("/tasks/:id/completion", r.tasks.CompleteTask), openapi.Operation{Summary: "Mark a task done for the current member", Tags: []string{"tasks"}, Security: bearer,
+		Description: "Completes a user task, or records the member's completion of a group task. A blocked task can only be completed by an admin with overrideBlockers.",
+		Body:        models.CompleteTaskInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The task", models.Task{}), badRequest, unauthorized, forbidden, notFound,
+			openapi.Error(http.StatusConflict, "The task is waiting on tasks that are not completed"), serverError}})
+	spec.Add(api.DELETE("/tasks/:id/completion", r.tasks.UncompleteTask), openapi.Operation{Summary: "Undo the current member's completion", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The task", models.Task{}), unauthorized, forbidden, notFound, serverError}})
+	spec.Add(api.GET("/tasks/:id/progress", r.tasks.GetTaskProgress), openapi.Operation{Summary: "Show which assignees have finished a task", Tags: []string{"tasks"}, Security: bearer,
+		Description: "Only the task's creator or an admin can see the progress.",
+		Responses:   []openapi.Response{openapi.JSON(http.StatusOK, "Members who are done and who are pending", models.TaskProgress{}), unauthorized, forbidden, notFound, serverError}})
+
+	spec.Add(api.GET("/tasks/:id/comments", r.tasks.GetTaskComments), openapi.Operation{Summary: "List a task's comments as threads", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "Top-level comments with their replies, oldest first", []models.TaskCommentThread{}), unauthorized, notFound, serverError}})
+	spec.Add(api.POST("/tasks/:id/comments", r.tasks.CreateTaskComment), openapi.Operation{Summary: "Comment on a task", Tags: []string{"tasks"}, Security: bearer,
+		Description: "Set parentId to reply to another comment on the task.",
+		Body:        models.CreateTaskCommentInput{},
+		Responses:   []openapi.Response{openapi.JSON(http.StatusCreated, "The created comment", models.TaskComment{}), badRequest, unauthorized, notFound, serverError}})
+	spec.Add(api.PUT("/tasks/:id/comments/:commentId", r.tasks.UpdateTaskComment), openapi.Operation{Summary: "Edit a comment", Tags: []string{"tasks"}, Security: bearer,
+		Description: "Only the author can edit a comment.",
+		Body:        models.UpdateTaskCommentInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The edited comment", models.TaskComment{}),
+			badRequest, unauthorized, forbidden, notFound, openapi.Error(http.StatusConflict, "The comment was deleted"), serverError}})
+	spec.Add(api.DELETE("/tasks/:id/comments/:commentId", r.tasks.DeleteTaskComment), openapi.Operation{Summary: "Delete a comment", Tags: []string{"tasks"}, Security: bearer,
+		Description: "The comment stays in its thread without its body so replies keep their context.",
+		Responses:   []openapi.Response{openapi.Empty(http.StatusNoContent, "The comment was deleted"), unauthorized, forbidden, notFound, serverError}})
+
+	spec.Add(api.POST("/tasks/:id/checklist", r.tasks.AddChecklistItem), openapi.Operation{Summary: "Add a checklist item", Tags: []string{"tasks"}, Security: bearer,
+		Body:      models.ChecklistItemInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusCreated, "The added item", models.ChecklistItem{}), badRequest, unauthorized, notFound, serverError}})
+	spec.Add(api.PUT("/tasks/:id/checklist/:itemId", r.tasks.UpdateChecklistItem), openapi.Operation{Summary: "Update or check off a checklist item", Tags: []string{"tasks"}, Security: bearer,
+		Body:      models.ChecklistItemInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The updated item", models.ChecklistItem{}), badRequest, unauthorized, notFound, serverError}})
+	spec.Add(api.DELETE("/tasks/:id/checklist/:itemId", r.tasks.DeleteChecklistItem), openapi.Operation{Summary: "Remove a checklist item", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The item was removed"), unauthorized, notFound, serverError}})
+
+	spec.Add(api.GET("/tasks/:id/attachments", r.tasks.GetTaskAttachments), openapi.Operation{Summary: "List a task's attachments", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "Attachments, oldest first", []models.TaskAttachment{}), unauthorized, notFound, serverError}})
+	spec.Add(api.POST("/tasks/:id/attachments", r.tasks.UploadTaskAttachment, bodyLimit(r.tasks.MaxAttachmentRequestSize())), openapi.Operation{Summary: "Attach a file to a task", Tags: []string{"tasks"}, Security: bearer,
+		Form: TaskAttachmentForm{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusCreated, "The attachment", models.TaskAttachment{}),
+			badRequest, unauthorized, notFound, openapi.Error(http.StatusRequestEntityTooLarge, "File is too large"), serverError}})
+	spec.Add(api.GET("/tasks/:id/attachments/:attachmentId", r.tasks.DownloadTaskAttachment), openapi.Operation{Summary: "Download an attachment", Tags: []string{"tasks"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Binary(http.StatusOK, "The file, sent as a download", "application/octet-stream"), unauthorized, notFound, serverError}})
+	spec.Add(api.DELETE("/tasks/:id/attachments/:attachmentId", r.tasks.DeleteTaskAttachment), openapi.Operation{Summary: "Remove an attachment", Tags: []string{"tasks"}, Security: bearer,
+		Description: "The uploader, the task's creator and admins can remove an attachment.",
+		Responses:   []openapi.Response{openapi.Empty(http.StatusNoContent, "The attachment was removed"), unauthorized, forbidden, notFound, serverError}})
+
+	// Change stream
+	topics := make([]string, len(realtime.Topics))
+	for i, t := range realtime.Topics {
+		topics[i] = string(t)
+	}
+	spec.Add(api.GET("/stream", r.stream.Stream), openapi.Operation{Summary: "Stream changes as Server-Sent Events", Tags: []string{"realtime"}, Security: bearer,
+		Description: "Each event is named after its topic and carries a Change as JSON. Only changes the current user may see are sent. " +
+			"The stream ends when the client falls behind or the server shuts down; reconnect and reload what is shown.",
+		Query: []openapi.Param{{Name: "topics", Description: "Comma-separated topics to follow, all by default: " + strings.Join(topics, ", ")}},
+		Responses: []openapi.Response{{Status: http.StatusOK, Description: "An event stream of changes", Body: realtime.Change{}, ContentTypes: []string{"text/event-stream"}},
+			badRequest, unauthorized, openapi.Error(http.StatusServiceUnavailable, "The server is shutting down"), serverError}})
+
+	// Admin routes
+	admin := api.Group("/admin")
+	admin.Use(middleware.RoleMiddleware(models.AdminRole))
+	if r.requireAdminTwoFactor {
+		admin.Use(middleware.RequireTwoFactor())
+	}
+
+	route := admin.GET("/users", r.users.GetAllUsers)
+	apiKeyRoutes.Allow(route, models.ScopeUsersRead)
+	spec.Add(route, openapi.Operation{Summary: "List users", Tags: []string{"admin"}, Security: bearerOrKey,
+		Description: "API keys need the " + models.ScopeUsersRead + " scope.",
+		Query:       userListQuery,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "A page of users", models.ListResult[*models.User]{}),
+			badRequest, unauthorized, forbidden, serverError}})
+
+	spec.Add(admin.POST("/users/import", r.roster.ImportUsers, bodyLimit(handlers.MaxRosterRequestSize)), openapi.Operation{Summary: "Import users from a CSV or Excel roster", Tags: []string{"admin"}, Security: bearer,
+		Form: RosterImportForm{},
+		Responses: []openapi.Response{
+			openapi.JSON(http.StatusOK, "Dry run report", models.RosterImportReport{}),
+			openapi.JSON(http.StatusCreated, "Import report with the created users and their invitation links", models.RosterImportReport{}),
+			openapi.JSON(http.StatusUnprocessableEntity, "Rows with errors; nothing was created", models.RosterImportReport{}),
+			badRequest, unauthorized, forbidden, openapi.Error(http.StatusRequestEntityTooLarge, "Roster file is too large"), serverError,
+		}})
+
+	route = admin.GET("/users/export", r.roster.ExportUsers)
+	apiKeyRoutes.Allow(route, models.ScopeUsersExport)
+	spec.Add(route, openapi.Operation{Summary: "Export users", Tags: []string{"admin"}, Security: bearerOrKey,
+		Description: "API keys need the " + models.ScopeUsersExport + " scope.",
+		Query: append([]openapi.Param{
+			{Name: "format", Enum: []string{string(roster.FormatCSV), string(roster.FormatXLSX), string(roster.FormatPDF)}},
+			{Name: "columns", Description: "Comma-separated columns to include, in order"},
+		}, userListQuery...),
+		Responses: []openapi.Response{openapi.Binary(http.StatusOK, "The exported file",
+			roster.FormatCSV.ContentType(), roster.FormatXLSX.ContentType(), roster.FormatPDF.ContentType()),
+			badRequest, unauthorized, forbidden, serverError}})
+
+	route = admin.GET("/users/:id", r.users.GetUser)
+	apiKeyRoutes.Allow(route, models.ScopeUsersRead)
+	spec.Add(route, openapi.Operation{Summary: "Get a user", Tags: []string{"admin"}, Security: bearerOrKey,
+		Description: "API keys need the " + models.ScopeUsersRead + " scope.",
+		Responses:   []openapi.Response{openapi.JSON(http.StatusOK, "The user", models.User{}), unauthorized, forbidden, notFound, serverError}})
+
+	spec.Add(admin.PUT("/users/:id", r.users.UpdateUser), openapi.Operation{Summary: "Update a user", Tags: []string{"admin"}, Security: bearer,
+		Body:      models.UpdateUserInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The updated user", models.User{}), badRequest, unauthorized, forbidden, notFound, serverError}})
+	spec.Add(admin.DELETE("/users/:id", r.users.DeleteUser), openapi.Operation{Summary: "Delete a user", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The user was deleted"), unauthorized, forbidden, serverError}})
+	spec.Add(admin.POST("/users/:id/unlock", r.users.UnlockUser), openapi.Operation{Summary: "Clear a user's failed logins", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The user can log in again"), unauthorized, forbidden, notFound, serverError}})
+
+	// API key management
+	spec.Add(admin.GET("/api-keys", r.apiKeys.GetAllAPIKeys), openapi.Operation{Summary: "List API keys", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "All API keys, newest first", []models.APIKey{}), unauthorized, forbidden, serverError}})
+	spec.Add(admin.POST("/api-keys", r.apiKeys.CreateAPIKey), openapi.Operation{Summary: "Create an API key", Tags: []string{"admin"}, Security: bearer,
+		Body: models.CreateAPIKeyInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusCreated, "The API key and its secret", CreatedAPIKeyResponse{}),
+			badRequest, unauthorized, forbidden, serverError}})
+	spec.Add(admin.DELETE("/api-keys/:id", r.apiKeys.RevokeAPIKey), openapi.Operation{Summary: "Revoke an API key", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The key was revoked"), unauthorized, forbidden, notFound, serverError}})
+
+	// Outgoing webhooks
+	const noWebhookProducers = "Reserved event types: no change emits them yet, so subscriptions receive no deliveries until the APIs that change " +
+		"events, attendance and practice menus exist. "
+	eventTypes := make([]string, len(models.WebhookEventTypes))
+	for i, t := range models.WebhookEventTypes {
+		eventTypes[i] = string(t)
+	}
+	spec.Add(admin.GET("/webhooks", r.webhooks.GetAllWebhooks), openapi.Operation{Summary: "List webhooks", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "All webhooks, newest first", []models.WebhookSubscription{}), unauthorized, forbidden, serverError}})
+	spec.Add(admin.POST("/webhooks", r.webhooks.CreateWebhook), openapi.Operation{Summary: "Create a webhook", Tags: []string{"admin"}, Security: bearer,
+		Description: noWebhookProducers + "Event types: " + strings.Join(eventTypes, ", ") + ". The URL must use https. " +
+			"Deliveries are POSTed with X-Webhook-Timestamp and X-Webhook-Signature headers; the signature is v1= followed by " +
+			"the hex HMAC-SHA256 of \"<timestamp>.<body>\" keyed with the secret.",
+		Body: models.CreateWebhookSubscriptionInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusCreated, "The webhook and its signing secret", CreatedWebhookResponse{}),
+			badRequest, unauthorized, forbidden, serverError}})
+	spec.Add(admin.PUT("/webhooks/:id", r.webhooks.UpdateWebhook), openapi.Operation{Summary: "Update a webhook", Tags: []string{"admin"}, Security: bearer,
+		Description: noWebhookProducers + "Event types: " + strings.Join(eventTypes, ", ") + ".",
+		Body:        models.UpdateWebhookSubscriptionInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The updated webhook", models.WebhookSubscription{}),
+			badRequest, unauthorized, forbidden, notFound, serverError}})
+	spec.Add(admin.DELETE("/webhooks/:id", r.webhooks.DeleteWebhook), openapi.Operation{Summary: "Delete a webhook", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.Empty(http.StatusNoContent, "The webhook was deleted"), unauthorized, forbidden, notFound, serverError}})
+	spec.Add(admin.GET("/webhooks/:id/deliveries", r.webhooks.GetWebhookDeliveries), openapi.Operation{Summary: "List a webhook's deliveries", Tags: []string{"admin"}, Security: bearer,
+		Query: []openapi.Param{
+			{Name: "status", Enum: []string{string(models.WebhookDeliveryPending), string(models.WebhookDeliverySucceeded), string(models.WebhookDeliveryDead)}},
+			{Name: "order", Enum: []string{"asc", "desc"}, Description: "Newest first by default"},
+			{Name: "limit", Type: "integer", Description: "Page size, at most 100"},
+			{Name: "cursor", Description: "nextCursor of the previous page"},
+		},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "A page of deliveries with their attempts", models.ListResult[*models.WebhookDelivery]{}),
+			badRequest, unauthorized, forbidden, notFound, serverError}})
+	spec.Add(admin.POST("/webhook-deliveries/:id/redeliver", r.webhooks.RedeliverWebhookDelivery), openapi.Operation{Summary: "Send a delivery again", Tags: []string{"admin"}, Security: bearer,
+		Description: "Queues a new delivery with the same event ID and payload, so receivers can discard duplicates.",
+		Responses: []openapi.Response{openapi.JSON(http.StatusAccepted, "The queued delivery", models.WebhookDelivery{}),
+			unauthorized, forbidden, notFound, serverError}})
+
+	// Recurring task templates
+	spec.Add(admin.GET("/task-templates", r.taskTemplates.GetAllTaskTemplates), openapi.Operation{Summary: "List recurring task templates", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "All templates by title", []models.TaskTemplate{}), unauthorized, forbidden, serverError}})
+	spec.Add(admin.POST("/task-templates", r.taskTemplates.CreateTaskTemplate), openapi.Operation{Summary: "Create a recurring task template", Tags: []string{"admin"}, Security: bearer,
+		Description: "Tasks are created leadDays before each due date of the recurrence: every interval days (daily), " +
+			"on weekdays every interval weeks (weekly, 0 is Sunday) or on the day of startsAt every interval months (monthly). " +
+			"Days are counted in timezone, Asia/Tokyo by default.",
+		Body: models.TaskTemplateInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusCreated, "The template with its next due date", models.TaskTemplate{}),
+			badRequest, unauthorized, forbidden, serverError}})
+	spec.Add(admin.GET("/task-templates/:id", r.taskTemplates.GetTaskTemplate), openapi.Operation{Summary: "Get a recurring task template", Tags: []string{"admin"}, Security: bearer,
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The template with its next due date", models.TaskTemplate{}), unauthorized, forbidden, notFound, serverError}})
+	spec.Add(admin.PUT("/task-templates/:id", r.taskTemplates.UpdateTaskTemplate), openapi.Operation{Summary: "Update a recurring task template", Tags: []string{"admin"}, Security: bearer,
+		Description: "Only tasks created after the change use it; tasks already created, open or completed, are left as they are.",
+		Body:        models.TaskTemplateInput{},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "The updated template", models.TaskTemplate{}),
+			badRequest, unauthorized, forbidden, notFound, serverError}})
+	spec.Add(admin.DELETE("/task-templates/:id", r.taskTemplates.DeleteTaskTemplate), openapi.Operation{Summary: "Delete a recurring task template", Tags: []string{"admin"}, Security: bearer,
+		Description: "Tasks already created from the template are kept.",
+		Responses:   []openapi.Response{openapi.Empty(http.StatusNoContent, "The template was deleted"), unauthorized, forbidden, notFound, serverError}})
+
+	return spec
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/handlers"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+// testRoutes registers every route, including the optional ones, with handlers that are never called
+func testRoutes(t *testing.T) (*echo.Echo, *openapi.Spec) {
+	t.Helper()
+
+	e := echo.New()
+	r := &routes{
+		users:                 &handlers.UserHandler{},
+		roster:                &handlers.RosterHandler{},
+		invitations:           &handlers.InvitationHandler{},
+		jwks:                  &handlers.JWKSHandler{},
+		apiKeys:               &handlers.APIKeyHandler{},
+		health:                &handlers.HealthHandler{},
+		stream:                &handlers.StreamHandler{},
+		notifications:         &handlers.NotificationHandler{},
+		webhooks:              &handlers.WebhookHandler{},
+		tasks:                 &handlers.TaskHandler{},
+		taskTemplates:         &handlers.TaskTemplateHandler{},
+		oidc:                  &handlers.OIDCHandler{},
+		requireAdminTwoFactor: true,
+	}
+	return e, r.register(e)
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	e, spec := testRoutes(t)
+
+	for _, route := range e.Routes() {
+		// Groups add catch-all routes that only answer 404
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		if !spec.Covers(route.Method, route.Path) {
+			t.Errorf("%s %s is missing from the OpenAPI spec", route.Method, route.Path)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	e, _ := testRoutes(t)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Error decoding document: %v", err)
+	}
+
+	if item, ok := doc.Paths["/api/admin/users/{id}"]; !ok || (*item)["put"] == nil {
+		t.Fatal("Expected PUT /api/admin/users/{id} with the path parameter in OpenAPI form")
+	}
+
+	user := doc.Components.Schemas["User"]
+	if user == nil {
+		t.Fatal("Expected a User schema")
+	}
+	if _, ok := user.Properties["password"]; ok {
+		t.Error("Expected the password to be left out of the User schema")
+	}
+
+	input := doc.Components.Schemas["CreateUserInput"]
+	if input == nil {
+		t.Fatal("Expected a CreateUserInput schema")
+	}
+	if got := strings.Join(input.Required, ","); got != "username,fullName,email,password,role" {
+		t.Errorf("Expected required fields from validate tags, got %s", got)
+	}
+	if input.Properties["email"].Format != "email" {
+		t.Error("Expected the email format from the validate tag")
+	}
+	if min := input.Properties["password"].MinLength; min == nil || *min != 6 {
+		t.Error("Expected the minimum password length from the validate tag")
+	}
+	if got := strings.Join(input.Properties["role"].Enum, ","); got != "admin,general" {
+		t.Errorf("Expected the role enum from the validate tag, got %s", got)
+	}
+
+	if _, ok := doc.Components.Schemas["ListResultUser"]; !ok {
+		t.Error("Expected the generic user page to be named ListResultUser")
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	e, _ := testRoutes(t)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/openapi.json") {
+		t.Errorf("Expected the Swagger UI page pointing at the spec, got %d", rec.Code)
+	}
+}
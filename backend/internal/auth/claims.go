@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// claimsContextKey is the echo context key the JWT middleware stores claims under
+const claimsContextKey = "user"
+
+// Claims are the claims of an access token. The subject is the user ID.
+type Claims struct {
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
+	// MFA records whether the user completed a second factor during this login
+	MFA bool `json:"mfa"`
+	// Purpose is set on purpose-bound tokens, which are never access tokens
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SetClaims stores verified access token claims in the request context
+func SetClaims(c echo.Context, claims *Claims) {
+	c.Set(claimsContextKey, claims)
+}
+
+// CurrentClaims returns the verified claims of the request, if any
+func CurrentClaims(c echo.Context) (*Claims, bool) {
+	claims, ok := c.Get(claimsContextKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// CurrentUser returns the ID and role of the authenticated user.
+// ok is false when the request carries no verified access token.
+func CurrentUser(c echo.Context) (userID string, role models.Role, ok bool) {
+	claims, ok := CurrentClaims(c)
+	if !ok || claims.Subject == "" {
+		return "", "", false
+	}
+	return claims.Subject, claims.Role, true
+}
+
+// apiKeyContextKey is the echo context key the API key middleware stores the key under
+const apiKeyContextKey = "apiKey"
+
+// SetAPIKey stores the API key that authenticated the request
+func SetAPIKey(c echo.Context, key *models.APIKey) {
+	c.Set(apiKeyContextKey, key)
+}
+
+// CurrentAPIKey returns the API key that authenticated the request, if any
+func CurrentAPIKey(c echo.Context) (*models.APIKey, bool) {
+	key, ok := c.Get(apiKeyContextKey).(*models.APIKey)
+	return key, ok && key != nil
+}
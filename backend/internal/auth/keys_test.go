@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// writePEM writes a PEM block to a file in the test's temporary directory
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Error writing key: %v", err)
+	}
+	return path
+}
+
+func writeRSAKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	return writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+}
+
+func writeEd25519Key(t *testing.T) (private, public string) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	privDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+	return writePEM(t, "ed25519.pem", "PRIVATE KEY", privDER), writePEM(t, "ed25519.pub.pem", "PUBLIC KEY", pubDER)
+}
+
+func testUser() *models.User {
+	return &models.User{ID: primitive.NewObjectID(), Username: "hanako", Role: models.GeneralRole}
+}
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	edPrivate, _ := writeEd25519Key(t)
+
+	for name, path := range map[string]string{"RS256": writeRSAKey(t), "EdDSA": edPrivate} {
+		t.Run(name, func(t *testing.T) {
+			keys, err := LoadKeySet(path, nil)
+			if err != nil {
+				t.Fatalf("Error loading keys: %v", err)
+			}
+
+			tokenString, err := NewTokenIssuer(keys, testTokenConfig).AccessToken(testUser(), false)
+			if err != nil {
+				t.Fatalf("Error signing token: %v", err)
+			}
+
+			claims := jwt.MapClaims{}
+			token, err := keys.Parse(tokenString, claims)
+			if err != nil || !token.Valid {
+				t.Fatalf("Expected token to verify, got %v", err)
+			}
+			if token.Method.Alg() != name {
+				t.Errorf("Expected %s, got %s", name, token.Method.Alg())
+			}
+			if kid, _ := token.Header["kid"].(string); kid == "" {
+				t.Error("Expected a kid header")
+			}
+			if claims["username"] != "hanako" {
+				t.Errorf("Unexpected claims: %v", claims)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldPrivate, oldPublic := writeEd25519Key(t)
+	newPrivate := writeRSAKey(t)
+
+	oldKeys, err := LoadKeySet(oldPrivate, nil)
+	if err != nil {
+		t.Fatalf("Error loading keys: %v", err)
+	}
+	oldToken, _ := NewTokenIssuer(oldKeys, testTokenConfig).AccessToken(testUser(), false)
+
+	// After rotation the old public key only verifies
+	rotated, err := LoadKeySet(newPrivate, []string{oldPublic})
+	if err != nil {
+		t.Fatalf("Error loading keys: %v", err)
+	}
+	if _, err := rotated.Parse(oldToken, jwt.MapClaims{}); err != nil {
+		t.Errorf("Expected token signed by the retired key to verify, got %v", err)
+	}
+	if len(rotated.JWKS().Keys) != 2 {
+		t.Errorf("Expected both keys in the JWKS, got %d", len(rotated.JWKS().Keys))
+	}
+
+	// Once the old key is dropped its tokens are rejected
+	current, _ := LoadKeySet(newPrivate, nil)
+	if _, err := current.Parse(oldToken, jwt.MapClaims{}); err == nil {
+		t.Error("Expected token signed by a removed key to be rejected")
+	}
+
+	if _, err := LoadKeySet(oldPublic, nil); err == nil {
+		t.Error("Expected a public key to be rejected as signing key")
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	rsaPath := writeRSAKey(t)
+	keys, _ := LoadKeySet(rsaPath, nil)
+	kid := keys.JWKS().Keys[0].Kid
+
+	// An HS256 token keyed with the published RSA modulus must not verify
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+	token.Header["kid"] = kid
+	forged, _ := token.SignedString([]byte(keys.JWKS().Keys[0].N))
+	if _, err := keys.Parse(forged, jwt.MapClaims{}); err == nil {
+		t.Error("Expected HS256 token to be rejected by an RSA key set")
+	}
+
+	// HMAC tokens are not accepted by an asymmetric key set without a kid either
+	hmacToken, _ := NewTokenIssuer(NewHMACKeySet("secret"), testTokenConfig).AccessToken(testUser(), false)
+	if _, err := keys.Parse(hmacToken, jwt.MapClaims{}); err == nil {
+		t.Error("Expected HMAC token to be rejected by an RSA key set")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	if len(NewHMACKeySet("secret").JWKS().Keys) != 0 {
+		t.Error("HMAC secrets must not be published")
+	}
+
+	_, edPublic := writeEd25519Key(t)
+	key, err := LoadKey(edPublic)
+	if err != nil {
+		t.Fatalf("Error loading key: %v", err)
+	}
+
+	keys, _ := LoadKeySet(writeRSAKey(t), []string{edPublic})
+	for _, jwk := range keys.JWKS().Keys {
+		switch jwk.Kty {
+		case "OKP":
+			if jwk.Kid != key.ID || jwk.Crv != "Ed25519" || jwk.X == "" || jwk.Alg != "EdDSA" {
+				t.Errorf("Unexpected Ed25519 JWK: %+v", jwk)
+			}
+		case "RSA":
+			if jwk.E != "AQAB" || jwk.N == "" || jwk.Alg != "RS256" || jwk.Use != "sig" {
+				t.Errorf("Unexpected RSA JWK: %+v", jwk)
+			}
+		default:
+			t.Errorf("Unexpected key type %q", jwk.Kty)
+		}
+	}
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// RFC 7638 section 3.1
+	jwk := JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	if got := jwk.thumbprint(); got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Unexpected thumbprint %s", got)
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCProvider is a minimal OpenID Connect provider for exercising the login flow
+type mockOIDCProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	m := &mockOIDCProvider{key: key}
+	mux := http.NewServeMux()
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "auth-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, m.claims)
+		token.Header["kid"] = "mock"
+		idToken, _ := token.SignedString(key)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "provider-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	return m
+}
+
+// authorize simulates the user signing in: it records the PKCE challenge from the auth URL
+func (m *mockOIDCProvider) authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("Invalid auth URL: %v", err)
+	}
+	params := u.Query()
+	m.challenge = params.Get("code_challenge")
+	return params
+}
+
+func (m *mockOIDCProvider) setIdentity(nonce, email string, verified bool) {
+	m.claims = jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            "dashboard",
+		"sub":            "google-oauth2|1234",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          email,
+		"email_verified": verified,
+		"name":           "Sato Hanako",
+		"hd":             "futo.example.jp",
+	}
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	ctx := context.Background()
+	mock := newMockOIDCProvider(t)
+
+	provider, err := NewOIDCProvider(ctx, OIDCConfig{
+		IssuerURL:     mock.server.URL,
+		ClientID:      "dashboard",
+		RedirectURL:   "http://localhost:8080/api/auth/oidc/callback",
+		AllowedDomain: "futo.example.jp",
+	})
+	if err != nil {
+		t.Fatalf("Error discovering provider: %v", err)
+	}
+
+	params := mock.authorize(t, provider.AuthCodeURL("state-1", "nonce-1", "verifier-0123456789-0123456789-0123456789"))
+	if params.Get("code_challenge_method") != "S256" {
+		t.Errorf("Expected S256 PKCE, got %q", params.Get("code_challenge_method"))
+	}
+	if params.Get("state") != "state-1" || params.Get("nonce") != "nonce-1" || params.Get("hd") != "futo.example.jp" {
+		t.Errorf("Unexpected auth URL parameters: %v", params)
+	}
+
+	mock.setIdentity("nonce-1", "Hanako@futo.example.jp", true)
+
+	identity, err := provider.Exchange(ctx, "auth-code", "verifier-0123456789-0123456789-0123456789", "nonce-1")
+	if err != nil {
+		t.Fatalf("Error exchanging code: %v", err)
+	}
+	if identity.Email != "hanako@futo.example.jp" || identity.Subject != "google-oauth2|1234" || identity.Name != "Sato Hanako" {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+	if !identity.InDomain("futo.example.jp") || identity.InDomain("example.jp") {
+		t.Error("Domain check failed")
+	}
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		if _, err := provider.Exchange(ctx, "auth-code", "another-verifier-0123456789-0123456789", "nonce-1"); err == nil {
+			t.Error("Expected exchange with the wrong PKCE verifier to fail")
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		if _, err := provider.Exchange(ctx, "auth-code", "verifier-0123456789-0123456789-0123456789", "nonce-2"); err == nil {
+			t.Error("Expected exchange with the wrong nonce to fail")
+		}
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		mock.setIdentity("nonce-1", "hanako@futo.example.jp", true)
+		mock.claims["aud"] = "another-client"
+		if _, err := provider.Exchange(ctx, "auth-code", "verifier-0123456789-0123456789-0123456789", "nonce-1"); err == nil {
+			t.Error("Expected an ID token for another client to be rejected")
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		mock.setIdentity("nonce-1", "hanako@futo.example.jp", false)
+		_, err := provider.Exchange(ctx, "auth-code", "verifier-0123456789-0123456789-0123456789", "nonce-1")
+		if !errors.Is(err, ErrUnverifiedEmail) {
+			t.Errorf("Expected ErrUnverifiedEmail, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+)
+
+const (
+	// PurposeTwoFactorChallenge marks tokens that only prove the password step of a 2FA login.
+	// They must not be accepted as access tokens.
+	PurposeTwoFactorChallenge = "2fa_challenge"
+	// PurposeOIDCState marks tokens that carry the state of an OIDC login between redirects
+	PurposeOIDCState = "oidc_state"
+
+	// DefaultAccessTokenTTL is the default lifetime of an access token
+	DefaultAccessTokenTTL = 72 * time.Hour
+	// DefaultChallengeTokenTTL is how long a user has by default to enter their second factor after the password
+	DefaultChallengeTokenTTL = 5 * time.Minute
+)
+
+// TokenConfig configures the tokens issued by this API
+type TokenConfig struct {
+	// Issuer and Audience are set in access tokens and required when verifying them
+	Issuer            string        `yaml:"issuer"`
+	Audience          string        `yaml:"audience"`
+	AccessTokenTTL    time.Duration `yaml:"accessTokenTTL"`
+	ChallengeTokenTTL time.Duration `yaml:"challengeTokenTTL"`
+}
+
+// errNotAccessToken is returned when a valid purpose-bound token is presented as an access token
+var errNotAccessToken = errors.New("token is not an access token")
+
+// TokenIssuer creates and parses the tokens issued by this API
+type TokenIssuer struct {
+	keys *KeySet
+	cfg  TokenConfig
+}
+
+// NewTokenIssuer creates a new TokenIssuer signing with the active key of the key set
+func NewTokenIssuer(keys *KeySet, cfg TokenConfig) *TokenIssuer {
+	return &TokenIssuer{keys: keys, cfg: cfg}
+}
+
+// AccessToken creates an access token for a user.
+// mfa records whether the user completed a second factor during this login.
+func (i *TokenIssuer) AccessToken(user *models.User, mfa bool) (string, error) {
+	now := time.Now()
+	return i.keys.Sign(&Claims{
+		Username: user.Username,
+		Role:     user.Role,
+		MFA:      mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    i.cfg.Issuer,
+			Subject:   user.ID.Hex(),
+			Audience:  jwt.ClaimStrings{i.cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(i.cfg.AccessTokenTTL)),
+		},
+	})
+}
+
+// ParseAccessToken verifies an access token's signature, issuer, audience and lifetime
+func (i *TokenIssuer) ParseAccessToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := i.keys.Parse(tokenString, claims,
+		jwt.WithIssuer(i.cfg.Issuer),
+		jwt.WithAudience(i.cfg.Audience),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.Purpose != "" || claims.Subject == "" || claims.IssuedAt == nil {
+		return nil, errNotAccessToken
+	}
+	return claims, nil
+}
+
+// ChallengeToken creates a token that only proves the password step of a 2FA login
+func (i *TokenIssuer) ChallengeToken(user *models.User) (string, error) {
+	return i.PurposeToken(PurposeTwoFactorChallenge, i.cfg.ChallengeTokenTTL, map[string]string{"id": user.ID.Hex()})
+}
+
+// ParseChallengeToken validates a challenge token and returns the user ID it was issued for
+func (i *TokenIssuer) ParseChallengeToken(tokenString string) (string, bool) {
+	data, ok := i.ParsePurposeToken(tokenString, PurposeTwoFactorChallenge)
+	if !ok || data["id"] == "" {
+		return "", false
+	}
+	return data["id"], true
+}
+
+// PurposeToken creates a short-lived signed token carrying data for a single purpose.
+// Purpose tokens are rejected by the JWT middleware.
+func (i *TokenIssuer) PurposeToken(purpose string, ttl time.Duration, data map[string]string) (string, error) {
+	claims := jwt.MapClaims{}
+	for k, v := range data {
+		claims[k] = v
+	}
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	return i.keys.Sign(claims)
+}
+
+// ParsePurposeToken validates a token created by PurposeToken for the same purpose and returns its data
+func (i *TokenIssuer) ParsePurposeToken(tokenString, purpose string) (map[string]string, bool) {
+	claims := jwt.MapClaims{}
+	token, err := i.keys.Parse(tokenString, claims)
+	if err != nil || !token.Valid || claims["purpose"] != purpose {
+		return nil, false
+	}
+
+	data := map[string]string{}
+	for k, v := range claims {
+		if s, ok := v.(string); ok && k != "purpose" {
+			data[k] = s
+		}
+	}
+	return data, true
+}
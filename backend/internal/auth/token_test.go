@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	testIssuer   = "futo-marching-dashboard"
+	testAudience = "futo-marching-dashboard-api"
+)
+
+var testTokenConfig = TokenConfig{
+	Issuer:            testIssuer,
+	Audience:          testAudience,
+	AccessTokenTTL:    DefaultAccessTokenTTL,
+	ChallengeTokenTTL: DefaultChallengeTokenTTL,
+}
+
+func TestParseAccessToken(t *testing.T) {
+	keys := NewHMACKeySet("secret")
+	tokens := NewTokenIssuer(keys, testTokenConfig)
+	user := testUser()
+
+	tokenString, err := tokens.AccessToken(user, true)
+	if err != nil {
+		t.Fatalf("Error signing token: %v", err)
+	}
+
+	claims, err := tokens.ParseAccessToken(tokenString)
+	if err != nil {
+		t.Fatalf("Expected access token to verify, got %v", err)
+	}
+	if claims.Subject != user.ID.Hex() || claims.Username != "hanako" || claims.Role != models.GeneralRole || !claims.MFA {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	t.Run("other issuer or audience", func(t *testing.T) {
+		for _, other := range []*TokenIssuer{
+			NewTokenIssuer(keys, TokenConfig{Issuer: "another-service", Audience: testAudience, AccessTokenTTL: time.Hour}),
+			NewTokenIssuer(keys, TokenConfig{Issuer: testIssuer, Audience: "another-api", AccessTokenTTL: time.Hour}),
+		} {
+			if _, err := other.ParseAccessToken(tokenString); err == nil {
+				t.Error("Expected token for another issuer or audience to be rejected")
+			}
+		}
+	})
+
+	t.Run("purpose token", func(t *testing.T) {
+		challenge, _ := tokens.ChallengeToken(user)
+		if _, err := tokens.ParseAccessToken(challenge); err == nil {
+			t.Error("Expected challenge token to be rejected as access token")
+		}
+	})
+
+	t.Run("missing subject", func(t *testing.T) {
+		now := time.Now()
+		forged, _ := keys.Sign(&Claims{Role: models.AdminRole, RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		}})
+		if _, err := tokens.ParseAccessToken(forged); err == nil {
+			t.Error("Expected token without subject to be rejected")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		past := time.Now().Add(-2 * DefaultAccessTokenTTL)
+		expired, _ := keys.Sign(&Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   user.ID.Hex(),
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(past),
+			ExpiresAt: jwt.NewNumericDate(past.Add(DefaultAccessTokenTTL)),
+		}})
+		if _, err := tokens.ParseAccessToken(expired); err == nil {
+			t.Error("Expected expired token to be rejected")
+		}
+	})
+}
+
+func TestCurrentUser(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+
+	if _, _, ok := CurrentUser(c); ok {
+		t.Error("Expected no current user without claims")
+	}
+
+	// Values of the wrong type must not panic
+	c.Set(claimsContextKey, jwt.MapClaims{"id": 42})
+	if _, _, ok := CurrentUser(c); ok {
+		t.Error("Expected no current user for untyped claims")
+	}
+
+	SetClaims(c, &Claims{Role: models.AdminRole, RegisteredClaims: jwt.RegisteredClaims{Subject: "abc"}})
+	id, role, ok := CurrentUser(c)
+	if !ok || id != "abc" || role != models.AdminRole {
+		t.Errorf("Unexpected current user %q %q %v", id, role, ok)
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key from the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, want := range vectors {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("Error generating code: %v", err)
+		}
+		if got != want {
+			t.Errorf("At %d: expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := TOTPStep(now)
+
+	previous, _ := TOTPCode(rfcSecret, step-1)
+	tooOld, _ := TOTPCode(rfcSecret, step-2)
+
+	if matched, ok := ValidateTOTP(rfcSecret, "005924", now, 0); !ok || matched != step {
+		t.Errorf("Expected current code to match step %d, got %d, %v", step, matched, ok)
+	}
+	if _, ok := ValidateTOTP(rfcSecret, previous, now, 0); !ok {
+		t.Error("Expected code from the previous step to be accepted")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, tooOld, now, 0); ok {
+		t.Error("Expected code from two steps ago to be rejected")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "005924", now, step); ok {
+		t.Error("Expected a replayed code to be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("FUTO Marching Dashboard", "hanako", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/FUTO%20Marching%20Dashboard:hanako?") {
+		t.Errorf("Unexpected URI label: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("URI is missing the secret: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("Error generating recovery codes: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("Expected %d codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	// Codes are accepted regardless of case and separators, but only once
+	remaining, ok := UseRecoveryCode(hashes, strings.ToUpper(strings.ReplaceAll(codes[3], "-", "")))
+	if !ok {
+		t.Fatal("Expected recovery code to be accepted")
+	}
+	if len(remaining) != recoveryCodeCount-1 {
+		t.Errorf("Expected %d remaining codes, got %d", recoveryCodeCount-1, len(remaining))
+	}
+	if _, ok := UseRecoveryCode(remaining, codes[3]); ok {
+		t.Error("Expected a used recovery code to be rejected")
+	}
+}
@@ -2,38 +2,201 @@ package config
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/middleware"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/notify"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/ratelimit"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/recurring"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/reminders"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/storage"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/webhooks"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"gopkg.in/yaml.v3"
 )
 
-// Config stores all configuration of the application
+// defaultJWTSecret is the placeholder secret that is only accepted in development
+const defaultJWTSecret = "your-secret-key"
+
+// placeholderJWTSecrets are published example secrets that are only accepted in development
+var placeholderJWTSecrets = map[string]bool{
+	defaultJWTSecret: true,
+	"your-secret-key-change-this-in-production": true,
+}
+
+// Valid values for settings that take one of a fixed set of values
+var (
+	appEnvs      = []string{"development", "test", "staging", "production"}
+	logLevels    = []string{"debug", "info", "warn", "error"}
+	limiterStore = []string{"memory", "mongo"}
+)
+
+// Config stores all configuration of the application.
+// Settings are read from defaults, then the YAML file named by CONFIG_FILE, then environment variables.
 type Config struct {
-	DBClient *mongo.Client
-	DBName   string
-	JWTSecret string
+	// AppEnv is the deployment environment: development, test, staging or production.
+	// It defaults to production, so development conveniences such as the placeholder secret need APP_ENV=development.
+	AppEnv string `yaml:"appEnv"`
+	// Port is the HTTP port the server listens on
+	Port int `yaml:"port"`
+	// ShutdownTimeout is how long in-flight requests and workers get to finish after SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// LogLevel is the minimum level that is logged: debug, info, warn or error
+	LogLevel string           `yaml:"logLevel"`
+	MongoURI string           `yaml:"mongoUri"`
+	DBName   string           `yaml:"dbName"`
+	CORS     CORSConfig       `yaml:"cors"`
+	JWT      JWTConfig        `yaml:"jwt"`
+	Tokens   auth.TokenConfig `yaml:"tokens"`
+	// MigrateOnStartup applies pending database migrations before the server starts
+	MigrateOnStartup bool `yaml:"migrateOnStartup"`
+	// RequireAdminTwoFactor makes 2FA mandatory for admin routes
+	RequireAdminTwoFactor bool `yaml:"requireAdminTwoFactor"`
+	// OIDC configures single sign-on; it is disabled when no issuer is set
+	OIDC      auth.OIDCConfig `yaml:"oidc"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	// TrustedProxies are the reverse proxies, as IPs or CIDR ranges, whose X-Forwarded-For is believed.
+	// When empty, the client address is the address of the connection.
+	TrustedProxies []string `yaml:"trustedProxies"`
+	// InvitationURL is the frontend page that accepts invitation tokens
+	InvitationURL string `yaml:"invitationUrl"`
+	// PDFFontPath is an optional TrueType font for PDF exports with Japanese text
+	PDFFontPath string `yaml:"pdfFontPath"`
+	// Tracing configures OpenTelemetry trace export; it is disabled by default
+	Tracing tracing.Config `yaml:"tracing"`
+	// Notifications configures delivery outside the app; email is disabled until an SMTP host is set
+	Notifications notify.Config `yaml:"notifications"`
+	// Reminders configures reminders before events and task due dates
+	Reminders reminders.Config `yaml:"reminders"`
+	// Webhooks configures retries of outgoing webhook deliveries
+	Webhooks webhooks.Config `yaml:"webhooks"`
+	// Attachments configures where task attachments are stored and how large they may be
+	Attachments storage.Config `yaml:"attachments"`
+	// TaskTemplates configures how often tasks are created from recurring templates
+	TaskTemplates recurring.Config `yaml:"taskTemplates"`
+
+	DBClient *mongo.Client `yaml:"-"`
+	// JWTKeys signs and verifies tokens; it uses the JWT secret unless a signing key file is set
+	JWTKeys *auth.KeySet `yaml:"-"`
+}
+
+// CORSConfig configures which browser origins may call the API
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+}
+
+// JWTConfig configures the keys tokens are signed with
+type JWTConfig struct {
+	// Secret is the HMAC secret used when no signing key file is set
+	Secret string `yaml:"secret"`
+	// SigningKeyFile is a PEM RSA or Ed25519 private key that signs new tokens
+	SigningKeyFile string `yaml:"signingKeyFile"`
+	// VerificationKeyFiles are retired keys whose tokens are still accepted during rotation
+	VerificationKeyFiles []string `yaml:"verificationKeyFiles"`
+}
+
+// RateLimitConfig configures login throttling
+type RateLimitConfig struct {
+	// Store selects where failed login counts are kept: "memory" or "mongo"
+	Store string           `yaml:"store"`
+	User  ratelimit.Policy `yaml:"user"`
+	IP    ratelimit.Policy `yaml:"ip"`
 }
 
-// LoadConfig reads configuration from environment variables
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		AppEnv:          "production",
+		Port:            8080,
+		ShutdownTimeout: 15 * time.Second,
+		LogLevel:        "info",
+		MongoURI:        "mongodb://localhost:27017",
+		DBName:          "futo_marching_dashboard",
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:3000"},
+		},
+		JWT: JWTConfig{
+			Secret: defaultJWTSecret,
+		},
+		Tokens: auth.TokenConfig{
+			Issuer:            "futo-marching-dashboard",
+			Audience:          "futo-marching-dashboard-api",
+			AccessTokenTTL:    auth.DefaultAccessTokenTTL,
+			ChallengeTokenTTL: auth.DefaultChallengeTokenTTL,
+		},
+		MigrateOnStartup: true,
+		OIDC: auth.OIDCConfig{
+			RedirectURL: "http://localhost:8080/api/auth/oidc/callback",
+		},
+		RateLimit: RateLimitConfig{
+			Store: "memory",
+			User:  ratelimit.DefaultUserPolicy,
+			IP:    ratelimit.DefaultIPPolicy,
+		},
+		InvitationURL: "http://localhost:3000/invite",
+		Tracing: tracing.Config{
+			ServiceName: "futo-marching-dashboard-backend",
+			SampleRatio: 1,
+		},
+		Notifications: notify.Config{
+			AppURL: "http://localhost:3000",
+			SMTP:   notify.SMTPConfig{Port: 587},
+		},
+		Reminders: reminders.Config{
+			Enabled:      true,
+			Interval:     time.Minute,
+			EventOffsets: []time.Duration{24 * time.Hour, time.Hour},
+			TaskOffsets:  []time.Duration{24 * time.Hour},
+		},
+		Webhooks: webhooks.Config{
+			MaxAttempts:  8,
+			BaseDelay:    30 * time.Second,
+			MaxDelay:     time.Hour,
+			Timeout:      10 * time.Second,
+			PollInterval: 5 * time.Second,
+		},
+		Attachments: storage.Config{
+			Dir:           "data/attachments",
+			MaxFileSizeMB: 10,
+		},
+		TaskTemplates: recurring.Config{
+			Enabled:  true,
+			Interval: time.Minute,
+		},
+	}
+}
+
+// LoadConfig reads and validates the configuration, loads the JWT keys and connects to MongoDB
 func LoadConfig() (*Config, error) {
-	// Load .env file if it exists
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	cfg, err := ReadConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	mongoURI := getEnv("MONGO_URI", "mongodb://localhost:27017")
-	dbName := getEnv("DB_NAME", "futo_marching_dashboard")
-	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
+	cfg.JWTKeys, err = cfg.loadJWTKeys()
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Connect to MongoDB
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	clientOpts := options.Client().ApplyURI(cfg.MongoURI)
+	if cfg.Tracing.Enabled {
+		clientOpts.SetMonitor(otelmongo.NewMonitor())
+	}
+	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -44,20 +207,246 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	return &Config{
-		DBClient:  client,
-		DBName:    dbName,
-		JWTSecret: jwtSecret,
-	}, nil
+	cfg.DBClient = client
+	return cfg, nil
+}
+
+// ReadConfig reads and validates the configuration from .env, the environment
+// and the optional YAML file without connecting to anything
+func ReadConfig() (*Config, error) {
+	// Load .env file if it exists
+	if err := godotenv.Load(); err != nil {
+		slog.Info("no .env file found, using environment variables")
+	}
+
+	return Load(os.LookupEnv)
+}
+
+// Load builds the configuration from defaults, the optional YAML file named by
+// CONFIG_FILE and environment variables, in increasing priority, and validates it
+func Load(lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	if path, ok := lookupEnv("CONFIG_FILE"); ok && path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	env := &envReader{lookup: lookupEnv}
+	cfg.applyEnv(env)
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
-// Get environment variable or return default value
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// loadFile reads settings from a YAML file. Unknown keys are rejected so typos are not ignored.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
 	}
-	return value
+	return nil
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (c *Config) applyEnv(env *envReader) {
+	env.string("APP_ENV", &c.AppEnv)
+	env.int("PORT", &c.Port)
+	env.duration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+	env.string("LOG_LEVEL", &c.LogLevel)
+	env.string("MONGO_URI", &c.MongoURI)
+	env.string("DB_NAME", &c.DBName)
+	env.bool("MIGRATE_ON_STARTUP", &c.MigrateOnStartup)
+	env.list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+
+	env.string("JWT_SECRET", &c.JWT.Secret)
+	env.string("JWT_SIGNING_KEY_FILE", &c.JWT.SigningKeyFile)
+	env.list("JWT_VERIFICATION_KEY_FILES", &c.JWT.VerificationKeyFiles)
+	env.string("JWT_ISSUER", &c.Tokens.Issuer)
+	env.string("JWT_AUDIENCE", &c.Tokens.Audience)
+	env.duration("JWT_ACCESS_TOKEN_TTL", &c.Tokens.AccessTokenTTL)
+	env.duration("JWT_CHALLENGE_TOKEN_TTL", &c.Tokens.ChallengeTokenTTL)
+	env.bool("REQUIRE_ADMIN_2FA", &c.RequireAdminTwoFactor)
+
+	env.string("OIDC_ISSUER_URL", &c.OIDC.IssuerURL)
+	env.string("OIDC_CLIENT_ID", &c.OIDC.ClientID)
+	env.string("OIDC_CLIENT_SECRET", &c.OIDC.ClientSecret)
+	env.string("OIDC_REDIRECT_URL", &c.OIDC.RedirectURL)
+	env.string("OIDC_ALLOWED_DOMAIN", &c.OIDC.AllowedDomain)
+	env.bool("OIDC_AUTO_PROVISION", &c.OIDC.AutoProvision)
+	env.string("OIDC_POST_LOGIN_REDIRECT", &c.OIDC.PostLoginRedirect)
+
+	env.string("LOGIN_LIMITER", &c.RateLimit.Store)
+	env.policy("LOGIN_USER_", &c.RateLimit.User)
+	env.policy("LOGIN_IP_", &c.RateLimit.IP)
+	env.list("TRUSTED_PROXIES", &c.TrustedProxies)
+
+	env.string("INVITATION_URL", &c.InvitationURL)
+	env.string("PDF_FONT_PATH", &c.PDFFontPath)
+
+	env.bool("TRACING_ENABLED", &c.Tracing.Enabled)
+	env.string("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	env.string("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	env.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	env.string("APP_URL", &c.Notifications.AppURL)
+	env.string("SMTP_HOST", &c.Notifications.SMTP.Host)
+	env.int("SMTP_PORT", &c.Notifications.SMTP.Port)
+	env.string("SMTP_USERNAME", &c.Notifications.SMTP.Username)
+	env.string("SMTP_PASSWORD", &c.Notifications.SMTP.Password)
+	env.string("SMTP_FROM", &c.Notifications.SMTP.From)
+
+	env.bool("REMINDERS_ENABLED", &c.Reminders.Enabled)
+	env.duration("REMINDER_INTERVAL", &c.Reminders.Interval)
+	env.durations("REMINDER_EVENT_OFFSETS", &c.Reminders.EventOffsets)
+	env.durations("REMINDER_TASK_OFFSETS", &c.Reminders.TaskOffsets)
+
+	env.int("WEBHOOK_MAX_ATTEMPTS", &c.Webhooks.MaxAttempts)
+	env.duration("WEBHOOK_BASE_DELAY", &c.Webhooks.BaseDelay)
+	env.duration("WEBHOOK_MAX_DELAY", &c.Webhooks.MaxDelay)
+	env.duration("WEBHOOK_TIMEOUT", &c.Webhooks.Timeout)
+	env.duration("WEBHOOK_POLL_INTERVAL", &c.Webhooks.PollInterval)
+
+	env.string("ATTACHMENTS_DIR", &c.Attachments.Dir)
+	env.int("ATTACHMENTS_MAX_FILE_SIZE_MB", &c.Attachments.MaxFileSizeMB)
+
+	env.bool("TASK_TEMPLATES_ENABLED", &c.TaskTemplates.Enabled)
+	env.duration("TASK_TEMPLATE_INTERVAL", &c.TaskTemplates.Interval)
+}
+
+// Validate checks every setting and reports all invalid values at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(oneOf(c.AppEnv, appEnvs), "appEnv must be one of %v, got %q", appEnvs, c.AppEnv)
+	check(c.Port > 0 && c.Port <= 65535, "port must be between 1 and 65535, got %d", c.Port)
+	check(c.ShutdownTimeout > 0, "shutdownTimeout must be positive")
+	check(oneOf(c.LogLevel, logLevels), "logLevel must be one of %v, got %q", logLevels, c.LogLevel)
+	check(c.MongoURI != "", "mongoUri is required")
+	check(c.DBName != "", "dbName is required")
+
+	check(len(c.CORS.AllowedOrigins) > 0, "cors.allowedOrigins must not be empty")
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			check(c.IsDevelopment(), "cors.allowedOrigins may only contain \"*\" in development")
+			continue
+		}
+		check(isOrigin(origin), "cors.allowedOrigins entry %q must be a scheme and host such as https://example.com", origin)
+	}
+
+	check(c.JWT.SigningKeyFile != "" || c.JWT.Secret != "", "jwt.secret or jwt.signingKeyFile is required")
+	check(c.Tokens.Issuer != "" && c.Tokens.Audience != "", "tokens.issuer and tokens.audience are required")
+	check(c.Tokens.AccessTokenTTL > 0, "tokens.accessTokenTTL must be positive")
+	check(c.Tokens.ChallengeTokenTTL > 0 && c.Tokens.ChallengeTokenTTL <= c.Tokens.AccessTokenTTL,
+		"tokens.challengeTokenTTL must be positive and not exceed tokens.accessTokenTTL")
+
+	if c.OIDC.Enabled() {
+		check(isURL(c.OIDC.IssuerURL), "oidc.issuerUrl must be an absolute URL")
+		check(isURL(c.OIDC.RedirectURL), "oidc.redirectUrl must be an absolute URL")
+		check(c.OIDC.PostLoginRedirect == "" || isURL(c.OIDC.PostLoginRedirect), "oidc.postLoginRedirect must be an absolute URL")
+	}
+	check(!c.OIDC.AutoProvision || c.OIDC.AllowedDomain != "", "oidc.autoProvision requires oidc.allowedDomain")
+
+	check(oneOf(c.RateLimit.Store, limiterStore), "rateLimit.store must be one of %v, got %q", limiterStore, c.RateLimit.Store)
+	if err := c.RateLimit.User.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimit.user: %w", err))
+	}
+	if err := c.RateLimit.IP.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimit.ip: %w", err))
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		_, err := middleware.ParseTrustedProxy(proxy)
+		check(err == nil, "trustedProxies entry %q must be an IP address or CIDR range", proxy)
+	}
+
+	check(isURL(c.InvitationURL), "invitationUrl must be an absolute URL")
+
+	if err := c.Tracing.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+	if err := c.Notifications.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("notifications: %w", err))
+	}
+	if err := c.Reminders.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("reminders: %w", err))
+	}
+	if err := c.Webhooks.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("webhooks: %w", err))
+	}
+	if err := c.Attachments.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("attachments: %w", err))
+	}
+	if err := c.TaskTemplates.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("taskTemplates: %w", err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// IsDevelopment reports whether the server runs in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.AppEnv == "development"
+}
+
+// loadJWTKeys loads the asymmetric signing keys when a signing key file is set
+// and falls back to the HMAC secret otherwise
+func (c *Config) loadJWTKeys() (*auth.KeySet, error) {
+	if c.JWT.SigningKeyFile != "" {
+		keys, err := auth.LoadKeySet(c.JWT.SigningKeyFile, c.JWT.VerificationKeyFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+		}
+		return keys, nil
+	}
+
+	if placeholderJWTSecrets[c.JWT.Secret] && !c.IsDevelopment() {
+		return nil, fmt.Errorf("JWT_SECRET must be changed from the default or JWT_SIGNING_KEY_FILE set when APP_ENV is %q", c.AppEnv)
+	}
+	return auth.NewHMACKeySet(c.JWT.Secret), nil
+}
+
+// oneOf reports whether value is in allowed
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// isURL reports whether s is an absolute http or https URL
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isOrigin reports whether s is a browser origin: a scheme and host without a path
+func isOrigin(s string) bool {
+	u, err := url.Parse(s)
+	return isURL(s) && err == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.User == nil
 }
 
 // Close database connection
@@ -65,4 +454,4 @@ func (c *Config) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	return c.DBClient.Disconnect(ctx)
-}
\ No newline at end of file
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envMap returns a lookup function over a fixed set of variables
+func envMap(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(envMap(nil))
+	if err != nil {
+		t.Fatalf("Expected defaults to be valid, got %v", err)
+	}
+
+	if cfg.Port != 8080 || cfg.AppEnv != "production" || cfg.RateLimit.Store != "memory" {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "http://localhost:3000" {
+		t.Errorf("Expected the bundled frontend to be allowed, got %v", cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestLoadFileAndEnvPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+port: 9000
+logLevel: debug
+cors:
+  allowedOrigins:
+    - https://dashboard.example.jp
+tokens:
+  accessTokenTTL: 12h
+rateLimit:
+  store: mongo
+  user:
+    maxFailures: 5
+reminders:
+  eventOffsets: [48h, 2h]
+`)
+
+	cfg, err := Load(envMap(map[string]string{
+		"CONFIG_FILE":                 path,
+		"PORT":                        "9100",
+		"CORS_ALLOWED_ORIGINS":        "https://dashboard.example.jp, http://localhost:3000",
+		"LOGIN_USER_LOCKOUT_DURATION": "1h",
+		"REMINDER_TASK_OFFSETS":       "12h, 30m",
+	}))
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+
+	if cfg.Port != 9100 {
+		t.Errorf("Expected environment to override the file, got port %d", cfg.Port)
+	}
+	if cfg.LogLevel != "debug" || cfg.Tokens.AccessTokenTTL != 12*time.Hour || cfg.RateLimit.Store != "mongo" {
+		t.Errorf("Expected file values to override defaults: %+v", cfg)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 2 || cfg.CORS.AllowedOrigins[1] != "http://localhost:3000" {
+		t.Errorf("Unexpected origins %v", cfg.CORS.AllowedOrigins)
+	}
+	// Fields missing from the file keep their defaults
+	if cfg.RateLimit.User.MaxFailures != 5 || cfg.RateLimit.User.FreeAttempts != 3 || cfg.RateLimit.User.LockoutDuration != time.Hour {
+		t.Errorf("Unexpected user policy %+v", cfg.RateLimit.User)
+	}
+	if got := cfg.Reminders.EventOffsets; len(got) != 2 || got[0] != 48*time.Hour || got[1] != 2*time.Hour {
+		t.Errorf("Unexpected event reminder offsets %v", got)
+	}
+	if got := cfg.Reminders.TaskOffsets; len(got) != 2 || got[0] != 12*time.Hour || got[1] != 30*time.Minute {
+		t.Errorf("Unexpected task reminder offsets %v", got)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{"port", map[string]string{"PORT": "http"}, "PORT"},
+		{"port range", map[string]string{"PORT": "70000"}, "port"},
+		{"log level", map[string]string{"LOG_LEVEL": "verbose"}, "logLevel"},
+		{"app env", map[string]string{"APP_ENV": "prod"}, "appEnv"},
+		{"origin", map[string]string{"CORS_ALLOWED_ORIGINS": "localhost:3000"}, "cors.allowedOrigins"},
+		{"wildcard outside development", map[string]string{"APP_ENV": "production", "CORS_ALLOWED_ORIGINS": "*"}, "cors.allowedOrigins"},
+		{"ttl", map[string]string{"JWT_ACCESS_TOKEN_TTL": "3 days"}, "JWT_ACCESS_TOKEN_TTL"},
+		{"challenge ttl", map[string]string{"JWT_CHALLENGE_TOKEN_TTL": "100h"}, "challengeTokenTTL"},
+		{"store", map[string]string{"LOGIN_LIMITER": "redis"}, "rateLimit.store"},
+		{"policy", map[string]string{"LOGIN_IP_MAX_FAILURES": "1"}, "rateLimit.ip"},
+		{"auto provision", map[string]string{"OIDC_AUTO_PROVISION": "true"}, "oidc.autoProvision"},
+		{"bool", map[string]string{"REQUIRE_ADMIN_2FA": "sometimes"}, "REQUIRE_ADMIN_2FA"},
+		{"sample ratio", map[string]string{"TRACING_SAMPLE_RATIO": "1.5"}, "tracing: sampleRatio"},
+		{"tracing endpoint", map[string]string{"TRACING_ENDPOINT": "otel-collector:4318"}, "tracing: endpoint"},
+		{"smtp sender", map[string]string{"SMTP_HOST": "smtp.example.jp", "SMTP_FROM": "dashboard"}, "notifications: smtp.from"},
+		{"attachment size", map[string]string{"ATTACHMENTS_MAX_FILE_SIZE_MB": "0"}, "attachments: maxFileSizeMB"},
+		{"webhook attempts", map[string]string{"WEBHOOK_MAX_ATTEMPTS": "0"}, "webhooks: maxAttempts"},
+		{"trusted proxy", map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"}, "trustedProxies"},
+		{"task template interval", map[string]string{"TASK_TEMPLATE_INTERVAL": "0s"}, "taskTemplates: interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(envMap(tt.vars))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := writeConfigFile(t, "cors:\n  allowedOrigin: https://dashboard.example.jp\n")
+
+	if _, err := Load(envMap(map[string]string{"CONFIG_FILE": path})); err == nil {
+		t.Error("Expected a misspelled key to be rejected")
+	}
+}
+
+func TestLoadJWTKeysRejectsPlaceholderSecret(t *testing.T) {
+	// Leaving APP_ENV unset is treated as production
+	for _, vars := range []map[string]string{nil, {"APP_ENV": "staging"}, {"APP_ENV": "production"}} {
+		cfg, err := Load(envMap(vars))
+		if err != nil {
+			t.Fatalf("Error loading config: %v", err)
+		}
+		if _, err := cfg.loadJWTKeys(); err == nil {
+			t.Errorf("Expected the default secret to be refused with %v", vars)
+		}
+	}
+
+	cfg, _ := Load(envMap(map[string]string{"APP_ENV": "development"}))
+	if _, err := cfg.loadJWTKeys(); err != nil {
+		t.Errorf("Expected the default secret to be accepted in development, got %v", err)
+	}
+
+	cfg, _ = Load(envMap(map[string]string{"APP_ENV": "production", "JWT_SECRET": "a-real-secret"}))
+	if _, err := cfg.loadJWTKeys(); err != nil {
+		t.Errorf("Expected a custom secret to be accepted, got %v", err)
+	}
+}
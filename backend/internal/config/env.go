@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/ratelimit"
+)
+
+// envReader overrides settings from environment variables and collects parse errors.
+// Variables that are unset or empty leave the setting unchanged.
+type envReader struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+// get returns the trimmed value of a variable that is set and not empty
+func (e *envReader) get(key string) (string, bool) {
+	value, ok := e.lookup(key)
+	value = strings.TrimSpace(value)
+	return value, ok && value != ""
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value, ok := e.get(key); ok {
+		*dst = value
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	if value, ok := e.get(key); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: expected an integer, got %q", key, value))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	if value, ok := e.get(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: expected true or false, got %q", key, value))
+			return
+		}
+		*dst = b
+	}
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	if value, ok := e.get(key); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: expected a duration such as 30m, got %q", key, value))
+			return
+		}
+		*dst = d
+	}
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	if value, ok := e.get(key); ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: expected a number, got %q", key, value))
+			return
+		}
+		*dst = f
+	}
+}
+
+// list reads a comma-separated list
+func (e *envReader) list(key string, dst *[]string) {
+	if value, ok := e.get(key); ok {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+	}
+}
+
+// durations reads a comma-separated list of durations
+func (e *envReader) durations(key string, dst *[]time.Duration) {
+	var items []string
+	e.list(key, &items)
+	if items == nil {
+		return
+	}
+
+	durations := make([]time.Duration, 0, len(items))
+	for _, item := range items {
+		d, err := time.ParseDuration(item)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s: expected durations such as 24h,1h, got %q", key, item))
+			return
+		}
+		durations = append(durations, d)
+	}
+	*dst = durations
+}
+
+// policy reads the fields of a throttling policy from variables starting with prefix
+func (e *envReader) policy(prefix string, dst *ratelimit.Policy) {
+	e.int(prefix+"FREE_ATTEMPTS", &dst.FreeAttempts)
+	e.duration(prefix+"BASE_DELAY", &dst.BaseDelay)
+	e.duration(prefix+"MAX_DELAY", &dst.MaxDelay)
+	e.int(prefix+"MAX_FAILURES", &dst.MaxFailures)
+	e.duration(prefix+"LOCKOUT_DURATION", &dst.LockoutDuration)
+	e.duration(prefix+"WINDOW", &dst.Window)
+}
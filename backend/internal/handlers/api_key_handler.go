@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// APIKeyHandler handles HTTP requests for managing API keys
+type APIKeyHandler struct {
+	apiKeyRepo repositories.APIKeyRepository
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler
+func NewAPIKeyHandler(apiKeyRepo repositories.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyRepo: apiKeyRepo}
+}
+
+// GetAllAPIKeys lists all API keys without their secrets
+func (h *APIKeyHandler) GetAllAPIKeys(c echo.Context) error {
+	keys, err := h.apiKeyRepo.FindAll(c.Request().Context())
+	if err != nil {
+		return internalError(c, "Failed to get API keys", err)
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey creates an API key. The key is only shown in this response.
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	var input models.CreateAPIKeyInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Name is required"})
+	}
+
+	if len(input.Scopes) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "At least one scope is required"})
+	}
+
+	scopes := []string{}
+	for _, scope := range input.Scopes {
+		if !models.IsValidAPIKeyScope(scope) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown scope: " + scope})
+		}
+		if !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Expiry must be in the future"})
+	}
+
+	currentUserID, _, _ := auth.CurrentUser(c)
+	createdBy, _ := primitive.ObjectIDFromHex(currentUserID)
+
+	apiKey, key, err := models.NewAPIKey(input.Name, scopes, input.ExpiresAt, createdBy)
+	if err != nil {
+		return internalError(c, "Failed to generate API key", err)
+	}
+
+	if _, err := h.apiKeyRepo.Create(c.Request().Context(), apiKey); err != nil {
+		return internalError(c, "Failed to create API key", err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"apiKey": apiKey,
+		"key":    key,
+	})
+}
+
+// RevokeAPIKey disables an API key
+func (h *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	err := h.apiKeyRepo.Revoke(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "API key not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to revoke API key", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
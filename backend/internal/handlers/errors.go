@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/labstack/echo/v4"
+)
+
+// internalError logs the cause of a failed request with the request logger and
+// responds with a 500 that does not reveal it
+func internalError(c echo.Context, message string, err error) error {
+	ctx := c.Request().Context()
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, message, "error", err)
+	} else {
+		logging.FromContext(ctx).ErrorContext(ctx, message)
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": message})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// readinessTimeout bounds how long a readiness probe waits for MongoDB
+const readinessTimeout = 2 * time.Second
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	client       *mongo.Client
+	shuttingDown atomic.Bool
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(client *mongo.Client) *HealthHandler {
+	return &HealthHandler{client: client}
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop sending traffic while requests drain
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Healthz reports that the process is alive
+func (h *HealthHandler) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the server can handle requests, which requires MongoDB
+func (h *HealthHandler) Readyz(c echo.Context) error {
+	if h.shuttingDown.Load() {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), readinessTimeout)
+	defer cancel()
+
+	if err := h.client.Ping(ctx, nil); err != nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": "Database is not reachable"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InvitationHandler handles HTTP requests related to invitations
+type InvitationHandler struct {
+	userRepo       repositories.UserRepository
+	invitationRepo repositories.InvitationRepository
+}
+
+// NewInvitationHandler creates a new InvitationHandler
+func NewInvitationHandler(userRepo repositories.UserRepository, invitationRepo repositories.InvitationRepository) *InvitationHandler {
+	return &InvitationHandler{
+		userRepo:       userRepo,
+		invitationRepo: invitationRepo,
+	}
+}
+
+// AcceptInvitation sets the password of an invited user
+func (h *InvitationHandler) AcceptInvitation(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var input models.AcceptInvitationInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if len(input.Password) < 6 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Password must be at least 6 characters"})
+	}
+
+	invitation, err := h.invitationRepo.FindByTokenHash(ctx, models.HashInvitationToken(input.Token))
+	if err != nil {
+		return internalError(c, "Failed to get invitation", err)
+	}
+
+	if invitation == nil || !invitation.IsUsable() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired invitation"})
+	}
+
+	user, err := h.userRepo.FindByID(ctx, invitation.UserID.Hex())
+	if err != nil {
+		return internalError(c, "Failed to get user", err)
+	}
+
+	if user == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired invitation"})
+	}
+
+	user.Password = input.Password
+	if err := user.HashPassword(); err != nil {
+		return internalError(c, "Failed to hash password", err)
+	}
+
+	user.PrepareUpdate()
+
+	// Claim the invitation first so concurrent requests with the same link cannot both set a password
+	err = h.invitationRepo.MarkAccepted(ctx, invitation.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired invitation"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to accept invitation", err)
+	}
+
+	if err := h.userRepo.Update(ctx, user.ID.Hex(), user); err != nil {
+		// Release the claim so a failed update does not use up the link
+		if releaseErr := h.invitationRepo.ReleaseAccepted(ctx, invitation.ID); releaseErr != nil {
+			err = errors.Join(err, releaseErr)
+		}
+		return internalError(c, "Failed to update user", err)
+	}
+	metrics.InvitationsAccepted.Inc()
+
+	return c.NoContent(http.StatusNoContent)
+}
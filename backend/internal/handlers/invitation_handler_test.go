@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeInvitations keeps one invitation in memory
+type fakeInvitations struct {
+	repositories.InvitationRepository
+	invitation *models.Invitation
+}
+
+func (f *fakeInvitations) FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	if f.invitation.TokenHash != tokenHash {
+		return nil, nil
+	}
+	copied := *f.invitation
+	return &copied, nil
+}
+
+func (f *fakeInvitations) MarkAccepted(ctx context.Context, id primitive.ObjectID) error {
+	if f.invitation.AcceptedAt != nil {
+		return mongo.ErrNoDocuments
+	}
+	now := time.Now()
+	f.invitation.AcceptedAt = &now
+	return nil
+}
+
+func (f *fakeInvitations) ReleaseAccepted(ctx context.Context, id primitive.ObjectID) error {
+	f.invitation.AcceptedAt = nil
+	return nil
+}
+
+// fakeInvitedUsers stores the invited user's password, failing while updateErr is set
+type fakeInvitedUsers struct {
+	repositories.UserRepository
+	user      *models.User
+	updateErr error
+	updates   int
+}
+
+func (f *fakeInvitedUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	copied := *f.user
+	return &copied, nil
+}
+
+func (f *fakeInvitedUsers) Update(ctx context.Context, id string, user *models.User) error {
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	f.updates++
+	f.user.Password = user.Password
+	return nil
+}
+
+// acceptInvitation posts an invitation token and new password to the handler
+func acceptInvitation(h *InvitationHandler, token, password string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/invitations/accept", strings.NewReader(`{"token":"`+token+`","password":"`+password+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return rec, h.AcceptInvitation(echo.New().NewContext(req, rec))
+}
+
+func TestAcceptInvitationUsesTheLinkOnce(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Username: "hanako"}
+	invitations := &fakeInvitations{invitation: &models.Invitation{
+		ID: primitive.NewObjectID(), UserID: user.ID, TokenHash: models.HashInvitationToken("token"), ExpiresAt: time.Now().Add(time.Hour),
+	}}
+	users := &fakeInvitedUsers{user: user, updateErr: errors.New("connection reset")}
+	h := NewInvitationHandler(users, invitations)
+
+	// A failed password update leaves the link usable
+	rec, err := acceptInvitation(h, "token", "first-password")
+	checkStatus(t, err, rec, http.StatusInternalServerError)
+	if invitations.invitation.AcceptedAt != nil {
+		t.Fatal("Expected the invitation to be released after the failed update")
+	}
+
+	users.updateErr = nil
+	rec, err = acceptInvitation(h, "token", "second-password")
+	checkStatus(t, err, rec, http.StatusNoContent)
+
+	// The used link cannot set the password again
+	rec, err = acceptInvitation(h, "token", "third-password")
+	checkStatus(t, err, rec, http.StatusBadRequest)
+	if users.updates != 1 || !user.CheckPassword("second-password") {
+		t.Errorf("Expected only the accepted request to set the password, got %d updates", users.updates)
+	}
+}
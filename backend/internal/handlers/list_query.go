@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// parseListQuery reads the list query parameters shared by all list endpoints:
+// q (search text), sort, order (asc|desc), limit and cursor
+func parseListQuery(c echo.Context, allowedSorts []string, defaultSort string) (models.ListQuery, error) {
+	query := models.ListQuery{
+		Search: c.QueryParam("q"),
+		Sort:   c.QueryParam("sort"),
+		Cursor: c.QueryParam("cursor"),
+	}
+
+	switch c.QueryParam("order") {
+	case "", "asc":
+		query.Order = models.SortAsc
+	case "desc":
+		query.Order = models.SortDesc
+	default:
+		return query, errors.New("Invalid order, expected asc or desc")
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return query, errors.New("Invalid limit")
+		}
+		query.Limit = n
+	}
+
+	if !query.Normalize(allowedSorts, defaultSort) {
+		return query, errors.New("Invalid sort field")
+	}
+
+	return query, nil
+}
+
+// parseUserFilter reads the user listing filters from the request
+func parseUserFilter(c echo.Context) (models.UserFilter, error) {
+	filter := models.UserFilter{
+		Role:       models.Role(c.QueryParam("role")),
+		Section:    c.QueryParam("section"),
+		Instrument: c.QueryParam("instrument"),
+	}
+
+	switch filter.Role {
+	case "", models.AdminRole, models.GeneralRole:
+	default:
+		return filter, errors.New("Invalid role")
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NotificationHandler handles HTTP requests for the current user's inbox and notification preferences
+type NotificationHandler struct {
+	notificationRepo repositories.NotificationRepository
+	preferencesRepo  repositories.NotificationPreferencesRepository
+}
+
+// NewNotificationHandler creates a new NotificationHandler
+func NewNotificationHandler(notificationRepo repositories.NotificationRepository, preferencesRepo repositories.NotificationPreferencesRepository) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: notificationRepo, preferencesRepo: preferencesRepo}
+}
+
+// currentUserObjectID returns the ID of the authenticated user
+func currentUserObjectID(c echo.Context) (primitive.ObjectID, bool) {
+	userID, _, ok := auth.CurrentUser(c)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(userID)
+	return id, err == nil
+}
+
+// GetNotifications lists the current user's notifications, newest first unless another order is requested
+func (h *NotificationHandler) GetNotifications(c echo.Context) error {
+	userID, ok := currentUserObjectID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	query, err := parseListQuery(c, []string{"createdAt"}, "createdAt")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if c.QueryParam("order") == "" {
+		query.Order = models.SortDesc
+	}
+
+	result, err := h.notificationRepo.List(c.Request().Context(), userID, c.QueryParam("unread") == "true", query)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to get notifications", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// GetUnreadCount counts the current user's unread notifications
+func (h *NotificationHandler) GetUnreadCount(c echo.Context) error {
+	userID, ok := currentUserObjectID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	count, err := h.notificationRepo.CountUnread(c.Request().Context(), userID)
+	if err != nil {
+		return internalError(c, "Failed to count notifications", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]int64{"count": count})
+}
+
+// MarkRead marks one of the current user's notifications as read
+func (h *NotificationHandler) MarkRead(c echo.Context) error {
+	userID, ok := currentUserObjectID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Notification not found"})
+	}
+
+	err = h.notificationRepo.MarkRead(c.Request().Context(), userID, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Notification not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to mark notification as read", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// MarkAllRead marks all of the current user's notifications as read
+func (h *NotificationHandler) MarkAllRead(c echo.Context) error {
+	userID, ok := currentUserObjectID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	if err := h.notificationRepo.MarkAllRead(c.Request().Context(), userID); err != nil {
+		return internalError(c, "Failed to mark notifications as read", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetPreferences returns the current user's notification preferences, or the defaults if they have not saved any
+func (h *NotificationHandler) GetPreferences(c echo.Context) error {
+	userID, ok := currentUserObjectID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	preferences, err := h.preferencesRepo.FindByUserID(c.Request().Context(), userID)
+	if err != nil {
+		return internalError(c, "Failed to get notification preferences", err)
+	}
+	if preferences == nil {
+		preferences = models.DefaultNotificationPreferences(userID)
+	}
+	// Leave out types saved before they were hidden for having nothing that sends them
+	for t := range preferences.Channels {
+		if !models.IsValidNotificationType(t) {
+			delete(preferences.Channels, t)
+		}
+	}
+
+	return c.JSON(http.StatusOK, preferences)
+}
+
+// UpdatePreferences replaces the current user's notification preferences.
+// Types left out of the channels are only delivered to the inbox.
+func (h *NotificationHandler) UpdatePreferences(c echo.Context) error {
+	userID, ok := currentUserObjectID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	var input models.UpdateNotificationPreferencesInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	channels := map[models.NotificationType][]models.NotificationChannel{}
+	for t, list := range input.Channels {
+		if !models.IsValidNotificationType(t) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown notification type: " + string(t)})
+		}
+		channels[t] = []models.NotificationChannel{}
+		for _, ch := range list {
+			if !models.IsValidNotificationChannel(ch) {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown notification channel: " + string(ch)})
+			}
+			if !containsChannel(channels[t], ch) {
+				channels[t] = append(channels[t], ch)
+			}
+		}
+	}
+
+	if input.WebhookURL != "" && !isHTTPSURL(input.WebhookURL) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Webhook URL must be an https URL"})
+	}
+	if input.ChatWebhookURL != "" && !isHTTPSURL(input.ChatWebhookURL) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Chat webhook URL must be an https URL"})
+	}
+
+	preferences := &models.NotificationPreferences{
+		UserID:         userID,
+		Channels:       channels,
+		WebhookURL:     input.WebhookURL,
+		ChatWebhookURL: input.ChatWebhookURL,
+		UpdatedAt:      time.Now(),
+	}
+	if err := h.preferencesRepo.Save(c.Request().Context(), preferences); err != nil {
+		return internalError(c, "Failed to save notification preferences", err)
+	}
+
+	return c.JSON(http.StatusOK, preferences)
+}
+
+// containsChannel reports whether ch is in list
+func containsChannel(list []models.NotificationChannel, ch models.NotificationChannel) bool {
+	for _, v := range list {
+		if v == ch {
+			return true
+		}
+	}
+	return false
+}
+
+// isHTTPSURL reports whether s is an absolute https URL
+func isHTTPSURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// oidcFlowCookie carries the signed state, nonce and PKCE verifier between redirects
+	oidcFlowCookie = "oidc_flow"
+	// oidcFlowTTL is how long a user has to finish signing in at the provider
+	oidcFlowTTL = 10 * time.Minute
+)
+
+var (
+	errNoLinkedAccount = errors.New("no account is linked to this email")
+	errSubjectMismatch = errors.New("account is linked to a different identity")
+)
+
+// usernameInvalidChars matches characters not allowed in provisioned usernames
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]`)
+
+// OIDCHandler handles single sign-on through an OpenID Connect provider
+type OIDCHandler struct {
+	userRepo repositories.UserRepository
+	tokens   *auth.TokenIssuer
+	provider *auth.OIDCProvider
+	cfg      auth.OIDCConfig
+}
+
+// NewOIDCHandler creates a new OIDCHandler
+func NewOIDCHandler(userRepo repositories.UserRepository, tokens *auth.TokenIssuer, provider *auth.OIDCProvider, cfg auth.OIDCConfig) *OIDCHandler {
+	return &OIDCHandler{
+		userRepo: userRepo,
+		tokens:   tokens,
+		provider: provider,
+		cfg:      cfg,
+	}
+}
+
+// Login redirects the user to the provider to sign in
+func (h *OIDCHandler) Login(c echo.Context) error {
+	state, err := auth.RandomString()
+	if err != nil {
+		return internalError(c, "Failed to start login", err)
+	}
+	nonce, err := auth.RandomString()
+	if err != nil {
+		return internalError(c, "Failed to start login", err)
+	}
+	verifier, err := auth.RandomString()
+	if err != nil {
+		return internalError(c, "Failed to start login", err)
+	}
+
+	flow, err := h.tokens.PurposeToken(auth.PurposeOIDCState, oidcFlowTTL, map[string]string{
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+	})
+	if err != nil {
+		return internalError(c, "Failed to start login", err)
+	}
+
+	c.SetCookie(h.flowCookie(c, flow, int(oidcFlowTTL.Seconds())))
+
+	return c.Redirect(http.StatusFound, h.provider.AuthCodeURL(state, nonce, verifier))
+}
+
+// Callback finishes the login after the provider redirects back with an authorization code
+func (h *OIDCHandler) Callback(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	cookie, err := c.Cookie(oidcFlowCookie)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Login session expired, please try again"})
+	}
+	c.SetCookie(h.flowCookie(c, "", -1))
+
+	flow, ok := h.tokens.ParsePurposeToken(cookie.Value, auth.PurposeOIDCState)
+	if !ok || c.QueryParam("state") == "" || c.QueryParam("state") != flow["state"] {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid login state"})
+	}
+
+	if c.QueryParam("error") != "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Login was not completed at the identity provider"})
+	}
+
+	identity, err := h.provider.Exchange(ctx, c.QueryParam("code"), flow["verifier"], flow["nonce"])
+	if err != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginMethodOIDC, metrics.LoginFailure).Inc()
+	}
+	if errors.Is(err, auth.ErrUnverifiedEmail) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Email address is not verified"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Failed to verify identity"})
+	}
+
+	user, err := h.findOrProvision(ctx, identity)
+	if errors.Is(err, errNoLinkedAccount) || errors.Is(err, errSubjectMismatch) {
+		metrics.Logins.WithLabelValues(metrics.LoginMethodOIDC, metrics.LoginFailure).Inc()
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "No account is available for this identity"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to sign in", err)
+	}
+
+	// Single sign-on replaces the password, not the second factor
+	if user.TwoFactorEnabled {
+		challenge, err := h.tokens.ChallengeToken(user)
+		if err != nil {
+			return internalError(c, "Failed to generate token", err)
+		}
+		metrics.Logins.WithLabelValues(metrics.LoginMethodOIDC, metrics.LoginChallenged).Inc()
+		return h.respond(c, "challengeToken", challenge)
+	}
+
+	token, err := h.tokens.AccessToken(user, false)
+	if err != nil {
+		return internalError(c, "Failed to generate token", err)
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginMethodOIDC, metrics.LoginSuccess).Inc()
+	return h.respond(c, "token", token)
+}
+
+// findOrProvision returns the user linked to an identity, linking by email on
+// first login and creating the account when auto-provisioning allows it
+func (h *OIDCHandler) findOrProvision(ctx context.Context, identity *auth.OIDCIdentity) (*models.User, error) {
+	user, err := h.userRepo.FindByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		if user.OIDCSubject != "" && user.OIDCSubject != identity.Subject {
+			return nil, errSubjectMismatch
+		}
+		if user.OIDCSubject == "" {
+			user.OIDCSubject = identity.Subject
+			user.PrepareUpdate()
+			if err := h.userRepo.Update(ctx, user.ID.Hex(), user); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+
+	if !h.cfg.AutoProvision || h.cfg.AllowedDomain == "" || !identity.InDomain(h.cfg.AllowedDomain) {
+		return nil, errNoLinkedAccount
+	}
+
+	username, err := h.availableUsername(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	fullName := identity.Name
+	if fullName == "" {
+		fullName = username
+	}
+
+	user = &models.User{
+		Username:    username,
+		FullName:    fullName,
+		Email:       identity.Email,
+		Role:        models.GeneralRole,
+		OIDCSubject: identity.Subject,
+	}
+	user.PrepareCreate()
+
+	id, err := h.userRepo.Create(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	// A token must never be issued for an account that was not stored
+	if user.ID, err = primitive.ObjectIDFromHex(id); err != nil || user.ID.IsZero() {
+		return nil, fmt.Errorf("invalid id %q for provisioned user", id)
+	}
+	metrics.UsersCreated.WithLabelValues(metrics.UserSourceOIDC).Inc()
+
+	return user, nil
+}
+
+// availableUsername derives an unused username from the local part of an email
+func (h *OIDCHandler) availableUsername(ctx context.Context, email string) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(strings.ToLower(strings.SplitN(email, "@", 2)[0]), "")
+	if base == "" {
+		base = "member"
+	}
+
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+
+		existing, err := h.userRepo.FindByUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no username available for %s", email)
+}
+
+// respond hands a token to the frontend through the URL fragment, or as JSON
+// when no post-login page is configured
+func (h *OIDCHandler) respond(c echo.Context, key, value string) error {
+	if h.cfg.PostLoginRedirect == "" {
+		return c.JSON(http.StatusOK, map[string]string{key: value})
+	}
+	return c.Redirect(http.StatusFound, h.cfg.PostLoginRedirect+"#"+url.Values{key: {value}}.Encode())
+}
+
+// flowCookie builds the cookie that carries the login flow state.
+// A negative maxAge deletes the cookie.
+func (h *OIDCHandler) flowCookie(c echo.Context, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
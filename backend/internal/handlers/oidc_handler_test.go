@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeOIDCUsers stores users by email; createID overrides the ID Create reports
+type fakeOIDCUsers struct {
+	repositories.UserRepository
+	users    []*models.User
+	updated  int
+	createID *string
+}
+
+func (f *fakeOIDCUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeOIDCUsers) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeOIDCUsers) Update(ctx context.Context, id string, user *models.User) error {
+	for i, u := range f.users {
+		if u.ID.Hex() == id {
+			f.users[i] = user
+			f.updated++
+		}
+	}
+	return nil
+}
+
+func (f *fakeOIDCUsers) Create(ctx context.Context, user *models.User) (string, error) {
+	if f.createID != nil {
+		return *f.createID, nil
+	}
+	user.ID = primitive.NewObjectID()
+	f.users = append(f.users, user)
+	return user.ID.Hex(), nil
+}
+
+func TestFindOrProvision(t *testing.T) {
+	ctx := context.Background()
+	existing := &models.User{ID: primitive.NewObjectID(), Username: "hanako", Email: "h.sato@example.com"}
+	users := &fakeOIDCUsers{users: []*models.User{existing}}
+	h := &OIDCHandler{userRepo: users, cfg: auth.OIDCConfig{AutoProvision: true, AllowedDomain: "example.com"}}
+
+	// The first sign-in links the existing account and saves the link
+	user, err := h.findOrProvision(ctx, &auth.OIDCIdentity{Subject: "sub-1", Email: "h.sato@example.com"})
+	if err != nil || user.ID != existing.ID || users.updated != 1 || users.users[0].OIDCSubject != "sub-1" {
+		t.Fatalf("Expected the account to be linked and saved, got %+v (%v)", user, err)
+	}
+	if _, err := h.findOrProvision(ctx, &auth.OIDCIdentity{Subject: "sub-2", Email: "h.sato@example.com"}); err != errSubjectMismatch {
+		t.Errorf("Expected another identity with the same email to be refused, got %v", err)
+	}
+
+	user, err = h.findOrProvision(ctx, &auth.OIDCIdentity{Subject: "sub-3", Email: "hanako@example.com.evil"})
+	if err != errNoLinkedAccount {
+		t.Errorf("Expected an identity outside the domain to be refused, got %+v (%v)", user, err)
+	}
+
+	user, err = h.findOrProvision(ctx, &auth.OIDCIdentity{Subject: "sub-4", Email: "hanako@example.com"})
+	if err != nil || user.ID.IsZero() || user.Username != "hanako2" {
+		t.Fatalf("Expected a new account with a free username, got %+v (%v)", user, err)
+	}
+
+	// A store that reports no ID must fail the sign-in rather than issue a token for a zero ID
+	empty := ""
+	users.createID = &empty
+	if user, err := h.findOrProvision(ctx, &auth.OIDCIdentity{Subject: "sub-5", Email: "jiro@example.com"}); err == nil {
+		t.Errorf("Expected provisioning without an ID to fail, got %+v", user)
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/roster"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxRosterFileSize is the largest roster upload accepted
+const maxRosterFileSize = 5 << 20
+
+// MaxRosterRequestSize is the largest request body a roster import may have
+const MaxRosterRequestSize = maxRosterFileSize + multipartOverhead
+
+// RosterHandler handles HTTP requests for bulk roster management
+type RosterHandler struct {
+	userRepo       repositories.UserRepository
+	invitationRepo repositories.InvitationRepository
+	invitationURL  string
+	pdfFontPath    string
+}
+
+// NewRosterHandler creates a new RosterHandler
+func NewRosterHandler(userRepo repositories.UserRepository, invitationRepo repositories.InvitationRepository, invitationURL, pdfFontPath string) *RosterHandler {
+	return &RosterHandler{
+		userRepo:       userRepo,
+		invitationRepo: invitationRepo,
+		invitationURL:  invitationURL,
+		pdfFontPath:    pdfFontPath,
+	}
+}
+
+// ImportUsers validates a CSV or XLSX roster and, unless dryRun is set, creates
+// the accounts and an invitation link for each of them.
+//
+// The multipart form takes a "file", an optional "mapping" JSON object from
+// column header to user field, and "dryRun" which defaults to true. Accounts
+// are only created when every row is valid.
+func (h *RosterHandler) ImportUsers(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	dryRun := true
+	if v := c.FormValue("dryRun"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid dryRun value"})
+		}
+		dryRun = b
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Roster file is required"})
+	}
+	if fileHeader.Size > maxRosterFileSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Roster file is too large"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read roster file"})
+	}
+	defer file.Close()
+
+	table, err := roster.ReadTable(fileHeader.Filename, file)
+	if errors.Is(err, roster.ErrUnsupportedFormat) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unsupported file format, expected .csv or .xlsx"})
+	}
+	if err != nil || len(table) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to parse roster file"})
+	}
+
+	var mapping roster.Mapping
+	if v := c.FormValue("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &mapping); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid column mapping"})
+		}
+	} else {
+		mapping = roster.DefaultMapping(table[0])
+	}
+
+	rows, err := roster.Parse(table, mapping)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	rowErrors := roster.Validate(rows)
+
+	// Check for accounts that already exist, ignoring case like the checks within the file
+	existing, err := repositories.ListAllUsers(ctx, h.userRepo, models.UserFilter{})
+	if err != nil {
+		return internalError(c, "Failed to get users", err)
+	}
+	rowErrors = append(rowErrors, roster.Conflicts(rows, existing)...)
+
+	invalidRows := map[int]bool{}
+	for _, e := range rowErrors {
+		invalidRows[e.Row] = true
+	}
+
+	report := models.RosterImportReport{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Valid:  len(rows) - len(invalidRows),
+		Errors: rowErrors,
+	}
+
+	if dryRun {
+		return c.JSON(http.StatusOK, report)
+	}
+	if len(rowErrors) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, report)
+	}
+
+	currentUserID, _, _ := auth.CurrentUser(c)
+	createdBy, err := primitive.ObjectIDFromHex(currentUserID)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	// The whole roster is imported or, if any insert fails, nothing is
+	users := make([]*models.User, len(rows))
+	for i, row := range rows {
+		users[i] = row.User
+		users[i].PrepareCreate()
+	}
+	err = h.userRepo.CreateMany(ctx, users)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "An account in the roster was created during the import; run the import again"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to create users", err)
+	}
+
+	invitations := make([]*models.Invitation, len(users))
+	tokens := make([]string, len(users))
+	for i, user := range users {
+		if invitations[i], tokens[i], err = models.NewInvitation(user.ID, createdBy); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = h.invitationRepo.CreateMany(ctx, invitations)
+	}
+	if err != nil {
+		ids := make([]primitive.ObjectID, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		if cleanupErr := h.userRepo.DeleteMany(ctx, ids); cleanupErr != nil {
+			err = errors.Join(err, cleanupErr)
+		}
+		return internalError(c, "Failed to create invitations", err)
+	}
+
+	metrics.UsersCreated.WithLabelValues(metrics.UserSourceImport).Add(float64(len(users)))
+	for i, row := range rows {
+		report.Created = append(report.Created, models.ImportedUser{
+			Row:           row.Line,
+			ID:            users[i].ID.Hex(),
+			Username:      users[i].Username,
+			Email:         users[i].Email,
+			InvitationURL: h.invitationLink(tokens[i]),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, report)
+}
+
+// ExportUsers exports the users matching the list filters as CSV, XLSX or PDF.
+// The columns query parameter selects and orders the exported columns.
+func (h *RosterHandler) ExportUsers(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	format, err := roster.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid format, expected csv, xlsx or pdf"})
+	}
+
+	columns, err := roster.ParseColumns(c.QueryParam("columns"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	query, err := parseListQuery(c, models.UserSortFields, "name")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Walk every page of the listing so exports match what the list endpoint shows
+	query.Limit = models.MaxListLimit
+	query.Cursor = ""
+
+	var users []*models.User
+	for {
+		page, err := h.userRepo.List(ctx, filter, query)
+		if err != nil {
+			return internalError(c, "Failed to get users", err)
+		}
+		users = append(users, page.Items...)
+		if !page.HasMore {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	var buf bytes.Buffer
+	opts := roster.ExportOptions{Title: "Roster " + time.Now().Format(time.DateOnly), FontPath: h.pdfFontPath}
+	if err := roster.Export(&buf, format, columns, users, opts); err != nil {
+		return internalError(c, "Failed to export users", err)
+	}
+
+	filename := fmt.Sprintf("roster-%s.%s", time.Now().Format("20060102"), format)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+	return c.Blob(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// invitationLink builds the frontend URL for an invitation token
+func (h *RosterHandler) invitationLink(token string) string {
+	return h.invitationURL + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/labstack/echo/v4"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment so proxies keep the connection open
+const streamHeartbeat = 25 * time.Second
+
+// StreamHandler streams changes to clients as Server-Sent Events
+type StreamHandler struct {
+	hub       realtime.Hub
+	heartbeat time.Duration
+}
+
+// NewStreamHandler creates a new StreamHandler
+func NewStreamHandler(hub realtime.Hub) *StreamHandler {
+	return &StreamHandler{hub: hub, heartbeat: streamHeartbeat}
+}
+
+// Stream sends the changes the current user may see as Server-Sent Events named after their topic.
+// The stream ends when the client falls behind or the server shuts down; clients should
+// reconnect and reload what they show.
+func (h *StreamHandler) Stream(c echo.Context) error {
+	userID, role, ok := auth.CurrentUser(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	topics, err := parseTopics(c.QueryParam("topics"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	changes, err := h.hub.Subscribe(ctx, realtime.Filter{UserID: userID, Role: role, Topics: topics})
+	if errors.Is(err, realtime.ErrClosed) {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Server is shutting down"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to subscribe to changes", err)
+	}
+
+	metrics.StreamSubscribers.Inc()
+	defer metrics.StreamSubscribers.Dec()
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	w.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", change.Topic, data); err != nil {
+				return nil
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		}
+		w.Flush()
+	}
+}
+
+// parseTopics parses a comma-separated list of topics; empty means every topic
+func parseTopics(value string) ([]realtime.Topic, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var topics []realtime.Topic
+	for _, name := range strings.Split(value, ",") {
+		topic := realtime.Topic(strings.TrimSpace(name))
+		known := false
+		for _, t := range realtime.Topics {
+			if t == topic {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown topic %q", topic)
+		}
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/storage"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// findTaskAttachment loads the attachment in the :attachmentId path parameter if it belongs to task.
+// When it returns no attachment, the error response has already been written.
+func (h *TaskHandler) findTaskAttachment(c echo.Context, task *models.Task) (*models.TaskAttachment, error) {
+	attachment, err := h.attachmentRepo.FindByID(c.Request().Context(), c.Param("attachmentId"))
+	if err != nil {
+		return nil, internalError(c, "Failed to get attachment", err)
+	}
+	if attachment == nil || attachment.TaskID != task.ID {
+		return nil, c.JSON(http.StatusNotFound, map[string]string{"error": "Attachment not found"})
+	}
+	return attachment, nil
+}
+
+// cleanFileName keeps the base name of an uploaded file without control characters
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+// GetTaskAttachments lists a task's attachments, oldest first
+func (h *TaskHandler) GetTaskAttachments(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	attachments, err := h.attachmentRepo.FindByTask(c.Request().Context(), task.ID)
+	if err != nil {
+		return internalError(c, "Failed to get attachments", err)
+	}
+
+	return c.JSON(http.StatusOK, attachments)
+}
+
+// multipartOverhead is room in an upload's request body for the form fields and framing around the file
+const multipartOverhead = 64 << 10
+
+// MaxAttachmentRequestSize is the largest request body an attachment upload may have
+func (h *TaskHandler) MaxAttachmentRequestSize() int64 {
+	return h.maxFileSize + multipartOverhead
+}
+
+// UploadTaskAttachment attaches the "file" of a multipart form to a task
+func (h *TaskHandler) UploadTaskAttachment(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "File is required"})
+	}
+	if fileHeader.Size > h.maxFileSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File is too large"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read file"})
+	}
+	defer file.Close()
+
+	contentType := fileHeader.Header.Get(echo.HeaderContentType)
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		contentType = "application/octet-stream"
+	}
+
+	ctx := c.Request().Context()
+	userID, _ := currentMember(c)
+	attachment := models.NewTaskAttachment(task.ID, cleanFileName(fileHeader.Filename), contentType, userID)
+
+	attachment.Size, err = h.files.Put(ctx, attachment.StorageKey, io.LimitReader(file, h.maxFileSize))
+	if err != nil {
+		return internalError(c, "Failed to store attachment", err)
+	}
+
+	if _, err := h.attachmentRepo.Create(ctx, attachment); err != nil {
+		if err := h.files.Delete(ctx, attachment.StorageKey); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "failed to delete attachment file", "key", attachment.StorageKey, "error", err)
+		}
+		return internalError(c, "Failed to create attachment", err)
+	}
+
+	return c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadTaskAttachment sends an attachment's file. It is always sent as a download
+// so uploaded HTML or SVG is never rendered on the API's origin.
+func (h *TaskHandler) DownloadTaskAttachment(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	attachment, err := h.findTaskAttachment(c, task)
+	if attachment == nil {
+		return err
+	}
+
+	file, err := h.files.Open(c.Request().Context(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Attachment file is missing"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to open attachment", err)
+	}
+	defer file.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, disposition)
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(echo.HeaderContentSecurityPolicy, "default-src 'none'; sandbox")
+
+	return c.Stream(http.StatusOK, attachment.ContentType, file)
+}
+
+// DeleteTaskAttachment removes an attachment. Its uploader, the task's creator and admins may remove it.
+func (h *TaskHandler) DeleteTaskAttachment(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	attachment, err := h.findTaskAttachment(c, task)
+	if attachment == nil {
+		return err
+	}
+
+	userID, role := currentMember(c)
+	if attachment.UploadedBy != userID && !task.ManagedBy(userID, role) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the uploader, the task's creator or an admin can remove an attachment"})
+	}
+
+	ctx := c.Request().Context()
+	err = h.attachmentRepo.Delete(ctx, attachment.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Attachment not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete attachment", err)
+	}
+
+	if err := h.files.Delete(ctx, attachment.StorageKey); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to delete attachment file", "key", attachment.StorageKey, "error", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
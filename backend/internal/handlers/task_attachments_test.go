@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAttachmentsOfHiddenTasksAreNotFound(t *testing.T) {
+	h, member, task := hiddenTask()
+	for name, handle := range map[string]echo.HandlerFunc{
+		"list":     h.GetTaskAttachments,
+		"upload":   h.UploadTaskAttachment,
+		"download": h.DownloadTaskAttachment,
+		"delete":   h.DeleteTaskAttachment,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, rec := taskRequest(member, http.MethodGet, task.ID.Hex(), "")
+			c.SetParamNames("id", "attachmentId")
+			c.SetParamValues(task.ID.Hex(), primitive.NewObjectID().Hex())
+			checkStatus(t, handle(c), rec, http.StatusNotFound)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// moveToColumn sets a task's status. A task that changes status goes to the bottom of its new Kanban column.
+func (h *TaskHandler) moveToColumn(c echo.Context, task *models.Task, status models.TaskStatus) error {
+	if task.Status == status {
+		return nil
+	}
+
+	rank, err := repositories.RankAtEnd(c.Request().Context(), h.taskRepo, status)
+	if err != nil {
+		return err
+	}
+	task.Status = status
+	task.Rank = rank
+	return nil
+}
+
+// boardChanged is the error of a move whose neighbours changed since the board was loaded
+const boardChanged = "The board has changed; reload it and try again"
+
+// MoveTask moves a task on the Kanban board, within its column or to another one.
+// Only the moved task is written: it gets a rank between its new neighbours.
+func (h *TaskHandler) MoveTask(c echo.Context) error {
+	var input models.MoveTaskInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if !models.IsValidTaskStatus(input.Status) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown status: " + string(input.Status)})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	if userID, role := currentMember(c); task.IsGroupTask() && task.Status != input.Status && !task.ManagedBy(userID, role) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": groupTaskManagersOnly})
+	}
+	if refused, err := h.refuseBlockedProgress(c, task, input.Status, input.OverrideBlockers); refused {
+		return err
+	}
+
+	above, msg, err := h.neighbour(c, task, input.AfterID, input.Status)
+	if err != nil {
+		return internalError(c, "Failed to get task", err)
+	}
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+	below, msg, err := h.neighbour(c, task, input.BeforeID, input.Status)
+	if err != nil {
+		return internalError(c, "Failed to get task", err)
+	}
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	var rank string
+	if above == nil && below == nil {
+		rank, err = repositories.RankAtEnd(c.Request().Context(), h.taskRepo, input.Status)
+	} else {
+		var before, after string
+		if above != nil {
+			before = above.Rank
+		}
+		if below != nil {
+			after = below.Rank
+		}
+		// Neighbours that are out of order, or that another task on the user's board now sits
+		// between, were moved by someone else since the board was loaded
+		var viewer *models.User
+		if viewer, err = h.currentUser(c); err != nil {
+			return internalError(c, "Failed to get current user", err)
+		}
+		if viewer.Role == models.AdminRole {
+			viewer = nil
+		}
+		var moved bool
+		if moved, err = h.taskRepo.HasTaskBetween(c.Request().Context(), input.Status, before, after, task.ID, viewer); err != nil {
+			return internalError(c, "Failed to rank task", err)
+		}
+		if moved {
+			return c.JSON(http.StatusConflict, map[string]string{"error": boardChanged})
+		}
+		rank, err = models.UniqueRankBetween(before, after)
+		if errors.Is(err, models.ErrInvalidRank) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": boardChanged})
+		}
+	}
+	if err != nil {
+		return internalError(c, "Failed to rank task", err)
+	}
+
+	task.Status = input.Status
+	task.Rank = rank
+	task.PrepareUpdate()
+
+	err = h.taskRepo.Update(c.Request().Context(), task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update task", err)
+	}
+
+	h.publish(c, realtime.ActionUpdated, task)
+
+	return c.JSON(http.StatusOK, task)
+}
+
+// neighbour loads a task the moved task is dropped next to. It returns nil for an empty ID,
+// or a message if the neighbour is not a task the current user can see in the target column.
+func (h *TaskHandler) neighbour(c echo.Context, moved *models.Task, id string, status models.TaskStatus) (*models.Task, string, error) {
+	if id == "" {
+		return nil, "", nil
+	}
+	if id == moved.ID.Hex() {
+		return nil, "A task cannot be placed next to itself", nil
+	}
+
+	task, err := h.taskRepo.FindByID(c.Request().Context(), id)
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return nil, "", err
+	}
+	if task == nil || user == nil || !task.VisibleTo(user) {
+		return nil, "Neighbouring task not found: " + id, nil
+	}
+	if task.Status != status {
+		return nil, "Neighbouring task " + id + " is not in the " + string(status) + " column", nil
+	}
+	return task, "", nil
+}
+
+// normalizeLabels trims labels and drops duplicates, keeping the first spelling of each.
+// It returns a message describing the first problem, if there is one.
+func normalizeLabels(labels []string) ([]string, string) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			return nil, "Labels must not be empty"
+		}
+		if utf8.RuneCountInString(label) > models.MaxTaskLabelLength {
+			return nil, fmt.Sprintf("Labels must be at most %d characters", models.MaxTaskLabelLength)
+		}
+		if key := strings.ToLower(label); !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, label)
+		}
+	}
+	if len(normalized) > models.MaxTaskLabels {
+		return nil, fmt.Sprintf("A task can have at most %d labels", models.MaxTaskLabels)
+	}
+	return normalized, ""
+}
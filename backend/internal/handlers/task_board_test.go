@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMoveTaskBetweenTasksAddedTogether(t *testing.T) {
+	ctx := context.Background()
+	admin := &models.User{ID: primitive.NewObjectID(), Role: models.AdminRole}
+	tasks := newFakeTasks(&models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a0"})
+
+	// Two tasks added to the bottom of the column at the same moment see the same last rank
+	first, err := repositories.RankAtEnd(ctx, tasks, models.TaskStatusTodo)
+	if err != nil {
+		t.Fatalf("Error ranking task: %v", err)
+	}
+	second, err := repositories.RankAtEnd(ctx, tasks, models.TaskStatusTodo)
+	if err != nil {
+		t.Fatalf("Error ranking task: %v", err)
+	}
+	if first == second {
+		t.Fatalf("Expected tasks added together to get distinct ranks, got %q twice", first)
+	}
+	if first > second {
+		first, second = second, first
+	}
+	above := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: first}
+	below := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: second}
+	moved := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusInProgress, Rank: "a0"}
+	for _, task := range []*models.Task{above, below, moved} {
+		tasks.tasks[task.ID] = task
+	}
+
+	h := newTaskHandler(tasks, admin)
+	c, rec := taskRequest(admin, http.MethodPost, moved.ID.Hex(), `{"status":"todo","afterId":"`+above.ID.Hex()+`","beforeId":"`+below.ID.Hex()+`"}`)
+	checkStatus(t, h.MoveTask(c), rec, http.StatusOK)
+
+	if rank := tasks.tasks[moved.ID].Rank; rank <= first || rank >= second {
+		t.Errorf("Expected the moved task to rank between %q and %q, got %q", first, second, rank)
+	}
+}
+
+func TestMoveTaskRefusesNeighboursNoLongerNextToEachOther(t *testing.T) {
+	admin := &models.User{ID: primitive.NewObjectID(), Role: models.AdminRole}
+	above := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a0"}
+	between := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a0V"}
+	below := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a1"}
+	moved := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a2"}
+	tasks := newFakeTasks(above, between, below, moved)
+	h := newTaskHandler(tasks, admin)
+
+	// Someone dropped a task between the two neighbours since the board was loaded
+	c, rec := taskRequest(admin, http.MethodPost, moved.ID.Hex(), `{"status":"todo","afterId":"`+above.ID.Hex()+`","beforeId":"`+below.ID.Hex()+`"}`)
+	checkStatus(t, h.MoveTask(c), rec, http.StatusConflict)
+
+	// The same goes for the top of the column
+	c, rec = taskRequest(admin, http.MethodPost, moved.ID.Hex(), `{"status":"todo","beforeId":"`+between.ID.Hex()+`"}`)
+	checkStatus(t, h.MoveTask(c), rec, http.StatusConflict)
+
+	// Moving the task within its own slot is not a conflict with itself
+	c, rec = taskRequest(admin, http.MethodPost, moved.ID.Hex(), `{"status":"todo","afterId":"`+below.ID.Hex()+`"}`)
+	checkStatus(t, h.MoveTask(c), rec, http.StatusOK)
+
+	if tasks.tasks[moved.ID].Rank <= below.Rank {
+		t.Errorf("Expected the task to stay below %q, got %q", below.Rank, tasks.tasks[moved.ID].Rank)
+	}
+}
+
+func TestMoveTaskIgnoresHiddenTasksBetweenNeighbours(t *testing.T) {
+	member := &models.User{ID: primitive.NewObjectID(), Role: models.GeneralRole, Section: "brass"}
+	above := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a0", AssignedTo: member.ID}
+	hidden := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a0V", AssignedTo: primitive.NewObjectID()}
+	below := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a1", AssignedTo: member.ID}
+	moved := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a2", AssignedTo: member.ID}
+	tasks := newFakeTasks(above, hidden, below, moved)
+	h := newTaskHandler(tasks, member)
+
+	// The member's board shows the two neighbours next to each other
+	c, rec := taskRequest(member, http.MethodPost, moved.ID.Hex(), `{"status":"todo","afterId":"`+above.ID.Hex()+`","beforeId":"`+below.ID.Hex()+`"}`)
+	checkStatus(t, h.MoveTask(c), rec, http.StatusOK)
+
+	if rank := tasks.tasks[moved.ID].Rank; rank <= above.Rank || rank >= below.Rank {
+		t.Errorf("Expected the moved task to rank between %q and %q, got %q", above.Rank, below.Rank, rank)
+	}
+}
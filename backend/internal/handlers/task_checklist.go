@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// validateChecklistText trims a checklist item's text and returns a message describing why it is not accepted
+func validateChecklistText(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", "Text is required"
+	}
+	if utf8.RuneCountInString(text) > models.MaxChecklistItemLength {
+		return "", "Text is too long"
+	}
+	return text, ""
+}
+
+// findChecklistItem returns the index of the item in the :itemId path parameter, or -1
+func findChecklistItem(c echo.Context, task *models.Task) int {
+	for i, item := range task.Checklist {
+		if item.ID.Hex() == c.Param("itemId") {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddChecklistItem adds an open item to a task's checklist
+func (h *TaskHandler) AddChecklistItem(c echo.Context) error {
+	var input models.ChecklistItemInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	text, msg := validateChecklistText(input.Text)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	item := models.NewChecklistItem(text)
+	if input.Done {
+		userID, _ := currentMember(c)
+		item.SetDone(true, userID)
+	}
+
+	err = h.taskRepo.AddChecklistItem(c.Request().Context(), task.ID, item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to add checklist item", err)
+	}
+
+	task.Checklist = append(task.Checklist, item)
+	h.publish(c, realtime.ActionUpdated, task)
+
+	return c.JSON(http.StatusCreated, item)
+}
+
+// UpdateChecklistItem changes the text of a checklist item and checks or unchecks it
+func (h *TaskHandler) UpdateChecklistItem(c echo.Context) error {
+	var input models.ChecklistItemInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	text, msg := validateChecklistText(input.Text)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	i := findChecklistItem(c, task)
+	if i < 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Checklist item not found"})
+	}
+
+	userID, _ := currentMember(c)
+	item := &task.Checklist[i]
+	item.Text = text
+	item.SetDone(input.Done, userID)
+
+	err = h.taskRepo.UpdateChecklistItem(c.Request().Context(), task.ID, *item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Checklist item not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update checklist item", err)
+	}
+
+	h.publish(c, realtime.ActionUpdated, task)
+
+	return c.JSON(http.StatusOK, item)
+}
+
+// DeleteChecklistItem removes an item from a task's checklist
+func (h *TaskHandler) DeleteChecklistItem(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	i := findChecklistItem(c, task)
+	if i < 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Checklist item not found"})
+	}
+
+	err = h.taskRepo.DeleteChecklistItem(c.Request().Context(), task.ID, task.Checklist[i].ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Checklist item not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete checklist item", err)
+	}
+
+	task.Checklist = append(task.Checklist[:i], task.Checklist[i+1:]...)
+	h.publish(c, realtime.ActionUpdated, task)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// findTaskComment loads the comment in the :commentId path parameter if it belongs to task.
+// When it returns no comment, the error response has already been written.
+func (h *TaskHandler) findTaskComment(c echo.Context, task *models.Task) (*models.TaskComment, error) {
+	comment, err := h.commentRepo.FindByID(c.Request().Context(), c.Param("commentId"))
+	if err != nil {
+		return nil, internalError(c, "Failed to get comment", err)
+	}
+	if comment == nil || comment.TaskID != task.ID {
+		return nil, c.JSON(http.StatusNotFound, map[string]string{"error": "Comment not found"})
+	}
+	return comment, nil
+}
+
+// validateCommentBody trims a comment body and returns a message describing why it is not accepted
+func validateCommentBody(body string) (string, string) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", "Body is required"
+	}
+	if utf8.RuneCountInString(body) > models.MaxTaskCommentLength {
+		return "", "Body is too long"
+	}
+	return body, ""
+}
+
+// GetTaskComments lists a task's comments as threads, oldest first
+func (h *TaskHandler) GetTaskComments(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	comments, err := h.commentRepo.FindByTask(c.Request().Context(), task.ID)
+	if err != nil {
+		return internalError(c, "Failed to get comments", err)
+	}
+
+	return c.JSON(http.StatusOK, models.BuildTaskCommentThreads(comments))
+}
+
+// CreateTaskComment comments on a task, or replies to one of its comments when parentId is set
+func (h *TaskHandler) CreateTaskComment(c echo.Context) error {
+	var input models.CreateTaskCommentInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	body, msg := validateCommentBody(input.Body)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userID, _ := currentMember(c)
+	comment := &models.TaskComment{TaskID: task.ID, AuthorID: userID, Body: body}
+
+	if input.ParentID != "" {
+		parent, err := h.commentRepo.FindByID(ctx, input.ParentID)
+		if err != nil {
+			return internalError(c, "Failed to get comment", err)
+		}
+		if parent == nil || parent.TaskID != task.ID {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Parent comment not found on this task"})
+		}
+		comment.ParentID = &parent.ID
+	}
+
+	comment.PrepareCreate()
+	if _, err := h.commentRepo.Create(ctx, comment); err != nil {
+		return internalError(c, "Failed to create comment", err)
+	}
+
+	return c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateTaskComment edits a comment. Only its author may edit it.
+func (h *TaskHandler) UpdateTaskComment(c echo.Context) error {
+	var input models.UpdateTaskCommentInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	body, msg := validateCommentBody(input.Body)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	comment, err := h.findTaskComment(c, task)
+	if comment == nil {
+		return err
+	}
+
+	userID, _ := currentMember(c)
+	if comment.AuthorID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the author can edit a comment"})
+	}
+	if comment.Deleted {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Comment was deleted"})
+	}
+
+	now := time.Now()
+	comment.Body = body
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+
+	err = h.commentRepo.Update(c.Request().Context(), comment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Comment not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update comment", err)
+	}
+
+	return c.JSON(http.StatusOK, comment)
+}
+
+// DeleteTaskComment deletes a comment. Its author and admins may delete it.
+// The comment keeps its place in the thread with its body removed, so replies to it stay in context.
+func (h *TaskHandler) DeleteTaskComment(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	comment, err := h.findTaskComment(c, task)
+	if comment == nil {
+		return err
+	}
+
+	userID, role := currentMember(c)
+	if comment.AuthorID != userID && role != models.AdminRole {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the author or an admin can delete a comment"})
+	}
+
+	comment.Body = ""
+	comment.Deleted = true
+	comment.UpdatedAt = time.Now()
+
+	err = h.commentRepo.Update(c.Request().Context(), comment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Comment not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete comment", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCommentsOfHiddenTasksAreNotFound(t *testing.T) {
+	h, member, task := hiddenTask()
+	for name, handle := range map[string]echo.HandlerFunc{
+		"list":   h.GetTaskComments,
+		"create": h.CreateTaskComment,
+		"update": h.UpdateTaskComment,
+		"delete": h.DeleteTaskComment,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, rec := taskRequest(member, http.MethodPost, task.ID.Hex(), `{"body":"Where are the spare reeds?"}`)
+			c.SetParamNames("id", "commentId")
+			c.SetParamValues(task.ID.Hex(), primitive.NewObjectID().Hex())
+			checkStatus(t, handle(c), rec, http.StatusNotFound)
+		})
+	}
+}
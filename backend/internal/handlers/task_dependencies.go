@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// markBlocked sets Blocked on tasks from the current status of the tasks they wait on
+func (h *TaskHandler) markBlocked(c echo.Context, tasks ...*models.Task) error {
+	ids := models.BlockerIDs(tasks)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	blockers, err := h.taskRepo.FindByIDs(c.Request().Context(), ids)
+	if err != nil {
+		return err
+	}
+	models.MarkBlocked(tasks, blockers)
+	return nil
+}
+
+// refuseBlockedProgress stops a task from being started or completed while it waits on open tasks,
+// unless an admin overrides it. When it returns true, the error response has already been written
+// and the returned error is the result of writing it.
+func (h *TaskHandler) refuseBlockedProgress(c echo.Context, task *models.Task, status models.TaskStatus, override bool) (bool, error) {
+	if !task.AdvancesBlocked(status) {
+		return false, nil
+	}
+	if !override {
+		return true, c.JSON(http.StatusConflict, map[string]string{"error": "The task is waiting on tasks that are not completed"})
+	}
+
+	userID, role := currentMember(c)
+	if role != models.AdminRole {
+		return true, c.JSON(http.StatusForbidden, map[string]string{"error": "Only an admin can start or complete a blocked task"})
+	}
+
+	ctx := c.Request().Context()
+	logging.FromContext(ctx).InfoContext(ctx, "blocked task moved by admin override", "task_id", task.ID.Hex(), "status", status, "user_id", userID.Hex())
+	return false, nil
+}
+
+// GetTaskDependencies lists the tasks a task waits on and the tasks waiting on it.
+// Tasks the current user cannot see are left out.
+func (h *TaskHandler) GetTaskDependencies(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return internalError(c, "Failed to get current user", err)
+	}
+
+	ctx := c.Request().Context()
+	blockers, err := h.taskRepo.FindByIDs(ctx, task.BlockedBy)
+	if err != nil {
+		return internalError(c, "Failed to get tasks", err)
+	}
+	blocking, err := h.taskRepo.FindBlocking(ctx, task.ID)
+	if err != nil {
+		return internalError(c, "Failed to get tasks", err)
+	}
+
+	dependencies := models.TaskDependencies{BlockedBy: []*models.Task{}, Blocking: []*models.Task{}}
+	for _, t := range blockers {
+		if t.VisibleTo(user) {
+			dependencies.BlockedBy = append(dependencies.BlockedBy, t)
+		}
+	}
+	for _, t := range blocking {
+		if t.VisibleTo(user) {
+			dependencies.Blocking = append(dependencies.Blocking, t)
+		}
+	}
+	if err := h.markBlocked(c, append(dependencies.BlockedBy, dependencies.Blocking...)...); err != nil {
+		return internalError(c, "Failed to get tasks", err)
+	}
+
+	return c.JSON(http.StatusOK, dependencies)
+}
+
+// dependencyCycle is the error of a dependency that would make a task wait on itself
+const dependencyCycle = "The blocking task already waits on this task, directly or through others"
+
+// AddTaskDependency makes a task wait on another until it is completed.
+// Only admins and the task's creator may change its dependencies.
+func (h *TaskHandler) AddTaskDependency(c echo.Context) error {
+	var input models.TaskDependencyInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	blockerID, err := primitive.ObjectIDFromHex(input.TaskID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid taskId"})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	userID, role := currentMember(c)
+	if !task.ManagedBy(userID, role) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the task's creator or an admin can change its dependencies"})
+	}
+	if blockerID == task.ID {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A task cannot wait on itself"})
+	}
+	if task.IsBlockedBy(blockerID) {
+		return c.JSON(http.StatusOK, task)
+	}
+
+	ctx := c.Request().Context()
+	blocker, err := h.taskRepo.FindByID(ctx, input.TaskID)
+	if err != nil {
+		return internalError(c, "Failed to get task", err)
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return internalError(c, "Failed to get current user", err)
+	}
+	if blocker == nil || !blocker.VisibleTo(user) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Blocking task not found: " + input.TaskID})
+	}
+
+	createsCycle := func() (bool, error) {
+		return models.DependencyCreatesCycle(task.ID, blockerID, func(ids []primitive.ObjectID) ([]*models.Task, error) {
+			return h.taskRepo.FindByIDs(ctx, ids)
+		})
+	}
+	cycle, err := createsCycle()
+	if err != nil {
+		return internalError(c, "Failed to check dependencies", err)
+	}
+	if cycle {
+		return c.JSON(http.StatusConflict, map[string]string{"error": dependencyCycle})
+	}
+
+	err = h.taskRepo.AddBlocker(ctx, task.ID, blockerID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if errors.Is(err, repositories.ErrTooManyBlockers) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("A task can wait on at most %d tasks", models.MaxTaskBlockers)})
+	}
+	if err != nil {
+		return internalError(c, "Failed to add dependency", err)
+	}
+
+	// Requests adding the dependencies of a cycle at the same time each pass the check above. Checking
+	// again once the dependency is stored finds the cycle in at least the last of them, which backs out.
+	if cycle, err = createsCycle(); err == nil && cycle {
+		err = h.taskRepo.RemoveBlocker(ctx, task.ID, blockerID)
+		if err == nil {
+			return c.JSON(http.StatusConflict, map[string]string{"error": dependencyCycle})
+		}
+	}
+	if err != nil {
+		return internalError(c, "Failed to check dependencies", err)
+	}
+
+	task.BlockedBy = append(task.BlockedBy, blockerID)
+	if blocker.Status != models.TaskStatusCompleted {
+		task.Blocked = true
+	}
+	h.publish(c, realtime.ActionUpdated, task)
+
+	return c.JSON(http.StatusOK, task)
+}
+
+// RemoveTaskDependency stops a task waiting on the task in the :blockerId path parameter
+func (h *TaskHandler) RemoveTaskDependency(c echo.Context) error {
+	blockerID, err := primitive.ObjectIDFromHex(c.Param("blockerId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Dependency not found"})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	userID, role := currentMember(c)
+	if !task.ManagedBy(userID, role) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the task's creator or an admin can change its dependencies"})
+	}
+	if !task.IsBlockedBy(blockerID) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Dependency not found"})
+	}
+
+	err = h.taskRepo.RemoveBlocker(c.Request().Context(), task.ID, blockerID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to remove dependency", err)
+	}
+
+	blockedBy := []primitive.ObjectID{}
+	for _, id := range task.BlockedBy {
+		if id != blockerID {
+			blockedBy = append(blockedBy, id)
+		}
+	}
+	task.BlockedBy = blockedBy
+	task.Blocked = false
+	if err := h.markBlocked(c, task); err != nil {
+		return internalError(c, "Failed to get tasks", err)
+	}
+	h.publish(c, realtime.ActionUpdated, task)
+
+	return c.JSON(http.StatusOK, task)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAddTaskDependencyBacksOutOfConcurrentCycle(t *testing.T) {
+	admin := &models.User{ID: primitive.NewObjectID(), Role: models.AdminRole}
+	design := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo}
+	props := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo}
+	tasks := newFakeTasks(design, props)
+	h := newTaskHandler(tasks, admin)
+
+	// Another request makes design wait on props after this one checked for cycles
+	tasks.beforeAddBlocker = func() {
+		tasks.beforeAddBlocker = nil
+		design.BlockedBy = append(design.BlockedBy, props.ID)
+	}
+	c, rec := taskRequest(admin, http.MethodPost, props.ID.Hex(), `{"taskId":"`+design.ID.Hex()+`"}`)
+	checkStatus(t, h.AddTaskDependency(c), rec, http.StatusConflict)
+	if len(props.BlockedBy) != 0 {
+		t.Errorf("Expected the dependency closing the cycle to be removed, got %v", props.BlockedBy)
+	}
+
+	// Without the other request the dependency is kept
+	design.BlockedBy = nil
+	c, rec = taskRequest(admin, http.MethodPost, props.ID.Hex(), `{"taskId":"`+design.ID.Hex()+`"}`)
+	checkStatus(t, h.AddTaskDependency(c), rec, http.StatusOK)
+	if !props.IsBlockedBy(design.ID) {
+		t.Error("Expected props to wait on design")
+	}
+}
+
+func TestBlockedTaskCompletionNeedsAdminOverride(t *testing.T) {
+	admin := &models.User{ID: primitive.NewObjectID(), Role: models.AdminRole}
+	member := &models.User{ID: primitive.NewObjectID(), Role: models.GeneralRole}
+	design := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusInProgress, Rank: "a0"}
+	props := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a1",
+		AssignedTo: member.ID, CreatedBy: admin.ID, BlockedBy: []primitive.ObjectID{design.ID}}
+	tasks := newFakeTasks(design, props)
+	h := newTaskHandler(tasks, admin, member)
+
+	c, rec := taskRequest(member, http.MethodPut, props.ID.Hex(), `{"status":"completed"}`)
+	checkStatus(t, h.UpdateTask(c), rec, http.StatusConflict)
+	c, rec = taskRequest(member, http.MethodPost, props.ID.Hex(), `{"status":"completed"}`)
+	checkStatus(t, h.MoveTask(c), rec, http.StatusConflict)
+	c, rec = taskRequest(member, http.MethodPost, props.ID.Hex(), "")
+	checkStatus(t, h.CompleteTask(c), rec, http.StatusConflict)
+	c, rec = taskRequest(member, http.MethodPost, props.ID.Hex(), `{"overrideBlockers":true}`)
+	checkStatus(t, h.CompleteTask(c), rec, http.StatusForbidden)
+	if tasks.tasks[props.ID].Status != models.TaskStatusTodo {
+		t.Fatalf("Expected the blocked task to stay open, got %q", tasks.tasks[props.ID].Status)
+	}
+
+	c, rec = taskRequest(admin, http.MethodPut, props.ID.Hex(), `{"status":"completed","overrideBlockers":true}`)
+	checkStatus(t, h.UpdateTask(c), rec, http.StatusOK)
+	if tasks.tasks[props.ID].Status != models.TaskStatusCompleted {
+		t.Errorf("Expected the admin to complete the blocked task, got %q", tasks.tasks[props.ID].Status)
+	}
+
+	// Group tasks are completed member by member through the same check
+	stored := tasks.tasks[props.ID]
+	stored.Status = models.TaskStatusTodo
+	stored.AssigneeType, stored.AssignedTo = models.TaskAssigneeEveryone, primitive.NilObjectID
+	c, rec = taskRequest(member, http.MethodPost, props.ID.Hex(), "")
+	checkStatus(t, h.CompleteTask(c), rec, http.StatusConflict)
+}
@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/notify"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/storage"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/taskfeed"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TaskHandler handles HTTP requests for tasks and their comments, checklists and attachments
+type TaskHandler struct {
+	taskRepo       repositories.TaskRepository
+	commentRepo    repositories.TaskCommentRepository
+	attachmentRepo repositories.TaskAttachmentRepository
+	userRepo       repositories.UserRepository
+	files          storage.Storage
+	maxFileSize    int64
+	feed           *taskfeed.Feed
+}
+
+// NewTaskHandler creates a new TaskHandler
+func NewTaskHandler(taskRepo repositories.TaskRepository, commentRepo repositories.TaskCommentRepository, attachmentRepo repositories.TaskAttachmentRepository,
+	userRepo repositories.UserRepository, files storage.Storage, maxFileSize int64, hub realtime.Hub, notifier *notify.Service) *TaskHandler {
+	return &TaskHandler{
+		taskRepo:       taskRepo,
+		commentRepo:    commentRepo,
+		attachmentRepo: attachmentRepo,
+		userRepo:       userRepo,
+		files:          files,
+		maxFileSize:    maxFileSize,
+		feed:           taskfeed.New(userRepo, hub, notifier),
+	}
+}
+
+// currentMember returns the ID and role of the authenticated user
+func currentMember(c echo.Context) (primitive.ObjectID, models.Role) {
+	userID, _ := currentUserObjectID(c)
+	_, role, _ := auth.CurrentUser(c)
+	return userID, role
+}
+
+// currentUserContextKey is the echo context key the current user's account is cached under
+const currentUserContextKey = "taskCurrentUser"
+
+// currentUser loads the account of the authenticated user, whose section and role decide which
+// group tasks they see. It returns nil if the account no longer exists.
+func (h *TaskHandler) currentUser(c echo.Context) (*models.User, error) {
+	if user, ok := c.Get(currentUserContextKey).(*models.User); ok {
+		return user, nil
+	}
+
+	userID, _ := currentMember(c)
+	user, err := h.userRepo.FindByID(c.Request().Context(), userID.Hex())
+	if err != nil || user == nil {
+		return nil, err
+	}
+	c.Set(currentUserContextKey, user)
+	return user, nil
+}
+
+// findVisibleTask loads the task in the :id path parameter. Tasks the current user cannot see are
+// reported as not found so their existence is not revealed. When it returns no task, the error
+// response has already been written and the returned error is the result of writing it.
+func (h *TaskHandler) findVisibleTask(c echo.Context) (*models.Task, error) {
+	task, err := h.taskRepo.FindByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return nil, internalError(c, "Failed to get task", err)
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return nil, internalError(c, "Failed to get current user", err)
+	}
+
+	if task == nil || user == nil || !task.VisibleTo(user) {
+		return nil, c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if err := h.markBlocked(c, task); err != nil {
+		return nil, internalError(c, "Failed to get task", err)
+	}
+	return task, nil
+}
+
+// assignees returns the members a task is assigned to: its assignee, or every member of its group
+func (h *TaskHandler) assignees(c echo.Context, task *models.Task) ([]*models.User, error) {
+	ctx := c.Request().Context()
+	if task.IsGroupTask() {
+		return repositories.ListAllUsers(ctx, h.userRepo, task.MemberFilter())
+	}
+
+	user, err := h.userRepo.FindByID(ctx, task.AssignedTo.Hex())
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return []*models.User{user}, nil
+}
+
+// publish sends a task change to the members who can see the task
+func (h *TaskHandler) publish(c echo.Context, action realtime.Action, task *models.Task) {
+	h.feed.Publish(c.Request().Context(), action, task)
+}
+
+// notifyAssignees tells the assignees about a task they were given
+func (h *TaskHandler) notifyAssignees(c echo.Context, task *models.Task, actorID primitive.ObjectID) {
+	h.feed.NotifyAssignees(c.Request().Context(), task, actorID)
+}
+
+// sameAssignment reports whether two versions of a task are assigned to the same member or group
+func sameAssignment(a, b *models.Task) bool {
+	return a.IsGroupTask() == b.IsGroupTask() && (!a.IsGroupTask() || a.AssigneeType == b.AssigneeType) &&
+		a.AssignedTo == b.AssignedTo && a.AssignedSection == b.AssignedSection && a.AssignedRole == b.AssignedRole
+}
+
+// assignTask sets who a task is assigned to, clearing the fields other assignee types use.
+// It returns a message describing why the assignment is not accepted, if it is not.
+func assignTask(c echo.Context, userRepo repositories.UserRepository, task *models.Task, assigneeType models.TaskAssigneeType, assignedTo, section string, role models.Role) (string, error) {
+	if assigneeType == "" {
+		assigneeType = models.TaskAssigneeUser
+	}
+	if !models.IsValidTaskAssigneeType(assigneeType) {
+		return "Unknown assignee type: " + string(assigneeType), nil
+	}
+
+	task.AssigneeType = assigneeType
+	task.AssignedTo = primitive.NilObjectID
+	task.AssignedSection = ""
+	task.AssignedRole = ""
+
+	switch assigneeType {
+	case models.TaskAssigneeUser:
+		user, err := userRepo.FindByID(c.Request().Context(), assignedTo)
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			return "Assignee not found", nil
+		}
+		task.AssignedTo = user.ID
+	case models.TaskAssigneeSection:
+		if task.AssignedSection = strings.TrimSpace(section); task.AssignedSection == "" {
+			return "Assigned section is required", nil
+		}
+	case models.TaskAssigneeRole:
+		if role != models.AdminRole && role != models.GeneralRole {
+			return "Assigned role must be admin or general", nil
+		}
+		task.AssignedRole = role
+	}
+	return "", nil
+}
+
+// GetTasks lists the tasks the current user can see. Admins see every task.
+func (h *TaskHandler) GetTasks(c echo.Context) error {
+	query, err := parseListQuery(c, models.TaskSortFields, "createdAt")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	filter := models.TaskFilter{Status: models.TaskStatus(c.QueryParam("status"))}
+	if filter.Status != "" && !models.IsValidTaskStatus(filter.Status) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown status: " + string(filter.Status)})
+	}
+	if v := c.QueryParam("assignedTo"); v != "" {
+		if filter.AssignedTo, err = primitive.ObjectIDFromHex(v); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid assignedTo"})
+		}
+	}
+	if v := c.QueryParam("templateId"); v != "" {
+		if filter.TemplateID, err = primitive.ObjectIDFromHex(v); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid templateId"})
+		}
+	}
+	filter.Priority = models.TaskPriority(c.QueryParam("priority"))
+	if filter.Priority != "" && !models.IsValidTaskPriority(filter.Priority) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown priority: " + string(filter.Priority)})
+	}
+	for _, label := range c.QueryParams()["label"] {
+		if label = strings.TrimSpace(label); label != "" {
+			filter.Labels = append(filter.Labels, label)
+		}
+	}
+
+	user, err := h.currentUser(c)
+	if err != nil {
+		return internalError(c, "Failed to get current user", err)
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	if user.Role != models.AdminRole {
+		filter.VisibleTo = user
+	}
+
+	result, err := h.taskRepo.List(c.Request().Context(), filter, query)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to get tasks", err)
+	}
+	if err := h.markBlocked(c, result.Items...); err != nil {
+		return internalError(c, "Failed to get tasks", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// GetTask gets a task
+func (h *TaskHandler) GetTask(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, task)
+}
+
+// CreateTask creates a task and notifies its assignee
+func (h *TaskHandler) CreateTask(c echo.Context) error {
+	var input models.CreateTaskInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	input.Title = strings.TrimSpace(input.Title)
+	if input.Title == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Title is required"})
+	}
+
+	if input.Priority != "" && !models.IsValidTaskPriority(input.Priority) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown priority: " + string(input.Priority)})
+	}
+	labels, msg := normalizeLabels(input.Labels)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	task := &models.Task{
+		Title:       input.Title,
+		Description: input.Description,
+		Priority:    input.Priority,
+		Labels:      labels,
+	}
+	msg, err := assignTask(c, h.userRepo, task, input.AssigneeType, input.AssignedTo, input.AssignedSection, input.AssignedRole)
+	if err != nil {
+		return internalError(c, "Failed to get assignee", err)
+	}
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+	if task.Rank, err = repositories.RankAtEnd(c.Request().Context(), h.taskRepo, models.TaskStatusTodo); err != nil {
+		return internalError(c, "Failed to rank task", err)
+	}
+	if !input.DueDate.IsZero() {
+		dueDate := input.DueDate
+		task.DueDate = &dueDate
+	}
+
+	userID, _ := currentMember(c)
+	task.PrepareCreate(userID)
+
+	if _, err := h.taskRepo.Create(c.Request().Context(), task); err != nil {
+		return internalError(c, "Failed to create task", err)
+	}
+
+	h.feed.Created(c.Request().Context(), task, userID)
+
+	return c.JSON(http.StatusCreated, task)
+}
+
+// UpdateTask updates the fields of a task that are set in the request.
+// Only admins and the task's creator may reassign it.
+func (h *TaskHandler) UpdateTask(c echo.Context) error {
+	var input models.UpdateTaskInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	userID, role := currentMember(c)
+	// A group task is shared by its members, who each report their own part through its completion
+	if task.IsGroupTask() && !task.ManagedBy(userID, role) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": groupTaskManagersOnly})
+	}
+
+	if title := strings.TrimSpace(input.Title); title != "" {
+		task.Title = title
+	}
+
+	if input.Description != "" {
+		task.Description = input.Description
+	}
+
+	if input.Priority != "" {
+		if !models.IsValidTaskPriority(input.Priority) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown priority: " + string(input.Priority)})
+		}
+		task.Priority = input.Priority
+	}
+
+	if input.Labels != nil {
+		labels, msg := normalizeLabels(*input.Labels)
+		if msg != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+		}
+		task.Labels = labels
+	}
+
+	if input.Status != "" {
+		if !models.IsValidTaskStatus(input.Status) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown status: " + string(input.Status)})
+		}
+		if refused, err := h.refuseBlockedProgress(c, task, input.Status, input.OverrideBlockers); refused {
+			return err
+		}
+		if err := h.moveToColumn(c, task, input.Status); err != nil {
+			return internalError(c, "Failed to rank task", err)
+		}
+	}
+
+	if input.DueDate != nil {
+		task.DueDate = input.DueDate
+	}
+
+	// A bare assignedTo keeps working as a reassignment to one member
+	assigneeType := input.AssigneeType
+	if assigneeType == "" && input.AssignedTo != "" {
+		assigneeType = models.TaskAssigneeUser
+	}
+
+	reassigned := false
+	if assigneeType != "" {
+		before := *task
+		msg, err := assignTask(c, h.userRepo, task, assigneeType, input.AssignedTo, input.AssignedSection, input.AssignedRole)
+		if err != nil {
+			return internalError(c, "Failed to get assignee", err)
+		}
+		if msg != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+		}
+
+		reassigned = !sameAssignment(&before, task)
+		if reassigned && !task.ManagedBy(userID, role) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the task's creator or an admin can reassign it"})
+		}
+	}
+
+	task.PrepareUpdate()
+
+	err = h.taskRepo.Update(c.Request().Context(), task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update task", err)
+	}
+
+	h.publish(c, realtime.ActionUpdated, task)
+	if reassigned {
+		h.notifyAssignees(c, task, userID)
+	}
+
+	return c.JSON(http.StatusOK, task)
+}
+
+// groupTaskManagersOnly is the error of a member changing a group task they do not manage
+const groupTaskManagersOnly = "Only the task's creator or an admin can change a group task; members complete their part with POST /api/tasks/:id/completion"
+
+// DeleteTask deletes a task with its comments and attachments.
+// Only admins and the task's creator may delete it.
+func (h *TaskHandler) DeleteTask(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	userID, role := currentMember(c)
+	if !task.ManagedBy(userID, role) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the task's creator or an admin can delete it"})
+	}
+
+	ctx := c.Request().Context()
+	attachments, err := h.attachmentRepo.FindByTask(ctx, task.ID)
+	if err != nil {
+		return internalError(c, "Failed to get attachments", err)
+	}
+
+	err = h.taskRepo.Delete(ctx, task.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete task", err)
+	}
+
+	// The task is gone, so leftovers are only logged; they can no longer be reached through the API
+	logger := logging.FromContext(ctx)
+	for _, attachment := range attachments {
+		if err := h.files.Delete(ctx, attachment.StorageKey); err != nil {
+			logger.WarnContext(ctx, "failed to delete attachment file", "key", attachment.StorageKey, "error", err)
+		}
+	}
+	if err := h.attachmentRepo.DeleteByTask(ctx, task.ID); err != nil {
+		logger.WarnContext(ctx, "failed to delete attachments", "task_id", task.ID.Hex(), "error", err)
+	}
+	if err := h.commentRepo.DeleteByTask(ctx, task.ID); err != nil {
+		logger.WarnContext(ctx, "failed to delete comments", "task_id", task.ID.Hex(), "error", err)
+	}
+	if err := h.taskRepo.RemoveBlockerFromAll(ctx, task.ID); err != nil {
+		logger.WarnContext(ctx, "failed to remove the task from its dependents", "task_id", task.ID.Hex(), "error", err)
+	}
+
+	h.publish(c, realtime.ActionDeleted, task)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/taskfeed"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeTasks keeps tasks in memory, handing out copies like the database does.
+// beforeAddBlocker runs just before a dependency is stored, like a concurrent request would.
+type fakeTasks struct {
+	repositories.TaskRepository
+	tasks            map[primitive.ObjectID]*models.Task
+	beforeAddBlocker func()
+}
+
+func newFakeTasks(tasks ...*models.Task) *fakeTasks {
+	f := &fakeTasks{tasks: map[primitive.ObjectID]*models.Task{}}
+	for _, task := range tasks {
+		f.tasks[task.ID] = task
+	}
+	return f
+}
+
+func (f *fakeTasks) FindByID(ctx context.Context, id string) (*models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	task, ok := f.tasks[objectID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *task
+	return &copied, nil
+}
+
+func (f *fakeTasks) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Task, error) {
+	var tasks []*models.Task
+	for _, id := range ids {
+		if task, ok := f.tasks[id]; ok {
+			copied := *task
+			tasks = append(tasks, &copied)
+		}
+	}
+	return tasks, nil
+}
+
+func (f *fakeTasks) Update(ctx context.Context, task *models.Task) error {
+	if _, ok := f.tasks[task.ID]; !ok {
+		return mongo.ErrNoDocuments
+	}
+	copied := *task
+	f.tasks[task.ID] = &copied
+	return nil
+}
+
+func (f *fakeTasks) LastRank(ctx context.Context, status models.TaskStatus) (string, error) {
+	last := ""
+	for _, task := range f.tasks {
+		if task.Status == status && task.Rank > last {
+			last = task.Rank
+		}
+	}
+	return last, nil
+}
+
+func (f *fakeTasks) HasTaskBetween(ctx context.Context, status models.TaskStatus, before, after string, except primitive.ObjectID, viewer *models.User) (bool, error) {
+	for _, task := range f.tasks {
+		if viewer != nil && !task.VisibleTo(viewer) {
+			continue
+		}
+		if task.ID != except && task.Status == status && task.Rank > before && (after == "" || task.Rank < after) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeTasks) AddBlocker(ctx context.Context, taskID, blockerID primitive.ObjectID) error {
+	if f.beforeAddBlocker != nil {
+		f.beforeAddBlocker()
+	}
+	task, ok := f.tasks[taskID]
+	if !ok {
+		return mongo.ErrNoDocuments
+	}
+	if !task.IsBlockedBy(blockerID) {
+		task.BlockedBy = append(task.BlockedBy, blockerID)
+	}
+	return nil
+}
+
+func (f *fakeTasks) RemoveBlocker(ctx context.Context, taskID, blockerID primitive.ObjectID) error {
+	task, ok := f.tasks[taskID]
+	if !ok {
+		return mongo.ErrNoDocuments
+	}
+	blockedBy := []primitive.ObjectID{}
+	for _, id := range task.BlockedBy {
+		if id != blockerID {
+			blockedBy = append(blockedBy, id)
+		}
+	}
+	task.BlockedBy = blockedBy
+	return nil
+}
+
+// fakeTaskUsers finds the accounts of the users making requests
+type fakeTaskUsers struct {
+	repositories.UserRepository
+	users []*models.User
+}
+
+func (f *fakeTaskUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.ID.Hex() == id {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeTaskUsers) List(ctx context.Context, filter models.UserFilter, query models.ListQuery) (*models.ListResult[*models.User], error) {
+	result := &models.ListResult[*models.User]{Items: []*models.User{}}
+	for _, u := range f.users {
+		if (filter.Role == "" || u.Role == filter.Role) && (filter.Section == "" || u.Section == filter.Section) {
+			result.Items = append(result.Items, u)
+		}
+	}
+	result.Total = int64(len(result.Items))
+	return result, nil
+}
+
+// hiddenTask returns a handler with a task that the returned member can neither see nor manage
+func hiddenTask() (*TaskHandler, *models.User, *models.Task) {
+	owner := &models.User{ID: primitive.NewObjectID(), Role: models.GeneralRole}
+	member := &models.User{ID: primitive.NewObjectID(), Role: models.GeneralRole}
+	task := &models.Task{ID: primitive.NewObjectID(), Status: models.TaskStatusTodo, Rank: "a0", AssignedTo: owner.ID, CreatedBy: owner.ID}
+	return newTaskHandler(newFakeTasks(task), owner, member), member, task
+}
+
+// newTaskHandler creates a TaskHandler on in-memory tasks for the given users
+func newTaskHandler(tasks *fakeTasks, users ...*models.User) *TaskHandler {
+	userRepo := &fakeTaskUsers{users: users}
+	return &TaskHandler{taskRepo: tasks, userRepo: userRepo, feed: taskfeed.New(userRepo, realtime.NewMemoryHub(), nil)}
+}
+
+// taskRequest builds a JSON request by user for the task in the :id path parameter
+func taskRequest(user *models.User, method, taskID, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/api/tasks/"+taskID, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(taskID)
+	auth.SetClaims(c, &auth.Claims{Role: user.Role, RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID.Hex()}})
+	return c, rec
+}
+
+// checkStatus fails the test if a handler did not respond with the expected status
+func checkStatus(t *testing.T, err error, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Handler returned an error: %v", err)
+	}
+	if rec.Code != want {
+		t.Fatalf("Expected status %d, got %d: %s", want, rec.Code, rec.Body.String())
+	}
+}
+
+func TestUpdateTaskLeavesGroupTasksToTheirManagers(t *testing.T) {
+	creator := &models.User{ID: primitive.NewObjectID(), Role: models.GeneralRole, Section: "brass"}
+	member := &models.User{ID: primitive.NewObjectID(), Role: models.GeneralRole, Section: "brass"}
+	task := &models.Task{ID: primitive.NewObjectID(), Title: "Polish instruments", Status: models.TaskStatusTodo, Rank: "a0",
+		AssigneeType: models.TaskAssigneeSection, AssignedSection: "brass", CreatedBy: creator.ID}
+	tasks := newFakeTasks(task)
+	h := newTaskHandler(tasks, creator, member)
+
+	c, rec := taskRequest(member, http.MethodPut, task.ID.Hex(), `{"status":"completed"}`)
+	checkStatus(t, h.UpdateTask(c), rec, http.StatusForbidden)
+	c, rec = taskRequest(member, http.MethodPut, task.ID.Hex(), `{"title":"Skip polishing"}`)
+	checkStatus(t, h.UpdateTask(c), rec, http.StatusForbidden)
+	c, rec = taskRequest(member, http.MethodPost, task.ID.Hex(), `{"status":"completed"}`)
+	checkStatus(t, h.MoveTask(c), rec, http.StatusForbidden)
+	if stored := tasks.tasks[task.ID]; stored.Status != models.TaskStatusTodo || stored.Title != "Polish instruments" {
+		t.Fatalf("Expected the group task to be unchanged, got %q %q", stored.Status, stored.Title)
+	}
+
+	c, rec = taskRequest(creator, http.MethodPut, task.ID.Hex(), `{"title":"Polish and oil instruments"}`)
+	checkStatus(t, h.UpdateTask(c), rec, http.StatusOK)
+	if stored := tasks.tasks[task.ID]; stored.Title != "Polish and oil instruments" {
+		t.Errorf("Expected the creator's edit to be saved, got %q", stored.Title)
+	}
+}
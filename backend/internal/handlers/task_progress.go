@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CompleteTask marks the current user's part of a task as finished. A user task is completed;
+// a group task records the member's completion and stays open for the rest of the group.
+func (h *TaskHandler) CompleteTask(c echo.Context) error {
+	var input models.CompleteTaskInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	return h.setCompletion(c, true, input.OverrideBlockers)
+}
+
+// UncompleteTask takes back the current user's completion of a task
+func (h *TaskHandler) UncompleteTask(c echo.Context) error {
+	return h.setCompletion(c, false, false)
+}
+
+// setCompletion records or removes the current user's completion of a task
+func (h *TaskHandler) setCompletion(c echo.Context, done, overrideBlockers bool) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return internalError(c, "Failed to get current user", err)
+	}
+	if !task.IsAssignee(user) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "The task is not assigned to you"})
+	}
+	if done {
+		if refused, err := h.refuseBlockedProgress(c, task, models.TaskStatusCompleted, overrideBlockers); refused {
+			return err
+		}
+	}
+
+	ctx := c.Request().Context()
+	if task.IsGroupTask() {
+		err = h.setGroupCompletion(c, task, user, done)
+	} else {
+		status := models.TaskStatusTodo
+		if done {
+			status = models.TaskStatusCompleted
+		}
+		if err = h.moveToColumn(c, task, status); err == nil {
+			task.PrepareUpdate()
+			err = h.taskRepo.Update(ctx, task)
+		}
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update task", err)
+	}
+
+	h.publish(c, realtime.ActionUpdated, task)
+
+	return c.JSON(http.StatusOK, task)
+}
+
+// setGroupCompletion records or removes a member's completion of a group task, also in task
+func (h *TaskHandler) setGroupCompletion(c echo.Context, task *models.Task, user *models.User, done bool) error {
+	ctx := c.Request().Context()
+	if !done {
+		if err := h.taskRepo.RemoveCompletion(ctx, task.ID, user.ID); err != nil {
+			return err
+		}
+		completions := []models.TaskCompletion{}
+		for _, completion := range task.Completions {
+			if completion.UserID != user.ID {
+				completions = append(completions, completion)
+			}
+		}
+		task.Completions = completions
+		return nil
+	}
+
+	if task.CompletedBy(user.ID) != nil {
+		return nil
+	}
+	completion := models.TaskCompletion{UserID: user.ID, CompletedAt: time.Now()}
+	if err := h.taskRepo.AddCompletion(ctx, task.ID, completion); err != nil {
+		return err
+	}
+	task.Completions = append(task.Completions, completion)
+	return nil
+}
+
+// GetTaskProgress shows which assignees have and have not finished a task.
+// Only admins and the task's creator may see it.
+func (h *TaskHandler) GetTaskProgress(c echo.Context) error {
+	task, err := h.findVisibleTask(c)
+	if task == nil {
+		return err
+	}
+
+	userID, role := currentMember(c)
+	if !task.ManagedBy(userID, role) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Only the task's creator or an admin can see its progress"})
+	}
+
+	assignees, err := h.assignees(c, task)
+	if err != nil {
+		return internalError(c, "Failed to get assignees", err)
+	}
+
+	return c.JSON(http.StatusOK, task.Progress(assignees))
+}
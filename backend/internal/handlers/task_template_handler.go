@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TaskTemplateHandler handles HTTP requests for managing recurring task templates
+type TaskTemplateHandler struct {
+	templateRepo repositories.TaskTemplateRepository
+	userRepo     repositories.UserRepository
+}
+
+// NewTaskTemplateHandler creates a new TaskTemplateHandler
+func NewTaskTemplateHandler(templateRepo repositories.TaskTemplateRepository, userRepo repositories.UserRepository) *TaskTemplateHandler {
+	return &TaskTemplateHandler{templateRepo: templateRepo, userRepo: userRepo}
+}
+
+// GetAllTaskTemplates lists all task templates
+func (h *TaskTemplateHandler) GetAllTaskTemplates(c echo.Context) error {
+	templates, err := h.templateRepo.FindAll(c.Request().Context())
+	if err != nil {
+		return internalError(c, "Failed to get task templates", err)
+	}
+
+	return c.JSON(http.StatusOK, templates)
+}
+
+// GetTaskTemplate gets a task template with its next due date
+func (h *TaskTemplateHandler) GetTaskTemplate(c echo.Context) error {
+	template, err := h.templateRepo.FindByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return internalError(c, "Failed to get task template", err)
+	}
+	if template == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task template not found"})
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// CreateTaskTemplate creates a task template and schedules its first task
+func (h *TaskTemplateHandler) CreateTaskTemplate(c echo.Context) error {
+	var input models.TaskTemplateInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	template := &models.TaskTemplate{}
+	msg, err := h.apply(c, template, input)
+	if err != nil {
+		return internalError(c, "Failed to get assignee", err)
+	}
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	userID, _ := currentMember(c)
+	template.PrepareCreate(userID)
+	template.Schedule(template.CreatedAt)
+
+	if _, err := h.templateRepo.Create(c.Request().Context(), template); err != nil {
+		return internalError(c, "Failed to create task template", err)
+	}
+
+	return c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTaskTemplate replaces a task template and reschedules its next task.
+// Tasks already created from the template are left as they are.
+func (h *TaskTemplateHandler) UpdateTaskTemplate(c echo.Context) error {
+	var input models.TaskTemplateInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	ctx := c.Request().Context()
+	template, err := h.templateRepo.FindByID(ctx, c.Param("id"))
+	if err != nil {
+		return internalError(c, "Failed to get task template", err)
+	}
+	if template == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task template not found"})
+	}
+
+	msg, err := h.apply(c, template, input)
+	if err != nil {
+		return internalError(c, "Failed to get assignee", err)
+	}
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	template.UpdatedAt = time.Now()
+	template.Schedule(template.UpdatedAt)
+
+	err = h.templateRepo.Update(ctx, template)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task template not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update task template", err)
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// DeleteTaskTemplate deletes a task template. Tasks already created from it are kept.
+func (h *TaskTemplateHandler) DeleteTaskTemplate(c echo.Context) error {
+	err := h.templateRepo.Delete(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task template not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete task template", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// apply validates the input and copies it onto the template.
+// It returns a message describing the first problem, if there is one.
+func (h *TaskTemplateHandler) apply(c echo.Context, template *models.TaskTemplate, input models.TaskTemplateInput) (string, error) {
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		return "Title is required", nil
+	}
+
+	checklist := []string{}
+	for _, text := range input.Checklist {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return "Checklist items must not be empty", nil
+		}
+		if utf8.RuneCountInString(text) > models.MaxChecklistItemLength {
+			return "Checklist items are too long", nil
+		}
+		checklist = append(checklist, text)
+	}
+
+	if input.Priority != "" && !models.IsValidTaskPriority(input.Priority) {
+		return "Unknown priority: " + string(input.Priority), nil
+	}
+	labels, msg := normalizeLabels(input.Labels)
+	if msg != "" {
+		return msg, nil
+	}
+
+	if err := input.Recurrence.Validate(); err != nil {
+		return "Invalid recurrence: " + err.Error(), nil
+	}
+	if input.LeadDays < 0 || input.LeadDays > models.MaxTaskTemplateLeadDays {
+		return fmt.Sprintf("Lead days must be between 0 and %d", models.MaxTaskTemplateLeadDays), nil
+	}
+
+	// Templates share the assignment rules of the tasks they create
+	var assignment models.Task
+	if msg, err := assignTask(c, h.userRepo, &assignment, input.AssigneeType, input.AssignedTo, input.AssignedSection, input.AssignedRole); msg != "" || err != nil {
+		return msg, err
+	}
+
+	template.Title = title
+	template.Description = input.Description
+	template.Checklist = checklist
+	template.Priority = input.Priority
+	if template.Priority == "" {
+		template.Priority = models.TaskPriorityMedium
+	}
+	template.Labels = labels
+	template.AssigneeType = assignment.AssigneeType
+	template.AssignedTo = assignment.AssignedTo
+	template.AssignedSection = assignment.AssignedSection
+	template.AssignedRole = assignment.AssignedRole
+	template.Recurrence = input.Recurrence
+	template.LeadDays = input.LeadDays
+	template.Active = input.Active
+	return "", nil
+}
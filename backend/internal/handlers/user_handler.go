@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/ratelimit"
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,15 +18,17 @@ import (
 
 // UserHandler handles HTTP requests related to users
 type UserHandler struct {
-	userRepo   repositories.UserRepository
-	jwtSecret  string
+	userRepo repositories.UserRepository
+	tokens   *auth.TokenIssuer
+	throttle *ratelimit.LoginThrottle
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(userRepo repositories.UserRepository, jwtSecret string) *UserHandler {
+func NewUserHandler(userRepo repositories.UserRepository, tokens *auth.TokenIssuer, throttle *ratelimit.LoginThrottle) *UserHandler {
 	return &UserHandler{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo: userRepo,
+		tokens:   tokens,
+		throttle: throttle,
 	}
 }
 
@@ -46,22 +53,25 @@ func (h *UserHandler) Register(c echo.Context) error {
 
 	// Create new user
 	user := &models.User{
-		Username: input.Username,
-		FullName: input.FullName,
-		Email:    input.Email,
-		Password: input.Password,
-		Role:     input.Role,
+		Username:   input.Username,
+		FullName:   input.FullName,
+		Email:      input.Email,
+		Password:   input.Password,
+		Role:       input.Role,
+		Section:    input.Section,
+		Instrument: input.Instrument,
 	}
 
 	user.PrepareCreate()
 	if err := user.HashPassword(); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
+		return internalError(c, "Failed to hash password", err)
 	}
 
 	id, err := h.userRepo.Create(c.Request().Context(), user)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
+		return internalError(c, "Failed to create user", err)
 	}
+	metrics.UsersCreated.WithLabelValues(metrics.UserSourceRegister).Inc()
 
 	user.ID, _ = primitive.ObjectIDFromHex(id)
 	user.Password = "" // Remove password from response
@@ -76,143 +86,229 @@ func (h *UserHandler) Login(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 	}
 
+	ctx := c.Request().Context()
+	ip := c.RealIP()
+
+	// Reject the attempt while the username or client is backing off
+	wait, err := h.throttle.Check(ctx, input.Username, ip)
+	if err != nil {
+		return internalError(c, "Failed to check login attempts", err)
+	}
+	if wait > 0 {
+		return tooManyAttempts(c, metrics.LoginMethodPassword, wait)
+	}
+
 	// Find user by username
-	user, err := h.userRepo.FindByUsername(c.Request().Context(), input.Username)
+	user, err := h.userRepo.FindByUsername(ctx, input.Username)
 	if err != nil || user == nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
+		return h.loginFailed(c, metrics.LoginMethodPassword, input.Username, ip)
 	}
 
 	// Check password
 	if !user.CheckPassword(input.Password) {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
+		return h.loginFailed(c, metrics.LoginMethodPassword, input.Username, ip)
 	}
 
-	// Create JWT token
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["id"] = user.ID.Hex()
-	claims["username"] = user.Username
-	claims["role"] = user.Role
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
+	// Accounts with 2FA get a short-lived challenge instead of a token.
+	// The throttle is only cleared once the second factor is verified.
+	if user.TwoFactorEnabled {
+		challenge, err := h.tokens.ChallengeToken(user)
+		if err != nil {
+			return internalError(c, "Failed to generate token", err)
+		}
+		metrics.Logins.WithLabelValues(metrics.LoginMethodPassword, metrics.LoginChallenged).Inc()
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"twoFactorRequired": true,
+			"challengeToken":    challenge,
+		})
+	}
+
+	if err := h.throttle.Success(ctx, input.Username); err != nil {
+		return internalError(c, "Failed to record login", err)
+	}
 
-	// Generate encoded token
-	tokenString, err := token.SignedString([]byte(h.jwtSecret))
+	tokenString, err := h.tokens.AccessToken(user, false)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		return internalError(c, "Failed to generate token", err)
 	}
+	metrics.Logins.WithLabelValues(metrics.LoginMethodPassword, metrics.LoginSuccess).Inc()
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"token": tokenString,
 	})
 }
 
+// loginFailed records a failed login and responds with 401
+func (h *UserHandler) loginFailed(c echo.Context, method, username, ip string) error {
+	metrics.Logins.WithLabelValues(method, metrics.LoginFailure).Inc()
+	if err := h.throttle.Failure(c.Request().Context(), username, ip); err != nil {
+		return internalError(c, "Failed to record login attempt", err)
+	}
+	return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
+}
+
+// tooManyAttempts records a throttled login and responds with 429 and a Retry-After header
+func tooManyAttempts(c echo.Context, method string, wait time.Duration) error {
+	metrics.Logins.WithLabelValues(method, metrics.LoginThrottled).Inc()
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "Too many failed login attempts, try again later"})
+}
+
 // GetMe gets the current user
 func (h *UserHandler) GetMe(c echo.Context) error {
-	userID := c.Get("user").(jwt.MapClaims)["id"].(string)
-	
+	userID, _, ok := auth.CurrentUser(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
 	user, err := h.userRepo.FindByID(c.Request().Context(), userID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get user"})
+		return internalError(c, "Failed to get user", err)
+	}
+	if user == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 	}
-	
+
 	user.Password = "" // Remove password from response
-	
+
 	return c.JSON(http.StatusOK, user)
 }
 
-// GetAllUsers gets all users
+// GetAllUsers gets a page of users, optionally filtered, sorted and searched
 func (h *UserHandler) GetAllUsers(c echo.Context) error {
-	users, err := h.userRepo.FindAll(c.Request().Context())
+	query, err := parseListQuery(c, models.UserSortFields, "name")
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get users"})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	result, err := h.userRepo.List(c.Request().Context(), filter, query)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
 	}
-	
+	if err != nil {
+		return internalError(c, "Failed to get users", err)
+	}
+
 	// Remove passwords from response
-	for _, user := range users {
+	for _, user := range result.Items {
 		user.Password = ""
 	}
-	
-	return c.JSON(http.StatusOK, users)
+
+	return c.JSON(http.StatusOK, result)
 }
 
 // GetUser gets a user by ID
 func (h *UserHandler) GetUser(c echo.Context) error {
 	id := c.Param("id")
-	
+
 	user, err := h.userRepo.FindByID(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get user"})
+		return internalError(c, "Failed to get user", err)
 	}
-	
+
 	if user == nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 	}
-	
+
 	user.Password = "" // Remove password from response
-	
+
 	return c.JSON(http.StatusOK, user)
 }
 
 // UpdateUser updates a user
 func (h *UserHandler) UpdateUser(c echo.Context) error {
 	id := c.Param("id")
-	
+
 	var input models.UpdateUserInput
 	if err := c.Bind(&input); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 	}
-	
+
 	user, err := h.userRepo.FindByID(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get user"})
+		return internalError(c, "Failed to get user", err)
 	}
-	
+
 	if user == nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 	}
-	
+
 	// Update fields
 	if input.Username != "" {
 		user.Username = input.Username
 	}
-	
+
 	if input.FullName != "" {
 		user.FullName = input.FullName
 	}
-	
+
 	if input.Email != "" {
 		user.Email = input.Email
 	}
-	
+
 	if input.Password != "" {
 		user.Password = input.Password
 		if err := user.HashPassword(); err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
+			return internalError(c, "Failed to hash password", err)
 		}
 	}
-	
+
 	if input.Role != "" {
 		user.Role = input.Role
 	}
-	
+
+	if input.Section != "" {
+		user.Section = input.Section
+	}
+
+	if input.Instrument != "" {
+		user.Instrument = input.Instrument
+	}
+
 	user.PrepareUpdate()
-	
+
 	if err := h.userRepo.Update(c.Request().Context(), id, user); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update user"})
+		return internalError(c, "Failed to update user", err)
 	}
-	
+
 	user.Password = "" // Remove password from response
-	
+
 	return c.JSON(http.StatusOK, user)
 }
 
+// UnlockUser lifts a login lockout on a user
+func (h *UserHandler) UnlockUser(c echo.Context) error {
+	id := c.Param("id")
+
+	user, err := h.userRepo.FindByID(c.Request().Context(), id)
+	if err != nil {
+		return internalError(c, "Failed to get user", err)
+	}
+
+	if user == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	}
+
+	if err := h.throttle.Unlock(c.Request().Context(), user.Username); err != nil {
+		return internalError(c, "Failed to unlock user", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 // DeleteUser deletes a user
 func (h *UserHandler) DeleteUser(c echo.Context) error {
 	id := c.Param("id")
-	
+
 	if err := h.userRepo.Delete(c.Request().Context(), id); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete user"})
+		return internalError(c, "Failed to delete user", err)
 	}
-	
+
 	return c.NoContent(http.StatusNoContent)
-}
\ No newline at end of file
+}
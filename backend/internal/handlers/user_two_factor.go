@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// totpIssuer is the account issuer shown in authenticator apps
+const totpIssuer = "FUTO Marching Dashboard"
+
+// checkSecondFactor verifies a TOTP code or consumes a recovery code. The used code is saved
+// with a conditional update, so a code is only accepted once even if it is sent twice at the
+// same time. On success the user is also updated in memory.
+func (h *UserHandler) checkSecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return false, nil
+		}
+		if ok, err := h.userRepo.RecordTOTPStep(ctx, user.ID, step); !ok || err != nil {
+			return false, err
+		}
+		user.TOTPLastStep = step
+		return true, nil
+	}
+
+	if recoveryCode != "" {
+		remaining, ok := auth.UseRecoveryCode(user.RecoveryCodes, recoveryCode)
+		if !ok {
+			return false, nil
+		}
+		if ok, err := h.userRepo.UseRecoveryCode(ctx, user.ID, auth.HashRecoveryCode(recoveryCode)); !ok || err != nil {
+			return false, err
+		}
+		user.RecoveryCodes = remaining
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// checkThrottledSecondFactor checks a code sent by a signed-in user through the login throttle,
+// so a stolen access token cannot be used to guess codes. When it reports false, the error
+// response has already been written and the returned error is the result of writing it.
+func (h *UserHandler) checkThrottledSecondFactor(c echo.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	ctx := c.Request().Context()
+	ip := c.RealIP()
+
+	wait, err := h.throttle.Check(ctx, user.Username, ip)
+	if err != nil {
+		return false, internalError(c, "Failed to check login attempts", err)
+	}
+	if wait > 0 {
+		return false, tooManyAttempts(c, metrics.LoginMethodTwoFactor, wait)
+	}
+
+	ok, err := h.checkSecondFactor(ctx, user, code, recoveryCode)
+	if err != nil {
+		return false, internalError(c, "Failed to check second factor", err)
+	}
+	if !ok {
+		if err := h.throttle.Failure(ctx, user.Username, ip); err != nil {
+			return false, internalError(c, "Failed to record login attempt", err)
+		}
+		return false, c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid code"})
+	}
+
+	if err := h.throttle.Success(ctx, user.Username); err != nil {
+		return false, internalError(c, "Failed to record login", err)
+	}
+	return true, nil
+}
+
+// LoginTwoFactor completes a login by verifying the second factor for a challenge token
+func (h *UserHandler) LoginTwoFactor(c echo.Context) error {
+	var input models.TwoFactorLoginInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	ctx := c.Request().Context()
+	ip := c.RealIP()
+
+	userID, ok := h.tokens.ParseChallengeToken(input.ChallengeToken)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired challenge"})
+	}
+
+	user, err := h.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil || !user.TwoFactorEnabled {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired challenge"})
+	}
+
+	wait, err := h.throttle.Check(ctx, user.Username, ip)
+	if err != nil {
+		return internalError(c, "Failed to check login attempts", err)
+	}
+	if wait > 0 {
+		return tooManyAttempts(c, metrics.LoginMethodTwoFactor, wait)
+	}
+
+	ok, err = h.checkSecondFactor(ctx, user, input.Code, input.RecoveryCode)
+	if err != nil {
+		return internalError(c, "Failed to check second factor", err)
+	}
+	if !ok {
+		return h.loginFailed(c, metrics.LoginMethodTwoFactor, user.Username, ip)
+	}
+
+	if err := h.throttle.Success(ctx, user.Username); err != nil {
+		return internalError(c, "Failed to record login", err)
+	}
+
+	tokenString, err := h.tokens.AccessToken(user, true)
+	if err != nil {
+		return internalError(c, "Failed to generate token", err)
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginMethodTwoFactor, metrics.LoginSuccess).Inc()
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"token":                  tokenString,
+		"recoveryCodesRemaining": len(user.RecoveryCodes),
+	})
+}
+
+// EnrollTwoFactor starts 2FA enrollment by generating a new TOTP secret.
+// 2FA is not enabled until the secret is confirmed with VerifyTwoFactor.
+func (h *UserHandler) EnrollTwoFactor(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userID, _, ok := auth.CurrentUser(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	user, err := h.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return internalError(c, "Failed to get user", err)
+	}
+
+	if user.TwoFactorEnabled {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return internalError(c, "Failed to generate secret", err)
+	}
+
+	err = h.userRepo.StartTwoFactorEnrollment(ctx, user.ID, secret)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Two-factor authentication is already enabled"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update user", err)
+	}
+
+	return c.JSON(http.StatusOK, models.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// VerifyTwoFactor confirms enrollment with a code from the authenticator app,
+// enables 2FA and returns a fresh set of recovery codes
+func (h *UserHandler) VerifyTwoFactor(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var input models.TwoFactorCodeInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	userID, _, ok := auth.CurrentUser(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	user, err := h.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return internalError(c, "Failed to get user", err)
+	}
+
+	if user.TwoFactorEnabled {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Two-factor authentication is already enabled"})
+	}
+
+	if user.TOTPSecret == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Two-factor enrollment has not been started"})
+	}
+
+	if ok, err := h.checkThrottledSecondFactor(c, user, input.Code, ""); !ok {
+		return err
+	}
+
+	codes, hashes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return internalError(c, "Failed to generate recovery codes", err)
+	}
+
+	// The secret must still be the one the code was checked against
+	err = h.userRepo.EnableTwoFactor(ctx, user.ID, user.TOTPSecret, hashes)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Two-factor enrollment has changed; start it again"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update user", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string][]string{"recoveryCodes": codes})
+}
+
+// DisableTwoFactor turns 2FA off after checking a current code or recovery code
+func (h *UserHandler) DisableTwoFactor(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var input models.TwoFactorCodeInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	userID, _, ok := auth.CurrentUser(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	user, err := h.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return internalError(c, "Failed to get user", err)
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Two-factor authentication is not enabled"})
+	}
+
+	if ok, err := h.checkThrottledSecondFactor(c, user, input.Code, input.RecoveryCode); !ok {
+		return err
+	}
+
+	err = h.userRepo.DisableTwoFactor(ctx, user.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Two-factor authentication is not enabled"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update user", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
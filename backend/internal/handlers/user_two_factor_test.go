@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/ratelimit"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeTwoFactorUsers stores the 2FA state of one user, like the conditional updates of the user repository
+type fakeTwoFactorUsers struct {
+	repositories.UserRepository
+	user          *models.User
+	lastStep      int64
+	recoveryCodes []string
+}
+
+func (f *fakeTwoFactorUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	copied := *f.user
+	return &copied, nil
+}
+
+func (f *fakeTwoFactorUsers) DisableTwoFactor(ctx context.Context, id primitive.ObjectID) error {
+	f.user.TwoFactorEnabled = false
+	return nil
+}
+
+func (f *fakeTwoFactorUsers) RecordTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	if step <= f.lastStep {
+		return false, nil
+	}
+	f.lastStep = step
+	return true, nil
+}
+
+func (f *fakeTwoFactorUsers) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	for i, h := range f.recoveryCodes {
+		if h == hash {
+			f.recoveryCodes = append(f.recoveryCodes[:i], f.recoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestCheckSecondFactorAcceptsEachCodeOnce(t *testing.T) {
+	ctx := context.Background()
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Error generating secret: %v", err)
+	}
+	codes, hashes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("Error generating recovery codes: %v", err)
+	}
+
+	users := &fakeTwoFactorUsers{recoveryCodes: append([]string{}, hashes...)}
+	h := &UserHandler{userRepo: users}
+
+	// Two requests that loaded the user before either one saved it
+	first := &models.User{ID: primitive.NewObjectID(), TOTPSecret: secret, RecoveryCodes: hashes}
+	second := *first
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("Error generating code: %v", err)
+	}
+	if ok, err := h.checkSecondFactor(ctx, first, code, ""); !ok || err != nil {
+		t.Fatalf("Expected the code to be accepted, got %v (%v)", ok, err)
+	}
+	if ok, _ := h.checkSecondFactor(ctx, &second, code, ""); ok {
+		t.Error("Expected a replayed code to be refused")
+	}
+
+	if ok, err := h.checkSecondFactor(ctx, first, "", codes[0]); !ok || err != nil {
+		t.Fatalf("Expected the recovery code to be accepted, got %v (%v)", ok, err)
+	}
+	if ok, _ := h.checkSecondFactor(ctx, &second, "", codes[0]); ok {
+		t.Error("Expected a used recovery code to be refused")
+	}
+	if len(first.RecoveryCodes) != len(hashes)-1 || len(users.recoveryCodes) != len(hashes)-1 {
+		t.Errorf("Expected one recovery code to be used up, got %d in memory and %d stored", len(first.RecoveryCodes), len(users.recoveryCodes))
+	}
+}
+
+func TestDisableTwoFactorIsThrottled(t *testing.T) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Error generating secret: %v", err)
+	}
+	user := &models.User{ID: primitive.NewObjectID(), Username: "hanako", Role: models.GeneralRole, TwoFactorEnabled: true, TOTPSecret: secret}
+	users := &fakeTwoFactorUsers{user: user}
+	policy := ratelimit.Policy{FreeAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Hour, MaxFailures: 5, LockoutDuration: time.Hour, Window: time.Hour}
+	h := &UserHandler{userRepo: users, throttle: ratelimit.NewLoginThrottle(ratelimit.NewMemoryLimiter(policy), ratelimit.NewMemoryLimiter(policy))}
+
+	disable := func(code string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/api/users/me/2fa/disable", strings.NewReader(`{"code":"`+code+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		auth.SetClaims(c, &auth.Claims{Role: user.Role, RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID.Hex()}})
+		return rec, h.DisableTwoFactor(c)
+	}
+
+	// Guessing codes with a stolen access token is slowed down like guessing them at login
+	for _, want := range []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests} {
+		rec, err := disable("000000")
+		checkStatus(t, err, rec, want)
+	}
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("Error generating code: %v", err)
+	}
+	rec, err := disable(code)
+	checkStatus(t, err, rec, http.StatusTooManyRequests)
+	if !user.TwoFactorEnabled {
+		t.Error("Expected 2FA to stay enabled while throttled")
+	}
+}
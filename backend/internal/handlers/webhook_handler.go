@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/webhooks"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WebhookHandler handles HTTP requests for managing webhook subscriptions and their deliveries
+type WebhookHandler struct {
+	subscriptionRepo repositories.WebhookSubscriptionRepository
+	deliveryRepo     repositories.WebhookDeliveryRepository
+	service          *webhooks.Service
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(subscriptionRepo repositories.WebhookSubscriptionRepository, deliveryRepo repositories.WebhookDeliveryRepository, service *webhooks.Service) *WebhookHandler {
+	return &WebhookHandler{subscriptionRepo: subscriptionRepo, deliveryRepo: deliveryRepo, service: service}
+}
+
+// GetAllWebhooks lists all webhook subscriptions without their secrets
+func (h *WebhookHandler) GetAllWebhooks(c echo.Context) error {
+	subscriptions, err := h.subscriptionRepo.FindAll(c.Request().Context())
+	if err != nil {
+		return internalError(c, "Failed to get webhooks", err)
+	}
+
+	return c.JSON(http.StatusOK, subscriptions)
+}
+
+// CreateWebhook creates a webhook subscription. Its signing secret is only shown in this response.
+func (h *WebhookHandler) CreateWebhook(c echo.Context) error {
+	var input models.CreateWebhookSubscriptionInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	name, events, msg := validateWebhookInput(input.Name, input.URL, input.Events)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	createdBy, _ := currentUserObjectID(c)
+	subscription, err := models.NewWebhookSubscription(name, input.URL, events, createdBy)
+	if err != nil {
+		return internalError(c, "Failed to generate webhook secret", err)
+	}
+
+	if _, err := h.subscriptionRepo.Create(c.Request().Context(), subscription); err != nil {
+		return internalError(c, "Failed to create webhook", err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"webhook": subscription,
+		"secret":  subscription.Secret,
+	})
+}
+
+// UpdateWebhook replaces a webhook subscription's name, URL, event types and active flag
+func (h *WebhookHandler) UpdateWebhook(c echo.Context) error {
+	var input models.UpdateWebhookSubscriptionInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	name, events, msg := validateWebhookInput(input.Name, input.URL, input.Events)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	ctx := c.Request().Context()
+	subscription, err := h.subscriptionRepo.FindByID(ctx, c.Param("id"))
+	if err != nil {
+		return internalError(c, "Failed to get webhook", err)
+	}
+	if subscription == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+	}
+
+	subscription.Name = name
+	subscription.URL = input.URL
+	subscription.Events = events
+	subscription.Active = input.Active
+	subscription.UpdatedAt = time.Now()
+
+	err = h.subscriptionRepo.Update(ctx, subscription)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to update webhook", err)
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhook deletes a webhook subscription. Its pending deliveries are dead-lettered when next attempted.
+func (h *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	err := h.subscriptionRepo.Delete(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete webhook", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists a webhook subscription's deliveries with their attempts, newest first
+func (h *WebhookHandler) GetWebhookDeliveries(c echo.Context) error {
+	subscriptionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+	}
+
+	filter := models.WebhookDeliveryFilter{SubscriptionID: subscriptionID, Status: models.WebhookDeliveryStatus(c.QueryParam("status"))}
+	switch filter.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryDead:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown status: " + string(filter.Status)})
+	}
+
+	query, err := parseListQuery(c, []string{"createdAt"}, "createdAt")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if c.QueryParam("order") == "" {
+		query.Order = models.SortDesc
+	}
+
+	result, err := h.deliveryRepo.List(c.Request().Context(), filter, query)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+	}
+	if err != nil {
+		return internalError(c, "Failed to get webhook deliveries", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// RedeliverWebhookDelivery queues a delivery to be sent again with the same event ID and payload
+func (h *WebhookHandler) RedeliverWebhookDelivery(c echo.Context) error {
+	delivery, err := h.service.Redeliver(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return internalError(c, "Failed to redeliver webhook", err)
+	}
+	if delivery == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Delivery not found"})
+	}
+
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+// validateWebhookInput checks a subscription's fields and returns the trimmed name and
+// deduplicated event types, or a message describing the first problem
+func validateWebhookInput(name, url string, events []models.WebhookEventType) (string, []models.WebhookEventType, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, "Name is required"
+	}
+	if !isHTTPSURL(url) {
+		return "", nil, "URL must be an https URL"
+	}
+	if len(events) == 0 {
+		return "", nil, "At least one event type is required"
+	}
+
+	unique := []models.WebhookEventType{}
+	seen := map[models.WebhookEventType]bool{}
+	for _, event := range events {
+		if !models.IsValidWebhookEventType(event) {
+			return "", nil, "Unknown event type: " + string(event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+	return name, unique, ""
+}
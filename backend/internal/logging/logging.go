@@ -0,0 +1,47 @@
+// Package logging provides structured JSON logging with loggers bound to request contexts
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// contextKey is the context key the request logger is stored under
+type contextKey struct{}
+
+// levels maps configured log levels to slog levels
+var levels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// New creates a JSON logger writing to w that drops records below level.
+// Unknown levels fall back to info.
+func New(w io.Writer, level string) *slog.Logger {
+	lvl, ok := levels[level]
+	if !ok {
+		lvl = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl}))
+}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger bound to ctx, or the default logger if there is none
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a copy of ctx whose logger adds the given attributes to every record
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
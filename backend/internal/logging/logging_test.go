@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), New(&buf, "info"))
+	ctx = With(ctx, "request_id", "abc", "user_id", "u1")
+
+	FromContext(ctx).Debug("dropped")
+	FromContext(ctx).Info("listed users", "count", 3)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected exactly one JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "listed users" || record["request_id"] != "abc" || record["user_id"] != "u1" || record["count"] != float64(3) {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	if FromContext(context.Background()) == nil {
+		t.Error("Expected the default logger")
+	}
+}
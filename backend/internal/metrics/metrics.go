@@ -0,0 +1,154 @@
+// Package metrics defines the Prometheus metrics the backend exports at /metrics
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "fmd"
+
+// Login methods and results recorded by Logins
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodTwoFactor = "two_factor"
+	LoginMethodOIDC      = "oidc"
+
+	LoginSuccess   = "success"
+	LoginFailure   = "failure"
+	LoginThrottled = "throttled"
+	// LoginChallenged means the password was accepted and a second factor was requested
+	LoginChallenged = "challenged"
+)
+
+// Sources of new user accounts recorded by UsersCreated
+const (
+	UserSourceRegister = "register"
+	UserSourceImport   = "import"
+	UserSourceOIDC     = "oidc"
+)
+
+// Results of notification deliveries recorded by NotificationsSent
+const (
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed"
+	// NotificationSkipped means the channel is not configured or the recipient has no address for it
+	NotificationSkipped = "skipped"
+)
+
+// Registry holds every metric the backend exports, along with Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	// HTTPRequestDuration observes request latency by method, route template and status
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestsInFlight counts requests currently being served by method and route template
+	HTTPRequestsInFlight = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served by route template.",
+	}, []string{"method", "route"})
+
+	// MongoOperationDuration observes the duration of repository methods
+	MongoOperationDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of MongoDB operations by repository method.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repository", "method"})
+
+	// Logins counts login attempts by method and result
+	Logins = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by method and result.",
+	}, []string{"method", "result"})
+
+	// UsersCreated counts user accounts created by source
+	UsersCreated = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_created_total",
+		Help:      "User accounts created by source.",
+	}, []string{"source"})
+
+	// InvitationsAccepted counts invitations redeemed by new members
+	InvitationsAccepted = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invitations_accepted_total",
+		Help:      "Invitations accepted by new members.",
+	})
+
+	// NotificationsSent counts notification deliveries outside the app by channel and result
+	NotificationsSent = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Notification deliveries by channel and result.",
+	}, []string{"channel", "result"})
+
+	// RemindersSent counts reminders sent by what they were about
+	RemindersSent = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reminders_sent_total",
+		Help:      "Reminders sent before events and task due dates.",
+	}, []string{"kind"})
+
+	// RecurringTasksCreated counts tasks created from recurring task templates
+	RecurringTasksCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "recurring_tasks_created_total",
+		Help:      "Tasks created from recurring task templates.",
+	})
+
+	// WebhookDeliveries counts webhook delivery attempts by the status they left the delivery in:
+	// succeeded, pending for a retry, or dead
+	WebhookDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by resulting delivery status.",
+	}, []string{"status"})
+
+	// StreamSubscribers counts clients connected to the change stream
+	StreamSubscribers = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "realtime",
+		Name:      "subscribers",
+		Help:      "Clients connected to the change stream.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveMongo starts timing a repository method; call the returned function when it finishes
+func ObserveMongo(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		MongoOperationDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+)
+
+// apiKeyTouchInterval limits how often last-used timestamps are written
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyRoutes records which routes accept API keys and the scope each requires.
+// Routes that were not allowed reject API keys.
+type APIKeyRoutes struct {
+	scopes map[string]string
+}
+
+// NewAPIKeyRoutes creates an empty APIKeyRoutes
+func NewAPIKeyRoutes() *APIKeyRoutes {
+	return &APIKeyRoutes{scopes: map[string]string{}}
+}
+
+// Allow lets API keys granted scope call route. Routes must be allowed before the server starts.
+func (r *APIKeyRoutes) Allow(route *echo.Route, scope string) {
+	r.scopes[route.Method+" "+route.Path] = scope
+}
+
+// scope returns the scope required to call the matched route with an API key
+func (r *APIKeyRoutes) scope(c echo.Context) (string, bool) {
+	scope, ok := r.scopes[c.Request().Method+" "+c.Path()]
+	return scope, ok
+}
+
+// APIKeyMiddleware authenticates requests with an "Authorization: ApiKey ..." header.
+// It runs before JWTMiddleware, which lets requests authenticated here through.
+func APIKeyMiddleware(apiKeyRepo repositories.APIKeyRepository, routes *APIKeyRoutes) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scheme, key, found := strings.Cut(c.Request().Header.Get("Authorization"), " ")
+			if !found || scheme != "ApiKey" {
+				return next(c)
+			}
+
+			scope, ok := routes.scope(c)
+			if !ok {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "API keys are not allowed on this route"})
+			}
+
+			ctx := c.Request().Context()
+
+			apiKey, err := apiKeyRepo.FindByHash(ctx, models.HashAPIKey(strings.TrimSpace(key)))
+			if err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "Failed to check API key", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check API key"})
+			}
+
+			now := time.Now()
+			if apiKey == nil || !apiKey.IsActive(now) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired API key"})
+			}
+
+			if !apiKey.HasScope(scope) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "API key lacks the required scope"})
+			}
+
+			if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+				if err := apiKeyRepo.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+					logging.FromContext(ctx).Warn("failed to record API key use", "api_key_id", apiKey.ID.Hex(), "error", err)
+				}
+			}
+
+			auth.SetAPIKey(c, apiKey)
+			addLogAttrs(c, "api_key_id", apiKey.ID.Hex())
+
+			return next(c)
+		}
+	}
+}
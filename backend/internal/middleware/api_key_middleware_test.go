@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryAPIKeyRepo is an in-memory APIKeyRepository for tests
+type memoryAPIKeyRepo struct {
+	keys []*models.APIKey
+}
+
+func (r *memoryAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) (string, error) {
+	key.ID = primitive.NewObjectID()
+	r.keys = append(r.keys, key)
+	return key.ID.Hex(), nil
+}
+
+func (r *memoryAPIKeyRepo) FindAll(ctx context.Context) ([]*models.APIKey, error) {
+	return r.keys, nil
+}
+
+func (r *memoryAPIKeyRepo) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryAPIKeyRepo) Revoke(ctx context.Context, id string) error {
+	return nil
+}
+
+func (r *memoryAPIKeyRepo) TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	repo := &memoryAPIKeyRepo{}
+	newKey := func(scopes []string, expiresAt *time.Time) string {
+		apiKey, key, err := models.NewAPIKey("nightly export", scopes, expiresAt, primitive.NewObjectID())
+		if err != nil {
+			t.Fatalf("Error creating API key: %v", err)
+		}
+		repo.Create(context.Background(), apiKey)
+		return key
+	}
+
+	exportKey := newKey([]string{models.ScopeUsersExport}, nil)
+	past := time.Now().Add(-time.Hour)
+	expiredKey := newKey([]string{models.ScopeUsersExport}, &past)
+	revokedKey := newKey([]string{models.ScopeUsersExport}, nil)
+	repo.keys[2].RevokedAt = &past
+
+	e := echo.New()
+	routes := NewAPIKeyRoutes()
+	api := e.Group("/api")
+	api.Use(APIKeyMiddleware(repo, routes))
+	api.Use(JWTMiddleware(auth.NewTokenIssuer(auth.NewHMACKeySet("secret"), auth.TokenConfig{Issuer: "issuer", Audience: "audience", AccessTokenTTL: time.Hour})))
+
+	admin := api.Group("/admin")
+	admin.Use(RoleMiddleware(models.AdminRole))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	routes.Allow(admin.GET("/users/export", ok), models.ScopeUsersExport)
+	routes.Allow(admin.GET("/users/:id", ok), models.ScopeUsersRead)
+	admin.DELETE("/users/:id", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		want   int
+	}{
+		{"allowed route with scope", http.MethodGet, "/api/admin/users/export", "ApiKey " + exportKey, http.StatusOK},
+		{"allowed route without scope", http.MethodGet, "/api/admin/users/abc", "ApiKey " + exportKey, http.StatusForbidden},
+		{"route not allowed", http.MethodDelete, "/api/admin/users/abc", "ApiKey " + exportKey, http.StatusForbidden},
+		{"unknown key", http.MethodGet, "/api/admin/users/export", "ApiKey fmd_unknown", http.StatusUnauthorized},
+		{"expired key", http.MethodGet, "/api/admin/users/export", "ApiKey " + expiredKey, http.StatusUnauthorized},
+		{"revoked key", http.MethodGet, "/api/admin/users/export", "ApiKey " + revokedKey, http.StatusUnauthorized},
+		{"bearer still required without key", http.MethodGet, "/api/admin/users/export", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if repo.keys[0].LastUsedAt == nil {
+		t.Error("Expected last use to be recorded")
+	}
+}
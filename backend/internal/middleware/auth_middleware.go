@@ -4,15 +4,21 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
 	"github.com/labstack/echo/v4"
 )
 
-// JWTMiddleware creates a middleware for JWT authentication
-func JWTMiddleware(jwtSecret string) echo.MiddlewareFunc {
+// JWTMiddleware creates a middleware for JWT authentication.
+// Verified claims are available to handlers through auth.CurrentUser.
+func JWTMiddleware(tokens *auth.TokenIssuer) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			// Already authenticated by APIKeyMiddleware
+			if _, ok := auth.CurrentAPIKey(c); ok {
+				return next(c)
+			}
+
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authorization header required"})
@@ -26,30 +32,14 @@ func JWTMiddleware(jwtSecret string) echo.MiddlewareFunc {
 
 			tokenString := parts[1]
 
-			// Parse JWT token
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token signing method")
-				}
-				return []byte(jwtSecret), nil
-			})
-
+			// Verify signature, issuer, audience and lifetime
+			claims, err := tokens.ParseAccessToken(tokenString)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
 			}
 
-			// Check if token is valid
-			if !token.Valid {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
-			}
-
-			// Set user claims in context
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token claims"})
-			}
-
-			c.Set("user", claims)
+			auth.SetClaims(c, claims)
+			addLogAttrs(c, "user_id", claims.Subject)
 
 			return next(c)
 		}
@@ -60,22 +50,21 @@ func JWTMiddleware(jwtSecret string) echo.MiddlewareFunc {
 func RoleMiddleware(roles ...models.Role) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Get user claims from context
-			claims, ok := c.Get("user").(jwt.MapClaims)
-			if !ok {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			// API keys are limited by the scope of the route instead of a role
+			if _, ok := auth.CurrentAPIKey(c); ok {
+				return next(c)
 			}
 
-			// Check if user has required role
-			userRole, ok := claims["role"].(string)
+			// Get the authenticated user from context
+			_, userRole, ok := auth.CurrentUser(c)
 			if !ok {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid user role"})
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 			}
 
 			// Check if the user's role is in the allowed roles
 			hasRole := false
 			for _, role := range roles {
-				if string(role) == userRole {
+				if role == userRole {
 					hasRole = true
 					break
 				}
@@ -88,4 +77,28 @@ func RoleMiddleware(roles ...models.Role) echo.MiddlewareFunc {
 			return next(c)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// RequireTwoFactor creates a middleware that only admits tokens issued after a
+// completed second factor
+func RequireTwoFactor() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// API keys are not interactive logins
+			if _, ok := auth.CurrentAPIKey(c); ok {
+				return next(c)
+			}
+
+			claims, ok := auth.CurrentClaims(c)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			}
+
+			if !claims.MFA {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "Two-factor authentication required"})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"headers ignored without proxies", nil, "203.0.113.7:4321", "198.51.100.1", "203.0.113.7"},
+		{"private peers are not trusted by default", nil, "10.0.0.2:4321", "198.51.100.1", "10.0.0.2"},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "10.0.0.2:4321", "198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"spoofed hop before the proxy", []string{"10.0.0.2"}, "10.0.0.2:4321", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"untrusted peer", []string{"10.0.0.0/8"}, "203.0.113.7:4321", "198.51.100.1", "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extract, err := ClientIP(tt.trusted)
+			if err != nil {
+				t.Fatalf("Error creating extractor: %v", err)
+			}
+
+			req := httptest.NewRequest("POST", "/api/auth/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, tt.forwarded)
+			req.Header.Set(echo.HeaderXRealIP, tt.forwarded)
+
+			if got := extract(req); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := ClientIP([]string{"proxy.internal"}); err == nil {
+		t.Error("Expected a host name to be rejected")
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that matched no route, so arbitrary paths cannot create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics creates a middleware that records request latency and in-flight requests by route template.
+// Requests for which skip returns true are not recorded.
+func Metrics(skip func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skip != nil && skip(c) {
+				return next(c)
+			}
+
+			method := c.Request().Method
+			route := c.Path()
+			if route == "" || route == "/*" {
+				route = unmatchedRoute
+			}
+
+			inFlight := metrics.HTTPRequestsInFlight.WithLabelValues(method, route)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				// Let the error handler write the response so the recorded status is the one sent
+				c.Error(err)
+			}
+
+			status := strconv.Itoa(c.Response().Status)
+			metrics.HTTPRequestDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
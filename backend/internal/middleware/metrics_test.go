@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// requestCount returns how many requests were observed for a route and status
+func requestCount(t *testing.T, route, status string) uint64 {
+	t.Helper()
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Error gathering metrics: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "fmd_http_request_duration_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["route"] == route && labels["status"] == status {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	e := echo.New()
+	e.Use(Metrics(func(c echo.Context) bool { return c.Path() == "/metrics" }))
+	e.GET("/api/admin/users/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound, "User not found")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	for _, path := range []string{"/api/admin/users/a", "/api/admin/users/b", "/api/admin/users/missing", "/no/such/route", "/metrics"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		name   string
+		route  string
+		status string
+		want   uint64
+	}{
+		{"labeled by route template", "/api/admin/users/:id", "200", 2},
+		{"status from error handler", "/api/admin/users/:id", "404", 1},
+		{"unmatched paths share a label", unmatchedRoute, "404", 1},
+		{"skipped requests", "/metrics", "200", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestCount(t, tt.route, tt.status); got != tt.want {
+				t.Errorf("Expected %d requests for %s %s, got %d", tt.want, tt.route, tt.status, got)
+			}
+		})
+	}
+}
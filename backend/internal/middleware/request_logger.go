@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// validRequestID matches request IDs accepted from clients and proxies
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID creates a middleware that reuses a well-formed X-Request-ID header or
+// generates one, and returns it in the response
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(echo.HeaderXRequestID)
+			if !validRequestID.MatchString(id) {
+				id = newRequestID()
+			}
+
+			c.Request().Header.Set(echo.HeaderXRequestID, id)
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+			return next(c)
+		}
+	}
+}
+
+// newRequestID returns a random 128-bit request ID
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// RequestLogger creates a middleware that binds a logger carrying the request ID to
+// the request context and writes one access log record per request.
+// It must run after RequestID. Requests for which skip returns true are not logged.
+func RequestLogger(logger *slog.Logger, skip func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := logging.WithLogger(req.Context(), logger.With("request_id", req.Header.Get(echo.HeaderXRequestID)))
+			c.SetRequest(req.WithContext(ctx))
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response so the logged status is the one sent
+				c.Error(err)
+			}
+
+			if skip != nil && skip(c) {
+				return nil
+			}
+
+			status := c.Response().Status
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+
+			attrs := []any{
+				"method", req.Method,
+				"uri", req.RequestURI,
+				"route", c.Path(),
+				"status", status,
+				"latency_ms", time.Since(start).Milliseconds(),
+				"bytes_out", c.Response().Size,
+				"remote_ip", c.RealIP(),
+			}
+			if err != nil {
+				attrs = append(attrs, "error", err.Error())
+			}
+			if span := trace.SpanContextFromContext(req.Context()); span.IsValid() {
+				attrs = append(attrs, "trace_id", span.TraceID().String())
+			}
+
+			// Authentication middleware may have added the user to the request logger
+			logging.FromContext(c.Request().Context()).Log(req.Context(), level, "request", attrs...)
+			return nil
+		}
+	}
+}
+
+// addLogAttrs adds attributes to the logger bound to the request context
+func addLogAttrs(c echo.Context, args ...any) {
+	req := c.Request()
+	c.SetRequest(req.WithContext(logging.With(req.Context(), args...)))
+}
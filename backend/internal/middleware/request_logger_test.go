@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/auth"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	tokens := auth.NewTokenIssuer(auth.NewHMACKeySet("secret"), auth.TokenConfig{Issuer: "issuer", Audience: "audience", AccessTokenTTL: time.Hour})
+
+	e := echo.New()
+	e.Use(RequestID())
+	e.Use(RequestLogger(logging.New(&buf, "info"), func(c echo.Context) bool { return c.Path() == "/healthz" }))
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	api := e.Group("/api")
+	api.Use(JWTMiddleware(tokens))
+	api.GET("/users/me", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot, "short and stout")
+	})
+
+	user := &models.User{ID: primitive.NewObjectID(), Username: "alice", Role: models.GeneralRole}
+	token, err := tokens.AccessToken(user, false)
+	if err != nil {
+		t.Fatalf("Error signing token: %v", err)
+	}
+
+	t.Run("propagates request ID and user", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		req.Header.Set(echo.HeaderXRequestID, "abc-123")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(echo.HeaderXRequestID); got != "abc-123" {
+			t.Errorf("Expected request ID abc-123, got %q", got)
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("Expected one JSON record, got %q: %v", buf.String(), err)
+		}
+		if record["request_id"] != "abc-123" {
+			t.Errorf("Expected request_id abc-123, got %v", record["request_id"])
+		}
+		if record["user_id"] != user.ID.Hex() {
+			t.Errorf("Expected user_id %s, got %v", user.ID.Hex(), record["user_id"])
+		}
+		if record["status"] != float64(http.StatusTeapot) {
+			t.Errorf("Expected status %d, got %v", http.StatusTeapot, record["status"])
+		}
+		if record["route"] != "/api/users/me" {
+			t.Errorf("Expected route /api/users/me, got %v", record["route"])
+		}
+	})
+
+	t.Run("replaces malformed request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+		req.Header.Set(echo.HeaderXRequestID, "bad id\n")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		got := rec.Header().Get(echo.HeaderXRequestID)
+		if got == "bad id\n" || !validRequestID.MatchString(got) {
+			t.Errorf("Expected a generated request ID, got %q", got)
+		}
+	})
+
+	t.Run("skips probes", func(t *testing.T) {
+		buf.Reset()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if buf.Len() != 0 {
+			t.Errorf("Expected no log record, got %q", buf.String())
+		}
+		if rec.Header().Get(echo.HeaderXRequestID) == "" {
+			t.Error("Expected a request ID on probe responses")
+		}
+	})
+}
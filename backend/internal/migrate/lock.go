@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// lockCollection holds the single lock document
+	lockCollection = "schema_migrations_lock"
+	// lockID is the _id of the lock document
+	lockID = "migrations"
+	// lockLease is how long a lock stays valid without renewal, so a crashed replica cannot block migrations forever
+	lockLease = time.Minute
+	// lockPollInterval is how often a waiting replica retries
+	lockPollInterval = 2 * time.Second
+)
+
+// lock is a lease-based mutex stored in MongoDB
+type lock struct {
+	coll  *mongo.Collection
+	owner string
+}
+
+// newLock creates a lock with a unique owner for this process
+func newLock(db *mongo.Database) *lock {
+	host, _ := os.Hostname()
+	return &lock{
+		coll:  db.Collection(lockCollection),
+		owner: fmt.Sprintf("%s/%d/%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// acquire waits until the lock is free or ctx is done. The lease is renewed until release is called.
+func (l *lock) acquire(ctx context.Context) (release func(), err error) {
+	for {
+		ok, err := l.tryAcquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+
+		logging.FromContext(ctx).Info("waiting for another replica to finish migrating")
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for migration lock: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(lockLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if ok, err := l.tryAcquire(context.Background()); err != nil || !ok {
+					logging.FromContext(ctx).Error("failed to renew migration lock", "held", ok, "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := l.coll.DeleteOne(releaseCtx, bson.M{"_id": lockID, "owner": l.owner}); err != nil {
+			logging.FromContext(ctx).Error("failed to release migration lock", "error", err)
+		}
+	}, nil
+}
+
+// tryAcquire takes or renews the lock if it is free, expired or already ours
+func (l *lock) tryAcquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": lockID,
+		"$or": bson.A{
+			bson.M{"owner": l.owner},
+			bson.M{"expiresAt": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": l.owner, "expiresAt": now.Add(lockLease)}}
+
+	_, err := l.coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The lock document exists and belongs to someone else
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
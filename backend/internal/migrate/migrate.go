@@ -0,0 +1,226 @@
+// Package migrate applies versioned schema changes to MongoDB
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationsCollection records which migrations have been applied
+const migrationsCollection = "schema_migrations"
+
+// ErrIrreversible is returned when rolling back a migration without a Down step
+var ErrIrreversible = errors.New("migration cannot be rolled back")
+
+// Migration is a single versioned schema change
+type Migration struct {
+	// Version orders migrations; it must be unique and never change once released
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	// Down reverts Up; nil marks the migration as irreversible
+	Down func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is the stored state of an applied migration
+type Record struct {
+	Version   int       `bson:"_id" json:"version"`
+	Name      string    `bson:"name" json:"name"`
+	AppliedAt time.Time `bson:"appliedAt" json:"appliedAt"`
+}
+
+// Status describes a known migration and whether it has been applied
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Runner applies and rolls back migrations while holding the migration lock
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	lock       *lock
+}
+
+// NewRunner creates a Runner for the given migrations, which must have unique positive versions
+func NewRunner(client *mongo.Client, dbName string, migrations []Migration) (*Runner, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database(dbName)
+	return &Runner{db: db, migrations: sorted, lock: newLock(db)}, nil
+}
+
+// Up applies pending migrations in order up to and including target; 0 applies all of them
+func (r *Runner) Up(ctx context.Context, target int) error {
+	return r.withLock(ctx, func(ctx context.Context) error {
+		applied, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range pending(r.migrations, applied, target) {
+			logging.FromContext(ctx).Info("applying migration", "version", m.Version, "name", m.Name)
+			if err := m.Up(ctx, r.db); err != nil {
+				return fmt.Errorf("migration %d %s failed: %w", m.Version, m.Name, err)
+			}
+
+			record := Record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+			if _, err := r.db.Collection(migrationsCollection).InsertOne(ctx, record); err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (r *Runner) Down(ctx context.Context, steps int) error {
+	return r.withLock(ctx, func(ctx context.Context) error {
+		applied, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		rollback, err := latestApplied(r.migrations, applied, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range rollback {
+			if m.Down == nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, ErrIrreversible)
+			}
+
+			logging.FromContext(ctx).Info("rolling back migration", "version", m.Version, "name", m.Name)
+			if err := m.Down(ctx, r.db); err != nil {
+				return fmt.Errorf("rollback of migration %d %s failed: %w", m.Version, m.Name, err)
+			}
+
+			if _, err := r.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+				return fmt.Errorf("failed to remove record of migration %d: %w", m.Version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Status lists all known migrations and when they were applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withLock runs fn while holding the migration lock so only one replica migrates at a time
+func (r *Runner) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	release, err := r.lock.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(ctx)
+}
+
+// applied loads the records of applied migrations by version
+func (r *Runner) applied(ctx context.Context) (map[int]Record, error) {
+	cursor, err := r.db.Collection(migrationsCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// sortMigrations checks migrations and returns them ordered by version
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q must have a positive version", m.Name)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d %s has no Up step", m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+	}
+	return sorted, nil
+}
+
+// pending returns the migrations that are not applied yet, up to target if it is positive
+func pending(migrations []Migration, applied map[int]Record, target int) []Migration {
+	var result []Migration
+	for _, m := range migrations {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// latestApplied returns up to steps applied migrations, newest first.
+// It fails if an applied migration is unknown to this binary, since it could not be rolled back.
+func latestApplied(migrations []Migration, applied map[int]Record, steps int) ([]Migration, error) {
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	var result []Migration
+	for _, version := range versions {
+		if len(result) == steps {
+			break
+		}
+		m, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d is unknown to this version of the server", version)
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func noop(ctx context.Context, db *mongo.Database) error { return nil }
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 3, Name: "third", Up: noop},
+		{Version: 1, Name: "first", Up: noop, Down: noop},
+		{Version: 2, Name: "second", Up: noop, Down: noop},
+	}
+}
+
+func versions(migrations []Migration) []int {
+	var result []int
+	for _, m := range migrations {
+		result = append(result, m.Version)
+	}
+	return result
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSortMigrations(t *testing.T) {
+	sorted, err := sortMigrations(testMigrations())
+	if err != nil {
+		t.Fatalf("Error sorting migrations: %v", err)
+	}
+	if got := versions(sorted); !equalInts(got, []int{1, 2, 3}) {
+		t.Errorf("Expected migrations in version order, got %v", got)
+	}
+
+	invalid := map[string][]Migration{
+		"duplicate": {{Version: 1, Up: noop}, {Version: 1, Up: noop}},
+		"zero":      {{Version: 0, Up: noop}},
+		"no up":     {{Version: 1}},
+	}
+	for name, migrations := range invalid {
+		if _, err := sortMigrations(migrations); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestPending(t *testing.T) {
+	sorted, _ := sortMigrations(testMigrations())
+	applied := map[int]Record{1: {Version: 1}}
+
+	if got := versions(pending(sorted, applied, 0)); !equalInts(got, []int{2, 3}) {
+		t.Errorf("Expected 2 and 3 pending, got %v", got)
+	}
+	if got := versions(pending(sorted, applied, 2)); !equalInts(got, []int{2}) {
+		t.Errorf("Expected only 2 pending up to version 2, got %v", got)
+	}
+}
+
+func TestLatestApplied(t *testing.T) {
+	sorted, _ := sortMigrations(testMigrations())
+	applied := map[int]Record{1: {Version: 1}, 2: {Version: 2}, 3: {Version: 3}}
+
+	rollback, err := latestApplied(sorted, applied, 2)
+	if err != nil {
+		t.Fatalf("Error planning rollback: %v", err)
+	}
+	if got := versions(rollback); !equalInts(got, []int{3, 2}) {
+		t.Errorf("Expected newest first, got %v", got)
+	}
+
+	applied[4] = Record{Version: 4}
+	if _, err := latestApplied(sorted, applied, 1); err == nil {
+		t.Error("Expected an unknown applied migration to be rejected")
+	}
+}
+
+func TestAllMigrationsAreValid(t *testing.T) {
+	if _, err := sortMigrations(All); err != nil {
+		t.Fatalf("Invalid migration list: %v", err)
+	}
+}
+
+func TestUntieRanks(t *testing.T) {
+	ranks := []string{"a0", "a1", "a1", "a1", "a2", "a3", "a3"}
+	untied, err := untieRanks(ranks)
+	if err != nil {
+		t.Fatalf("Error untying ranks: %v", err)
+	}
+	if len(untied) != 3 {
+		t.Fatalf("Expected the three tied positions to change, got %v", untied)
+	}
+	for i, rank := range untied {
+		ranks[i] = rank
+	}
+	for i := 1; i < len(ranks); i++ {
+		if ranks[i] <= ranks[i-1] {
+			t.Errorf("Expected distinct ranks in order, got %v", ranks)
+			break
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All lists the schema changes of the application. Append new migrations with
+// the next version; never edit or renumber one that has been released.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "create user indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("users"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "username", Value: 1}},
+					Options: options.Index().SetName("username_unique").SetUnique(true),
+				},
+				mongo.IndexModel{
+					Keys: bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email_unique").SetUnique(true).
+						SetPartialFilterExpression(bson.M{"email": bson.M{"$type": "string", "$gt": ""}}),
+				},
+				mongo.IndexModel{
+					Keys: bson.D{{Key: "oidcSubject", Value: 1}},
+					Options: options.Index().SetName("oidcSubject_unique").SetUnique(true).
+						SetPartialFilterExpression(bson.M{"oidcSubject": bson.M{"$type": "string"}}),
+				},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("users"), "username_unique", "email_unique", "oidcSubject_unique")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create invitation and api key indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			err := createIndexes(ctx, db.Collection("invitations"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+					Options: options.Index().SetName("tokenHash_unique").SetUnique(true),
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("api_keys"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "keyHash", Value: 1}},
+					Options: options.Index().SetName("keyHash_unique").SetUnique(true),
+				},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("invitations"), "tokenHash_unique"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("api_keys"), "keyHash_unique")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "expire login attempt records",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"login_attempts_users", "login_attempts_ips"} {
+				err := createIndexes(ctx, db.Collection(name),
+					mongo.IndexModel{
+						Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+						Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+					},
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"login_attempts_users", "login_attempts_ips"} {
+				if err := dropIndexes(ctx, db.Collection(name), "expiresAt_ttl"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create notification indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("notifications"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName("userId_createdAt"),
+				},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("notifications"), "userId_createdAt")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create reminder indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			err := createIndexes(ctx, db.Collection("reminders"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "targetId", Value: 1}, {Key: "offset", Value: 1}, {Key: "dueAt", Value: 1}},
+					Options: options.Index().SetName("reminder_unique").SetUnique(true),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0),
+				},
+			)
+			if err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, db.Collection("events"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "startTime", Value: 1}},
+				Options: options.Index().SetName("startTime"),
+			}); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("tasks"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "dueDate", Value: 1}},
+				Options: options.Index().SetName("dueDate"),
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("reminders"), "reminder_unique", "expiresAt_ttl"); err != nil {
+				return err
+			}
+			if err := dropIndexes(ctx, db.Collection("events"), "startTime"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("tasks"), "dueDate")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create webhook indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createIndexes(ctx, db.Collection("webhook_subscriptions"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "events", Value: 1}, {Key: "active", Value: 1}},
+				Options: options.Index().SetName("events_active"),
+			}); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("webhook_deliveries"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+					Options: options.Index().SetName("status_nextAttemptAt"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "subscriptionId", Value: 1}, {Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName("subscriptionId_createdAt"),
+				},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("webhook_subscriptions"), "events_active"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("webhook_deliveries"), "status_nextAttemptAt", "subscriptionId_createdAt")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create task indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			err := createIndexes(ctx, db.Collection("tasks"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "assignedTo", Value: 1}, {Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName("assignedTo_createdAt"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "createdBy", Value: 1}, {Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName("createdBy_createdAt"),
+				},
+			)
+			if err != nil {
+				return err
+			}
+			for _, name := range []string{"task_comments", "task_attachments"} {
+				if err := createIndexes(ctx, db.Collection(name), mongo.IndexModel{
+					Keys:    bson.D{{Key: "taskId", Value: 1}, {Key: "createdAt", Value: 1}},
+					Options: options.Index().SetName("taskId_createdAt"),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("tasks"), "assignedTo_createdAt", "createdBy_createdAt"); err != nil {
+				return err
+			}
+			for _, name := range []string{"task_comments", "task_attachments"} {
+				if err := dropIndexes(ctx, db.Collection(name), "taskId_createdAt"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create group task indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("tasks"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "assigneeType", Value: 1}, {Key: "assignedSection", Value: 1}, {Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName("assigneeType_assignedSection_createdAt"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "assigneeType", Value: 1}, {Key: "assignedRole", Value: 1}, {Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName("assigneeType_assignedRole_createdAt"),
+				},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("tasks"), "assigneeType_assignedSection_createdAt", "assigneeType_assignedRole_createdAt")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create task template indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Each due date of a template creates at most one task, however many replicas generate them
+			err := createIndexes(ctx, db.Collection("tasks"), mongo.IndexModel{
+				Keys: bson.D{{Key: "templateId", Value: 1}, {Key: "dueDate", Value: 1}},
+				Options: options.Index().SetName("templateId_dueDate_unique").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"templateId": bson.M{"$type": "objectId"}}),
+			})
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("task_templates"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "active", Value: 1}, {Key: "nextRunAt", Value: 1}},
+				Options: options.Index().SetName("active_nextRunAt"),
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("tasks"), "templateId_dueDate_unique"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("task_templates"), "active_nextRunAt")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "backfill task priorities and board ranks",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			tasks := db.Collection("tasks")
+			_, err := tasks.UpdateMany(ctx,
+				bson.M{"priority": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"priority": models.TaskPriorityMedium, "labels": bson.A{}}})
+			if err != nil {
+				return err
+			}
+			for _, status := range []models.TaskStatus{models.TaskStatusTodo, models.TaskStatusInProgress, models.TaskStatusCompleted} {
+				if err := backfillTaskRanks(ctx, tasks, status); err != nil {
+					return err
+				}
+			}
+			return createIndexes(ctx, tasks,
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "status", Value: 1}, {Key: "rank", Value: 1}},
+					Options: options.Index().SetName("status_rank"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "labels", Value: 1}},
+					Options: options.Index().SetName("labels"),
+				},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("tasks"), "status_rank", "labels")
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create task dependency index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			tasks := db.Collection("tasks")
+			_, err := tasks.UpdateMany(ctx,
+				bson.M{"blockedBy": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"blockedBy": bson.A{}}})
+			if err != nil {
+				return err
+			}
+			// Finds the tasks waiting on a task, to list them and to unlink them when it is deleted
+			return createIndexes(ctx, tasks, mongo.IndexModel{
+				Keys:    bson.D{{Key: "blockedBy", Value: 1}},
+				Options: options.Index().SetName("blockedBy"),
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("tasks"), "blockedBy")
+		},
+	},
+	{
+		Version: 12,
+		Name:    "untie task board ranks",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			tasks := db.Collection("tasks")
+			for _, status := range []models.TaskStatus{models.TaskStatusTodo, models.TaskStatusInProgress, models.TaskStatusCompleted} {
+				if err := untieTaskRanks(ctx, tasks, status); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// The new ranks keep the order the tied ones were listed in, so there is nothing to undo
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return nil
+		},
+	},
+	{
+		Version: 13,
+		Name:    "ignore case in the email index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return rebuildEmailIndex(ctx, db.Collection("users"), emailCollation, nil)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return rebuildEmailIndex(ctx, db.Collection("users"), nil, emailCollation)
+		},
+	},
+}
+
+// emailCollation compares email addresses without regard to case, like UserRepository.FindByEmail
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// rebuildEmailIndex replaces the unique email index built with the previous collation by one built
+// with collation; nil means no collation. If the new index cannot be built, for example because two
+// accounts use the same address in different case, the previous index is restored and the accounts
+// have to be fixed by hand.
+func rebuildEmailIndex(ctx context.Context, users *mongo.Collection, collation, previous *options.Collation) error {
+	if err := dropIndexes(ctx, users, "email_unique"); err != nil {
+		return err
+	}
+	if err := createIndexes(ctx, users, emailIndex(collation)); err != nil {
+		if restoreErr := createIndexes(ctx, users, emailIndex(previous)); restoreErr != nil {
+			err = errors.Join(err, restoreErr)
+		}
+		return fmt.Errorf("rebuild email index: %w", err)
+	}
+	return nil
+}
+
+// emailIndex is the unique index on the email addresses that are set
+func emailIndex(collation *options.Collation) mongo.IndexModel {
+	opts := options.Index().SetName("email_unique").SetUnique(true).
+		SetPartialFilterExpression(bson.M{"email": bson.M{"$type": "string", "$gt": ""}})
+	if collation != nil {
+		opts.SetCollation(collation)
+	}
+	return mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}, Options: opts}
+}
+
+// backfillTaskRanks ranks the unranked tasks of a column after the ranked ones, oldest first
+func backfillTaskRanks(ctx context.Context, tasks *mongo.Collection, status models.TaskStatus) error {
+	var last struct {
+		Rank string `bson:"rank"`
+	}
+	err := tasks.FindOne(ctx,
+		bson.M{"status": status, "rank": bson.M{"$gt": ""}},
+		options.FindOne().SetSort(bson.D{{Key: "rank", Value: -1}}).SetProjection(bson.M{"rank": 1}),
+	).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	cursor, err := tasks.Find(ctx,
+		bson.M{"status": status, "$or": bson.A{bson.M{"rank": bson.M{"$exists": false}}, bson.M{"rank": ""}}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	rank := last.Rank
+	var writes []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var task struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&task); err != nil {
+			return err
+		}
+		if rank, err = models.RankBetween(rank, ""); err != nil {
+			return err
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": task.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"rank": rank}}))
+
+		if len(writes) == 500 {
+			if _, err := tasks.BulkWrite(ctx, writes); err != nil {
+				return err
+			}
+			writes = writes[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(writes) > 0 {
+		_, err = tasks.BulkWrite(ctx, writes)
+	}
+	return err
+}
+
+// untieTaskRanks gives the tasks of a column that share a rank ranks of their own, in the order the
+// listing showed them: by rank, then ID. A task could not be dropped between two tied tasks.
+func untieTaskRanks(ctx context.Context, tasks *mongo.Collection, status models.TaskStatus) error {
+	cursor, err := tasks.Find(ctx,
+		bson.M{"status": status},
+		options.Find().SetSort(bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}}).SetProjection(bson.M{"_id": 1, "rank": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var column []struct {
+		ID   primitive.ObjectID `bson:"_id"`
+		Rank string             `bson:"rank"`
+	}
+	if err := cursor.All(ctx, &column); err != nil {
+		return err
+	}
+
+	ranks := make([]string, len(column))
+	for i, task := range column {
+		ranks[i] = task.Rank
+	}
+	untied, err := untieRanks(ranks)
+	if err != nil {
+		return err
+	}
+
+	var writes []mongo.WriteModel
+	for i, rank := range untied {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": column[i].ID}).
+			SetUpdate(bson.M{"$set": bson.M{"rank": rank}}))
+
+		if len(writes) == 500 {
+			if _, err := tasks.BulkWrite(ctx, writes); err != nil {
+				return err
+			}
+			writes = writes[:0]
+		}
+	}
+	if len(writes) > 0 {
+		_, err = tasks.BulkWrite(ctx, writes)
+	}
+	return err
+}
+
+// untieRanks takes the sorted ranks of a column and returns new ranks for the positions that share a
+// rank with the one above them. Each gets a rank between the one above it and the next distinct rank.
+func untieRanks(ranks []string) (map[int]string, error) {
+	untied := map[int]string{}
+	for i := 0; i < len(ranks); {
+		j := i + 1
+		for j < len(ranks) && ranks[j] == ranks[i] {
+			j++
+		}
+		next := ""
+		if j < len(ranks) {
+			next = ranks[j]
+		}
+
+		rank := ranks[i]
+		for k := i + 1; k < j; k++ {
+			var err error
+			if rank, err = models.RankBetween(rank, next); err != nil {
+				return nil, err
+			}
+			untied[k] = rank
+		}
+		i = j
+	}
+	return untied, nil
+}
+
+// createIndexes creates indexes on a collection
+func createIndexes(ctx context.Context, coll *mongo.Collection, indexes ...mongo.IndexModel) error {
+	_, err := coll.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// dropIndexes drops indexes by name, ignoring indexes that do not exist
+func dropIndexes(ctx context.Context, coll *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		_, err := coll.Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound") {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
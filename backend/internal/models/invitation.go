@@ -0,0 +1,61 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvitationTTL is how long an invitation link stays valid
+const InvitationTTL = 14 * 24 * time.Hour
+
+// Invitation represents a one-time link that lets a new user set their own password
+type Invitation struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	TokenHash  string             `bson:"tokenHash" json:"-"` // Only the hash of the token is stored
+	ExpiresAt  time.Time          `bson:"expiresAt" json:"expiresAt"`
+	AcceptedAt *time.Time         `bson:"acceptedAt,omitempty" json:"acceptedAt,omitempty"`
+	CreatedBy  primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// AcceptInvitationInput represents data needed to accept an invitation
+type AcceptInvitationInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// NewInvitation creates an invitation for a user and returns it with the plaintext token.
+// The token is only ever returned here; the invitation stores its hash.
+func NewInvitation(userID, createdBy primitive.ObjectID) (*Invitation, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now()
+	return &Invitation{
+		UserID:    userID,
+		TokenHash: HashInvitationToken(token),
+		ExpiresAt: now.Add(InvitationTTL),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}, token, nil
+}
+
+// HashInvitationToken returns the stored form of an invitation token
+func HashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsUsable reports whether the invitation can still be accepted
+func (i *Invitation) IsUsable() bool {
+	return i.AcceptedAt == nil && time.Now().Before(i.ExpiresAt)
+}
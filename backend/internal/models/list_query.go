@@ -0,0 +1,76 @@
+package models
+
+import "strings"
+
+const (
+	// DefaultListLimit is the page size used when a list request does not specify one
+	DefaultListLimit = 20
+	// MaxListLimit is the largest page size a list request may ask for
+	MaxListLimit = 100
+)
+
+// SortOrder represents the direction of a sorted listing
+type SortOrder int
+
+const (
+	// SortAsc sorts in ascending order
+	SortAsc SortOrder = 1
+	// SortDesc sorts in descending order
+	SortDesc SortOrder = -1
+)
+
+// ListQuery represents the paging, sorting and search options shared by list endpoints
+type ListQuery struct {
+	Search string
+	Sort   string
+	Order  SortOrder
+	Limit  int
+	Cursor string
+}
+
+// ListResult represents a single page of a list response
+type ListResult[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+}
+
+// UserFilter represents the filters that can be applied to a user listing
+type UserFilter struct {
+	Role       Role
+	Section    string
+	Instrument string
+}
+
+// UserSortFields lists the sort keys accepted by the user listing
+var UserSortFields = []string{"name", "username", "createdAt", "role"}
+
+// Normalize fills in defaults and clamps the query to the allowed values.
+// It returns false if the requested sort key is not in allowedSorts.
+func (q *ListQuery) Normalize(allowedSorts []string, defaultSort string) bool {
+	q.Search = strings.TrimSpace(q.Search)
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit > MaxListLimit {
+		q.Limit = MaxListLimit
+	}
+
+	if q.Order != SortDesc {
+		q.Order = SortAsc
+	}
+
+	if q.Sort == "" {
+		q.Sort = defaultSort
+		return true
+	}
+
+	for _, s := range allowedSorts {
+		if s == q.Sort {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationType is what a notification is about
+type NotificationType string
+
+const (
+	// NotificationTaskAssigned is sent to the assignee of a task
+	NotificationTaskAssigned NotificationType = "task_assigned"
+	// NotificationEventChanged is sent to the attendees of an event that was created, moved or cancelled
+	NotificationEventChanged NotificationType = "event_changed"
+	// NotificationAbsenceDecided is sent to a member whose absence request was approved or rejected
+	NotificationAbsenceDecided NotificationType = "absence_decided"
+	// NotificationEventReminder is sent to the attendees of an upcoming event
+	NotificationEventReminder NotificationType = "event_reminder"
+	// NotificationTaskDue is sent to the assignee of an open task that is due soon
+	NotificationTaskDue NotificationType = "task_due"
+)
+
+// NotificationTypes lists the notification types members can choose channels for.
+// NotificationEventChanged and NotificationAbsenceDecided are left out until the APIs
+// that change events and decide absences send them.
+var NotificationTypes = []NotificationType{
+	NotificationTaskAssigned, NotificationEventReminder, NotificationTaskDue,
+}
+
+// NotificationChannel is a way of delivering notifications outside the app.
+// Every notification is also kept in the recipient's in-app inbox.
+type NotificationChannel string
+
+const (
+	NotificationEmail   NotificationChannel = "email"
+	NotificationWebhook NotificationChannel = "webhook"
+	// NotificationChat posts to a Slack-compatible incoming webhook
+	NotificationChat NotificationChannel = "chat"
+)
+
+// NotificationChannels lists every channel
+var NotificationChannels = []NotificationChannel{NotificationEmail, NotificationWebhook, NotificationChat}
+
+// Notification represents a message in a user's inbox
+type Notification struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID primitive.ObjectID `bson:"userId" json:"userId"`
+	Type   NotificationType   `bson:"type" json:"type"`
+	Title  string             `bson:"title" json:"title"`
+	Body   string             `bson:"body" json:"body"`
+	// Link is the frontend path of what the notification is about
+	Link      string     `bson:"link,omitempty" json:"link,omitempty"`
+	ReadAt    *time.Time `bson:"readAt,omitempty" json:"readAt,omitempty"`
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+}
+
+// NotificationPreferences represents where a user wants to be notified
+type NotificationPreferences struct {
+	UserID primitive.ObjectID `bson:"_id" json:"-"`
+	// Channels lists the channels each notification type is sent to besides the inbox
+	Channels map[NotificationType][]NotificationChannel `bson:"channels" json:"channels"`
+	// WebhookURL receives notifications as JSON on the webhook channel
+	WebhookURL string `bson:"webhookUrl,omitempty" json:"webhookUrl,omitempty"`
+	// ChatWebhookURL is a Slack-compatible incoming webhook for the chat channel
+	ChatWebhookURL string    `bson:"chatWebhookUrl,omitempty" json:"chatWebhookUrl,omitempty"`
+	UpdatedAt      time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// UpdateNotificationPreferencesInput represents data needed to replace a user's notification preferences
+type UpdateNotificationPreferencesInput struct {
+	Channels       map[NotificationType][]NotificationChannel `json:"channels" validate:"required"`
+	WebhookURL     string                                     `json:"webhookUrl" validate:"omitempty,url"`
+	ChatWebhookURL string                                     `json:"chatWebhookUrl" validate:"omitempty,url"`
+}
+
+// DefaultNotificationPreferences returns the preferences of a user who has not chosen any:
+// every notification is also sent by email
+func DefaultNotificationPreferences(userID primitive.ObjectID) *NotificationPreferences {
+	channels := map[NotificationType][]NotificationChannel{}
+	for _, t := range NotificationTypes {
+		channels[t] = []NotificationChannel{NotificationEmail}
+	}
+	return &NotificationPreferences{UserID: userID, Channels: channels}
+}
+
+// IsValidNotificationType reports whether t is a notification type members can choose channels for
+func IsValidNotificationType(t NotificationType) bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidNotificationChannel reports whether ch is a known channel
+func IsValidNotificationChannel(ch NotificationChannel) bool {
+	for _, known := range NotificationChannels {
+		if ch == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareCreate sets fields needed for creating a new notification
+func (n *Notification) PrepareCreate() {
+	n.CreatedAt = time.Now()
+	n.ReadAt = nil
+}
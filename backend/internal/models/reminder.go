@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReminderRetention is how long sent reminders are remembered after what they were about
+const ReminderRetention = 30 * 24 * time.Hour
+
+// ReminderKind is what a reminder is about
+type ReminderKind string
+
+const (
+	ReminderEvent ReminderKind = "event"
+	ReminderTask  ReminderKind = "task"
+)
+
+// Reminder records that a reminder was sent. Only one reminder can exist per kind, target,
+// offset and due time, which keeps reminders from being sent twice across restarts and
+// replicas while a rescheduled target gets reminded again for its new time.
+type Reminder struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Kind     ReminderKind       `bson:"kind" json:"kind"`
+	TargetID primitive.ObjectID `bson:"targetId" json:"targetId"`
+	// Offset is how long before DueAt the reminder was meant for
+	Offset time.Duration `bson:"offset" json:"offset"`
+	// DueAt is the event start or task due date the reminder was for
+	DueAt     time.Time `bson:"dueAt" json:"dueAt"`
+	SentAt    time.Time `bson:"sentAt" json:"sentAt"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"-"`
+}
+
+// NewReminder creates the record of a reminder sent now
+func NewReminder(kind ReminderKind, targetID primitive.ObjectID, offset time.Duration, dueAt time.Time) *Reminder {
+	return &Reminder{
+		Kind:      kind,
+		TargetID:  targetID,
+		Offset:    offset,
+		DueAt:     dueAt,
+		SentAt:    time.Now(),
+		ExpiresAt: dueAt.Add(ReminderRetention),
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+// RosterRowError represents a validation problem with one row of a roster import
+type RosterRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportedUser represents an account created by a roster import
+type ImportedUser struct {
+	Row           int    `json:"row"`
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	InvitationURL string `json:"invitationUrl"`
+}
+
+// RosterImportReport represents the outcome of a roster import or dry run
+type RosterImportReport struct {
+	DryRun  bool             `json:"dryRun"`
+	Total   int              `json:"total"`
+	Valid   int              `json:"valid"`
+	Errors  []RosterRowError `json:"errors"`
+	Created []ImportedUser   `json:"created,omitempty"`
+}
@@ -18,6 +18,37 @@ const (
 	TaskStatusCompleted TaskStatus = "completed"
 )
 
+// TaskPriority represents how urgent a task is
+type TaskPriority string
+
+const (
+	TaskPriorityLow    TaskPriority = "low"
+	TaskPriorityMedium TaskPriority = "medium"
+	TaskPriorityHigh   TaskPriority = "high"
+	TaskPriorityUrgent TaskPriority = "urgent"
+)
+
+const (
+	// MaxTaskLabels is the most labels a task can have
+	MaxTaskLabels = 20
+	// MaxTaskLabelLength is the longest label accepted, in characters
+	MaxTaskLabelLength = 50
+)
+
+// TaskAssigneeType says who a task is assigned to
+type TaskAssigneeType string
+
+const (
+	// TaskAssigneeUser tasks are assigned to the member in AssignedTo
+	TaskAssigneeUser TaskAssigneeType = "user"
+	// TaskAssigneeSection tasks are assigned to every member of AssignedSection
+	TaskAssigneeSection TaskAssigneeType = "section"
+	// TaskAssigneeRole tasks are assigned to every member with AssignedRole
+	TaskAssigneeRole TaskAssigneeType = "role"
+	// TaskAssigneeEveryone tasks are assigned to the whole band
+	TaskAssigneeEveryone TaskAssigneeType = "everyone"
+)
+
 // Task represents a task in the system
 type Task struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
@@ -30,6 +61,82 @@ type Task struct {
 	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
 	CompletedAt *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	Checklist   []ChecklistItem    `bson:"checklist" json:"checklist"`
+	// AssigneeType is empty on tasks created before groups could be assigned, which are user tasks
+	AssigneeType    TaskAssigneeType `bson:"assigneeType,omitempty" json:"assigneeType"`
+	AssignedSection string           `bson:"assignedSection,omitempty" json:"assignedSection,omitempty"`
+	AssignedRole    Role             `bson:"assignedRole,omitempty" json:"assignedRole,omitempty"`
+	// Completions records which members finished a task assigned to a group
+	Completions []TaskCompletion `bson:"completions" json:"completions"`
+	// TemplateID is set on tasks created from a recurring task template
+	TemplateID *primitive.ObjectID `bson:"templateId,omitempty" json:"templateId,omitempty"`
+	Priority   TaskPriority        `bson:"priority" json:"priority"`
+	Labels     []string            `bson:"labels" json:"labels"`
+	// Rank orders the task within the Kanban column of its status; see RankBetween
+	Rank string `bson:"rank" json:"rank"`
+	// BlockedBy lists the tasks that must be completed before this one can start
+	BlockedBy []primitive.ObjectID `bson:"blockedBy" json:"blockedBy"`
+	// Blocked is not stored: it is set when the task is read, if a task in BlockedBy is still open
+	Blocked bool `bson:"-" json:"blocked"`
+}
+
+// MoveTaskInput represents where a task is dropped on the Kanban board.
+// The task is placed between its new neighbours, which must be in the target column;
+// leaving one out places it at the top or bottom of the column.
+type MoveTaskInput struct {
+	Status TaskStatus `json:"status" validate:"required,oneof=todo in_progress completed"`
+	// AfterID is the task that ends up directly above the moved task
+	AfterID string `json:"afterId"`
+	// BeforeID is the task that ends up directly below the moved task
+	BeforeID string `json:"beforeId"`
+	// OverrideBlockers lets an admin start or complete a task whose blockers are still open
+	OverrideBlockers bool `json:"overrideBlockers"`
+}
+
+// CompleteTaskInput represents a member marking their part of a task done
+type CompleteTaskInput struct {
+	// OverrideBlockers lets an admin complete a task whose blockers are still open
+	OverrideBlockers bool `json:"overrideBlockers"`
+}
+
+// TaskCompletion records that a member finished their part of a group task
+type TaskCompletion struct {
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	CompletedAt time.Time          `bson:"completedAt" json:"completedAt"`
+}
+
+// TaskMemberProgress is one member's state in a task's progress
+type TaskMemberProgress struct {
+	UserID      primitive.ObjectID `json:"userId"`
+	Username    string             `json:"username"`
+	FullName    string             `json:"fullName"`
+	Section     string             `json:"section,omitempty"`
+	CompletedAt *time.Time         `json:"completedAt,omitempty"`
+}
+
+// TaskProgress shows which of a task's assignees have finished it
+type TaskProgress struct {
+	TaskID    primitive.ObjectID   `json:"taskId"`
+	Total     int                  `json:"total"`
+	Completed int                  `json:"completed"`
+	Pending   []TaskMemberProgress `json:"pending"`
+	Done      []TaskMemberProgress `json:"done"`
+}
+
+// ChecklistItem represents a sub-step of a task that is completed on its own
+type ChecklistItem struct {
+	ID        primitive.ObjectID  `bson:"_id" json:"id"`
+	Text      string              `bson:"text" json:"text"`
+	Done      bool                `bson:"done" json:"done"`
+	DoneBy    *primitive.ObjectID `bson:"doneBy,omitempty" json:"doneBy,omitempty"`
+	DoneAt    *time.Time          `bson:"doneAt,omitempty" json:"doneAt,omitempty"`
+	CreatedAt time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+// ChecklistItemInput represents data needed to add or update a checklist item
+type ChecklistItemInput struct {
+	Text string `json:"text" validate:"required"`
+	Done bool   `json:"done"`
 }
 
 // CreateTaskInput represents data needed to create a new task
@@ -37,7 +144,14 @@ type CreateTaskInput struct {
 	Title       string    `json:"title" validate:"required"`
 	Description string    `json:"description"`
 	DueDate     time.Time `json:"dueDate"`
-	AssignedTo  string    `json:"assignedTo" validate:"required"`
+	// AssigneeType defaults to user, which requires AssignedTo
+	AssigneeType    TaskAssigneeType `json:"assigneeType" validate:"omitempty,oneof=user section role everyone"`
+	AssignedTo      string           `json:"assignedTo"`
+	AssignedSection string           `json:"assignedSection"`
+	AssignedRole    Role             `json:"assignedRole" validate:"omitempty,oneof=admin general"`
+	// Priority defaults to medium
+	Priority TaskPriority `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	Labels   []string     `json:"labels"`
 }
 
 // UpdateTaskInput represents data needed to update an existing task
@@ -46,7 +160,47 @@ type UpdateTaskInput struct {
 	Description string     `json:"description"`
 	Status      TaskStatus `json:"status" validate:"omitempty,oneof=todo in_progress completed"`
 	DueDate     *time.Time `json:"dueDate"`
-	AssignedTo  string     `json:"assignedTo"`
+	// Setting AssigneeType reassigns the task with the assignee fields that type uses
+	AssigneeType    TaskAssigneeType `json:"assigneeType" validate:"omitempty,oneof=user section role everyone"`
+	AssignedTo      string           `json:"assignedTo"`
+	AssignedSection string           `json:"assignedSection"`
+	AssignedRole    Role             `json:"assignedRole" validate:"omitempty,oneof=admin general"`
+	Priority        TaskPriority     `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	// Labels replaces the task's labels when set; an empty list removes them all
+	Labels *[]string `json:"labels"`
+	// OverrideBlockers lets an admin start or complete a task whose blockers are still open
+	OverrideBlockers bool `json:"overrideBlockers"`
+}
+
+// TaskFilter represents the filters that can be applied to a task listing
+type TaskFilter struct {
+	Status     TaskStatus
+	AssignedTo primitive.ObjectID
+	TemplateID primitive.ObjectID
+	Priority   TaskPriority
+	// Labels limits the listing to tasks that have every one of the labels
+	Labels []string
+	// VisibleTo limits the listing to the tasks a member is assigned, alone or with a group,
+	// or created; it is nil for admins
+	VisibleTo *User
+}
+
+// TaskSortFields lists the sort keys accepted by the task listing
+var TaskSortFields = []string{"createdAt", "updatedAt", "title", "rank"}
+
+// IsValidTaskAssigneeType reports whether t is a known assignee type
+func IsValidTaskAssigneeType(t TaskAssigneeType) bool {
+	return t == TaskAssigneeUser || t == TaskAssigneeSection || t == TaskAssigneeRole || t == TaskAssigneeEveryone
+}
+
+// IsValidTaskPriority reports whether p is a known priority
+func IsValidTaskPriority(p TaskPriority) bool {
+	return p == TaskPriorityLow || p == TaskPriorityMedium || p == TaskPriorityHigh || p == TaskPriorityUrgent
+}
+
+// IsValidTaskStatus reports whether s is a known task status
+func IsValidTaskStatus(s TaskStatus) bool {
+	return s == TaskStatusTodo || s == TaskStatusInProgress || s == TaskStatusCompleted
 }
 
 // PrepareCreate sets fields needed for creating a new task
@@ -56,6 +210,117 @@ func (t *Task) PrepareCreate(userID primitive.ObjectID) {
 	t.UpdatedAt = now
 	t.Status = TaskStatusTodo
 	t.CreatedBy = userID
+	t.Checklist = []ChecklistItem{}
+	t.Completions = []TaskCompletion{}
+	if t.Priority == "" {
+		t.Priority = TaskPriorityMedium
+	}
+	if t.Labels == nil {
+		t.Labels = []string{}
+	}
+	if t.BlockedBy == nil {
+		t.BlockedBy = []primitive.ObjectID{}
+	}
+}
+
+// IsGroupTask reports whether the task is assigned to a section, a role or everyone
+func (t *Task) IsGroupTask() bool {
+	return t.AssigneeType != "" && t.AssigneeType != TaskAssigneeUser
+}
+
+// IsAssignee reports whether the task is assigned to user, alone or as part of a group
+func (t *Task) IsAssignee(user *User) bool {
+	switch t.AssigneeType {
+	case TaskAssigneeSection:
+		return user.Section != "" && user.Section == t.AssignedSection
+	case TaskAssigneeRole:
+		return user.Role == t.AssignedRole
+	case TaskAssigneeEveryone:
+		return true
+	default:
+		return !user.ID.IsZero() && user.ID == t.AssignedTo
+	}
+}
+
+// MemberFilter returns the user listing filter that finds the members of a group task
+func (t *Task) MemberFilter() UserFilter {
+	switch t.AssigneeType {
+	case TaskAssigneeSection:
+		return UserFilter{Section: t.AssignedSection}
+	case TaskAssigneeRole:
+		return UserFilter{Role: t.AssignedRole}
+	default:
+		return UserFilter{}
+	}
+}
+
+// VisibleTo reports whether a user can see the task, its comments, checklist and attachments.
+// Admins see every task; other members see the tasks they created and the tasks assigned
+// to them, alone or through their section, their role or the whole band.
+func (t *Task) VisibleTo(user *User) bool {
+	return user.Role == AdminRole || (!user.ID.IsZero() && t.CreatedBy == user.ID) || t.IsAssignee(user)
+}
+
+// CompletedBy returns when a member finished their part of the task, or nil.
+// A user task counts as finished by its assignee once it is completed.
+func (t *Task) CompletedBy(userID primitive.ObjectID) *time.Time {
+	if !t.IsGroupTask() {
+		if userID == t.AssignedTo && t.Status == TaskStatusCompleted {
+			return t.CompletedAt
+		}
+		return nil
+	}
+	for _, completion := range t.Completions {
+		if completion.UserID == userID {
+			completedAt := completion.CompletedAt
+			return &completedAt
+		}
+	}
+	return nil
+}
+
+// Progress sorts a task's assignees into those who have and have not finished it
+func (t *Task) Progress(assignees []*User) TaskProgress {
+	progress := TaskProgress{TaskID: t.ID, Total: len(assignees), Pending: []TaskMemberProgress{}, Done: []TaskMemberProgress{}}
+	for _, user := range assignees {
+		member := TaskMemberProgress{UserID: user.ID, Username: user.Username, FullName: user.FullName, Section: user.Section}
+		if member.CompletedAt = t.CompletedBy(user.ID); member.CompletedAt != nil {
+			progress.Done = append(progress.Done, member)
+		} else {
+			progress.Pending = append(progress.Pending, member)
+		}
+	}
+	progress.Completed = len(progress.Done)
+	return progress
+}
+
+// ManagedBy reports whether a user can reassign or delete the task: admins and its creator
+func (t *Task) ManagedBy(userID primitive.ObjectID, role Role) bool {
+	return role == AdminRole || (!userID.IsZero() && t.CreatedBy == userID)
+}
+
+// MaxChecklistItemLength is the longest checklist item text accepted, in characters
+const MaxChecklistItemLength = 500
+
+// NewChecklistItem creates an open checklist item
+func NewChecklistItem(text string) ChecklistItem {
+	return ChecklistItem{ID: primitive.NewObjectID(), Text: text, CreatedAt: time.Now()}
+}
+
+// SetDone marks the item done by userID, or open again
+func (i *ChecklistItem) SetDone(done bool, userID primitive.ObjectID) {
+	if done == i.Done {
+		return
+	}
+	i.Done = done
+	if done {
+		now := time.Now()
+		i.DoneBy = &userID
+		i.DoneAt = &now
+	} else {
+		i.DoneBy = nil
+		i.DoneAt = nil
+	}
 }
 
 // PrepareUpdate sets fields needed for updating a task
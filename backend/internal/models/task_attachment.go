@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskAttachment represents a file attached to a task. The file itself is kept in storage under StorageKey.
+type TaskAttachment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	TaskID      primitive.ObjectID `bson:"taskId" json:"taskId"`
+	FileName    string             `bson:"fileName" json:"fileName"`
+	ContentType string             `bson:"contentType" json:"contentType"`
+	Size        int64              `bson:"size" json:"size"`
+	StorageKey  string             `bson:"storageKey" json:"-"`
+	UploadedBy  primitive.ObjectID `bson:"uploadedBy" json:"uploadedBy"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// NewTaskAttachment creates an attachment with a new ID and the storage key derived from it
+func NewTaskAttachment(taskID primitive.ObjectID, fileName, contentType string, uploadedBy primitive.ObjectID) *TaskAttachment {
+	id := primitive.NewObjectID()
+	return &TaskAttachment{
+		ID:          id,
+		TaskID:      taskID,
+		FileName:    fileName,
+		ContentType: contentType,
+		StorageKey:  "tasks/" + taskID.Hex() + "/" + id.Hex(),
+		UploadedBy:  uploadedBy,
+		CreatedAt:   time.Now(),
+	}
+}
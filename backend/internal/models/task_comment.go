@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskComment represents a comment on a task. Replies point at the comment they answer.
+type TaskComment struct {
+	ID       primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	TaskID   primitive.ObjectID  `bson:"taskId" json:"taskId"`
+	ParentID *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	AuthorID primitive.ObjectID  `bson:"authorId" json:"authorId"`
+	Body     string              `bson:"body" json:"body"`
+	// Deleted comments keep their place in the thread so replies to them stay readable
+	Deleted   bool       `bson:"deleted" json:"deleted"`
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time  `bson:"updatedAt" json:"updatedAt"`
+	EditedAt  *time.Time `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+}
+
+// CreateTaskCommentInput represents data needed to comment on a task or reply to a comment
+type CreateTaskCommentInput struct {
+	Body     string `json:"body" validate:"required"`
+	ParentID string `json:"parentId"`
+}
+
+// UpdateTaskCommentInput represents data needed to edit a comment
+type UpdateTaskCommentInput struct {
+	Body string `json:"body" validate:"required"`
+}
+
+// TaskCommentThread is a comment with its replies, oldest first
+type TaskCommentThread struct {
+	TaskComment
+	Replies []*TaskCommentThread `json:"replies"`
+}
+
+// MaxTaskCommentLength is the longest comment body accepted, in characters
+const MaxTaskCommentLength = 5000
+
+// PrepareCreate sets fields needed for creating a new comment
+func (c *TaskComment) PrepareCreate() {
+	now := time.Now()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+}
+
+// BuildTaskCommentThreads nests comments under the comments they reply to.
+// Comments must be ordered oldest first; replies to missing comments become top-level threads.
+func BuildTaskCommentThreads(comments []*TaskComment) []*TaskCommentThread {
+	threads := []*TaskCommentThread{}
+	byID := make(map[primitive.ObjectID]*TaskCommentThread, len(comments))
+
+	for _, comment := range comments {
+		thread := &TaskCommentThread{TaskComment: *comment, Replies: []*TaskCommentThread{}}
+		byID[comment.ID] = thread
+
+		if comment.ParentID != nil {
+			if parent, ok := byID[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, thread)
+				continue
+			}
+		}
+		threads = append(threads, thread)
+	}
+	return threads
+}
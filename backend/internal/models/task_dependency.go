@@ -0,0 +1,102 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxTaskBlockers is the largest number of tasks one task can wait on
+const MaxTaskBlockers = 20
+
+// TaskDependencyInput names a task that must be completed before another can start
+type TaskDependencyInput struct {
+	TaskID string `json:"taskId" validate:"required"`
+}
+
+// TaskDependencies lists the tasks a task waits on and the tasks waiting on it
+type TaskDependencies struct {
+	BlockedBy []*Task `json:"blockedBy"`
+	Blocking  []*Task `json:"blocking"`
+}
+
+// IsBlockedBy reports whether the task waits on the task with the given ID
+func (t *Task) IsBlockedBy(id primitive.ObjectID) bool {
+	for _, blocker := range t.BlockedBy {
+		if blocker == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AdvancesBlocked reports whether changing a blocked task to status would start or complete it
+func (t *Task) AdvancesBlocked(status TaskStatus) bool {
+	if !t.Blocked || status == t.Status {
+		return false
+	}
+	return status == TaskStatusCompleted || (status == TaskStatusInProgress && t.Status != TaskStatusCompleted)
+}
+
+// BlockerIDs returns the distinct tasks the given tasks wait on
+func BlockerIDs(tasks []*Task) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, task := range tasks {
+		for _, id := range task.BlockedBy {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// MarkBlocked sets Blocked on tasks that wait on a blocker that is not completed.
+// Blockers missing from blockers were deleted and no longer block anything.
+func MarkBlocked(tasks []*Task, blockers []*Task) {
+	open := map[primitive.ObjectID]bool{}
+	for _, blocker := range blockers {
+		open[blocker.ID] = blocker.Status != TaskStatusCompleted
+	}
+	for _, task := range tasks {
+		task.Blocked = false
+		for _, id := range task.BlockedBy {
+			if open[id] {
+				task.Blocked = true
+				break
+			}
+		}
+	}
+}
+
+// DependencyCreatesCycle reports whether making taskID wait on blockerID would make a task
+// wait on itself, that is whether taskID is already among the tasks blockerID waits on,
+// directly or through others. find loads tasks by ID, one level of the graph at a time.
+func DependencyCreatesCycle(taskID, blockerID primitive.ObjectID, find func(ids []primitive.ObjectID) ([]*Task, error)) (bool, error) {
+	if taskID == blockerID {
+		return true, nil
+	}
+
+	visited := map[primitive.ObjectID]bool{blockerID: true}
+	level := []primitive.ObjectID{blockerID}
+	for len(level) > 0 {
+		tasks, err := find(level)
+		if err != nil {
+			return false, err
+		}
+
+		level = nil
+		for _, task := range tasks {
+			for _, id := range task.BlockedBy {
+				if id == taskID {
+					return true, nil
+				}
+				if !visited[id] {
+					visited[id] = true
+					level = append(level, id)
+				}
+			}
+		}
+	}
+	return false, nil
+}
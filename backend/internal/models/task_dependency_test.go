@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDependencyCreatesCycle(t *testing.T) {
+	// design <- props <- rehearsal, and costumes on its own
+	design := &Task{ID: primitive.NewObjectID()}
+	props := &Task{ID: primitive.NewObjectID(), BlockedBy: []primitive.ObjectID{design.ID}}
+	rehearsal := &Task{ID: primitive.NewObjectID(), BlockedBy: []primitive.ObjectID{props.ID}}
+	costumes := &Task{ID: primitive.NewObjectID()}
+
+	byID := map[primitive.ObjectID]*Task{}
+	for _, task := range []*Task{design, props, rehearsal, costumes} {
+		byID[task.ID] = task
+	}
+	lookups := 0
+	find := func(ids []primitive.ObjectID) ([]*Task, error) {
+		lookups++
+		var tasks []*Task
+		for _, id := range ids {
+			if task, ok := byID[id]; ok {
+				tasks = append(tasks, task)
+			}
+		}
+		return tasks, nil
+	}
+
+	tests := []struct {
+		name          string
+		task, blocker *Task
+		want          bool
+	}{
+		{"itself", design, design, true},
+		{"direct", design, props, true},
+		{"through another task", design, rehearsal, true},
+		{"same direction", rehearsal, design, false},
+		{"unrelated", costumes, rehearsal, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DependencyCreatesCycle(tt.task.ID, tt.blocker.ID, find)
+			if err != nil {
+				t.Fatalf("Error checking the dependency: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected cycle %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// A diamond is walked once per level, not once per path
+	design.BlockedBy = nil
+	shared := &Task{ID: primitive.NewObjectID()}
+	left := &Task{ID: primitive.NewObjectID(), BlockedBy: []primitive.ObjectID{shared.ID}}
+	right := &Task{ID: primitive.NewObjectID(), BlockedBy: []primitive.ObjectID{shared.ID}}
+	top := &Task{ID: primitive.NewObjectID(), BlockedBy: []primitive.ObjectID{left.ID, right.ID}}
+	for _, task := range []*Task{shared, left, right, top} {
+		byID[task.ID] = task
+	}
+	lookups = 0
+	if got, _ := DependencyCreatesCycle(costumes.ID, top.ID, find); got || lookups != 3 {
+		t.Errorf("Expected no cycle in three lookups, got %v in %d", got, lookups)
+	}
+}
+
+func TestMarkBlocked(t *testing.T) {
+	open := &Task{ID: primitive.NewObjectID(), Status: TaskStatusInProgress}
+	done := &Task{ID: primitive.NewObjectID(), Status: TaskStatusCompleted}
+	deleted := primitive.NewObjectID()
+
+	waiting := &Task{Status: TaskStatusTodo, BlockedBy: []primitive.ObjectID{done.ID, open.ID}}
+	ready := &Task{Status: TaskStatusTodo, BlockedBy: []primitive.ObjectID{done.ID, deleted}}
+	free := &Task{Status: TaskStatusTodo}
+
+	tasks := []*Task{waiting, ready, free}
+	if ids := BlockerIDs(tasks); len(ids) != 3 {
+		t.Fatalf("Expected three distinct blockers, got %v", ids)
+	}
+	MarkBlocked(tasks, []*Task{open, done})
+
+	if !waiting.Blocked || ready.Blocked || free.Blocked {
+		t.Errorf("Expected only the task waiting on an open task to be blocked, got %v %v %v", waiting.Blocked, ready.Blocked, free.Blocked)
+	}
+	if !waiting.AdvancesBlocked(TaskStatusInProgress) || !waiting.AdvancesBlocked(TaskStatusCompleted) || ready.AdvancesBlocked(TaskStatusInProgress) {
+		t.Error("Expected only starting or completing the blocked task to be refused")
+	}
+	waiting.Status = TaskStatusInProgress
+	if waiting.AdvancesBlocked(TaskStatusInProgress) || !waiting.AdvancesBlocked(TaskStatusCompleted) || waiting.AdvancesBlocked(TaskStatusTodo) {
+		t.Error("Expected a task already in progress to be refused only completion")
+	}
+	waiting.Status = TaskStatusCompleted
+	if waiting.AdvancesBlocked(TaskStatusInProgress) || waiting.AdvancesBlocked(TaskStatusTodo) {
+		t.Error("Expected a completed task to be reopened freely")
+	}
+}
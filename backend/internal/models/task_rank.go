@@ -0,0 +1,276 @@
+package models
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+)
+
+// Task ranks order the tasks of a Kanban column. They are strings that sort in
+// byte order, and a rank can always be made between any two others, so moving a
+// task only changes that task.
+//
+// A rank is an integer part followed by an optional fraction. The integer part's
+// first character encodes its length, so ranks stay short when tasks are added at
+// either end of a column: a0, a1 ... az, b00 ... Inserting between two neighbours
+// extends the fraction instead. This is the fractional indexing scheme described by
+// David Greenspan, in base 62.
+
+// rankDigits are the digits of ranks in ascending byte order
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// rankJitterDigits is how many random digits UniqueRankBetween appends to a rank
+const rankJitterDigits = 4
+
+// smallestRankInteger cannot be decremented, so it is never used as a rank
+const smallestRankInteger = "A00000000000000000000000000"
+
+// ErrInvalidRank is returned for ranks that were not made by RankBetween, or that are out of order
+var ErrInvalidRank = errors.New("invalid rank")
+
+// RankBetween returns a rank that sorts after before and ahead of after.
+// An empty before means the start of the column and an empty after its end.
+func RankBetween(before, after string) (string, error) {
+	if before != "" {
+		if err := validateRank(before); err != nil {
+			return "", err
+		}
+	}
+	if after != "" {
+		if err := validateRank(after); err != nil {
+			return "", err
+		}
+	}
+	if before != "" && after != "" && before >= after {
+		return "", ErrInvalidRank
+	}
+
+	switch {
+	case before == "" && after == "":
+		return "a0", nil
+	case before == "":
+		integer := rankInteger(after)
+		fraction := after[len(integer):]
+		if integer == smallestRankInteger {
+			return integer + rankMidpoint("", fraction), nil
+		}
+		if integer < after {
+			return integer, nil
+		}
+		previous, ok := decrementRankInteger(integer)
+		if !ok {
+			return "", ErrInvalidRank
+		}
+		return previous, nil
+	case after == "":
+		integer := rankInteger(before)
+		next, ok := incrementRankInteger(integer)
+		if !ok {
+			return integer + rankMidpoint(before[len(integer):], ""), nil
+		}
+		return next, nil
+	}
+
+	integerBefore, integerAfter := rankInteger(before), rankInteger(after)
+	if integerBefore == integerAfter {
+		return integerBefore + rankMidpoint(before[len(integerBefore):], after[len(integerAfter):]), nil
+	}
+	next, ok := incrementRankInteger(integerBefore)
+	if !ok {
+		return "", ErrInvalidRank
+	}
+	if next < after {
+		return next, nil
+	}
+	return integerBefore + rankMidpoint(before[len(integerBefore):], ""), nil
+}
+
+// UniqueRankBetween is RankBetween with random digits appended, so that tasks ranked between the
+// same neighbours at the same moment do not share a rank. Ranks stay short: the digits only make the
+// fraction longer, and the next rank at either end of a column drops them.
+func UniqueRankBetween(before, after string) (string, error) {
+	rank, err := RankBetween(before, after)
+	if err != nil {
+		return "", err
+	}
+
+	// Digits appended to rank keep it after before, and ahead of after unless rank is a prefix of after
+	limit := ""
+	if after != "" && strings.HasPrefix(after, rank) {
+		limit = after[len(rank):]
+	}
+	return rank + randomFraction(limit), nil
+}
+
+// randomFraction returns random digits that sort ahead of the fraction limit, or anywhere if limit is
+// empty. It follows limit's leading zeros, since nothing sorts ahead of them, and never ends in 0.
+func randomFraction(limit string) string {
+	digits := make([]byte, 0, rankJitterDigits+1)
+	below := limit == ""
+	for !below || len(digits) < rankJitterDigits {
+		n := len(rankDigits)
+		if !below {
+			n = strings.IndexByte(rankDigits, limit[len(digits)])
+			if n == 0 {
+				digits = append(digits, rankDigits[0])
+				continue
+			}
+			below = true
+		}
+		digits = append(digits, rankDigits[rand.Intn(n)])
+	}
+	if digits[len(digits)-1] == rankDigits[0] {
+		digits = append(digits, rankDigits[1+rand.Intn(len(rankDigits)-1)])
+	}
+	return string(digits)
+}
+
+// rankMidpoint returns a fraction between a and b, where an empty b is the end of the range.
+// Fractions never end in 0, so there is always room ahead of one.
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		// Keep the prefix the fractions share, padding a with zeros
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + rankMidpoint(suffix(a, n), b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(rankDigits, a[0])
+	}
+	digitB := len(rankDigits)
+	if b != "" {
+		digitB = strings.IndexByte(rankDigits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(rankDigits[(digitA+digitB+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(rankDigits[digitA]) + rankMidpoint(suffix(a, 1), "")
+}
+
+// rankDigitAt returns the digit of s at i, or 0 past its end
+func rankDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+// suffix returns s without its first n bytes, or an empty string if it is shorter
+func suffix(s string, n int) string {
+	if n >= len(s) {
+		return ""
+	}
+	return s[n:]
+}
+
+// rankIntegerLength returns the length of an integer part from its first character
+func rankIntegerLength(head byte) (int, bool) {
+	switch {
+	case head >= 'a' && head <= 'z':
+		return int(head-'a') + 2, true
+	case head >= 'A' && head <= 'Z':
+		return int('Z'-head) + 2, true
+	default:
+		return 0, false
+	}
+}
+
+// rankInteger returns the integer part of a valid rank
+func rankInteger(rank string) string {
+	length, _ := rankIntegerLength(rank[0])
+	return rank[:length]
+}
+
+// validateRank checks that a rank has a whole integer part made of digits and a fraction that does not end in 0
+func validateRank(rank string) error {
+	if rank == "" || rank == smallestRankInteger {
+		return ErrInvalidRank
+	}
+	length, ok := rankIntegerLength(rank[0])
+	if !ok || len(rank) < length {
+		return ErrInvalidRank
+	}
+	for i := 1; i < len(rank); i++ {
+		if strings.IndexByte(rankDigits, rank[i]) < 0 {
+			return ErrInvalidRank
+		}
+	}
+	if len(rank) > length && rank[len(rank)-1] == rankDigits[0] {
+		return ErrInvalidRank
+	}
+	return nil
+}
+
+// incrementRankInteger returns the integer after x, or false if x is the largest
+func incrementRankInteger(x string) (string, bool) {
+	head, digits := x[0], []byte(x[1:])
+	carry := true
+	for i := len(digits) - 1; carry && i >= 0; i-- {
+		d := strings.IndexByte(rankDigits, digits[i]) + 1
+		if d == len(rankDigits) {
+			digits[i] = rankDigits[0]
+		} else {
+			digits[i] = rankDigits[d]
+			carry = false
+		}
+	}
+	if !carry {
+		return string(head) + string(digits), true
+	}
+
+	switch head {
+	case 'Z':
+		return "a" + string(rankDigits[0]), true
+	case 'z':
+		return "", false
+	}
+	head++
+	if head > 'a' {
+		digits = append(digits, rankDigits[0])
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
+
+// decrementRankInteger returns the integer before x, or false if x is the smallest
+func decrementRankInteger(x string) (string, bool) {
+	head, digits := x[0], []byte(x[1:])
+	borrow := true
+	for i := len(digits) - 1; borrow && i >= 0; i-- {
+		d := strings.IndexByte(rankDigits, digits[i]) - 1
+		if d == -1 {
+			digits[i] = rankDigits[len(rankDigits)-1]
+		} else {
+			digits[i] = rankDigits[d]
+			borrow = false
+		}
+	}
+	if !borrow {
+		return string(head) + string(digits), true
+	}
+
+	switch head {
+	case 'a':
+		return "Z" + string(rankDigits[len(rankDigits)-1]), true
+	case 'A':
+		return "", false
+	}
+	head--
+	if head < 'Z' {
+		digits = append(digits, rankDigits[len(rankDigits)-1])
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestRankBetweenKeepsOrder(t *testing.T) {
+	// Build a column by inserting at random positions, like drag and drop
+	rng := rand.New(rand.NewSource(1))
+	column := []string{}
+	for i := 0; i < 2000; i++ {
+		at := rng.Intn(len(column) + 1)
+		before, after := "", ""
+		if at > 0 {
+			before = column[at-1]
+		}
+		if at < len(column) {
+			after = column[at]
+		}
+
+		rank, err := RankBetween(before, after)
+		if err != nil {
+			t.Fatalf("Error ranking between %q and %q: %v", before, after, err)
+		}
+		if (before != "" && rank <= before) || (after != "" && rank >= after) {
+			t.Fatalf("Expected %q to sort between %q and %q", rank, before, after)
+		}
+		column = append(column[:at], append([]string{rank}, column[at:]...)...)
+	}
+
+	if !sort.StringsAreSorted(column) {
+		t.Error("Expected the column to stay sorted")
+	}
+}
+
+func TestRankBetweenStaysShortAtTheEnds(t *testing.T) {
+	last, first := "", ""
+	for i := 0; i < 10000; i++ {
+		var err error
+		if last, err = RankBetween(last, ""); err != nil {
+			t.Fatalf("Error appending: %v", err)
+		}
+		if first, err = RankBetween("", first); err != nil {
+			t.Fatalf("Error prepending: %v", err)
+		}
+	}
+	if len(last) > 4 || len(first) > 4 {
+		t.Errorf("Expected short ranks after 10000 tasks at each end, got %q and %q", first, last)
+	}
+}
+
+func TestRankBetweenRejectsBadInput(t *testing.T) {
+	for _, tt := range [][2]string{{"a1", "a0"}, {"a1", "a1"}, {"a10", ""}, {"!", ""}, {"b0", ""}} {
+		if _, err := RankBetween(tt[0], tt[1]); err == nil {
+			t.Errorf("Expected ranking between %q and %q to fail", tt[0], tt[1])
+		}
+	}
+}
+
+func TestUniqueRankBetween(t *testing.T) {
+	// Includes neighbours that the plain rank between them is a prefix of
+	for _, tt := range [][2]string{{"", ""}, {"a0", ""}, {"", "a0"}, {"", "a0V"}, {"a0", "a1"}, {"a0", "a0001"}, {"Zz", "a01"}, {"a5", "a6"}} {
+		seen := map[string]bool{}
+		for i := 0; i < 200; i++ {
+			rank, err := UniqueRankBetween(tt[0], tt[1])
+			if err != nil {
+				t.Fatalf("Error ranking between %q and %q: %v", tt[0], tt[1], err)
+			}
+			if err := validateRank(rank); err != nil {
+				t.Fatalf("Expected %q to be a valid rank", rank)
+			}
+			if (tt[0] != "" && rank <= tt[0]) || (tt[1] != "" && rank >= tt[1]) {
+				t.Fatalf("Expected %q to sort between %q and %q", rank, tt[0], tt[1])
+			}
+			seen[rank] = true
+		}
+		if len(seen) < 190 {
+			t.Errorf("Expected ranks between %q and %q to differ, got %d distinct of 200", tt[0], tt[1], len(seen))
+		}
+	}
+
+	if _, err := UniqueRankBetween("a1", "a1"); err == nil {
+		t.Error("Expected ranking between equal ranks to fail")
+	}
+}
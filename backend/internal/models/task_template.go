@@ -0,0 +1,256 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	// The server image has no zoneinfo, so recurrence time zones are compiled in
+	_ "time/tzdata"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskRecurrenceFrequency is the unit a task template repeats in
+type TaskRecurrenceFrequency string
+
+const (
+	// TaskRecurrenceDaily templates repeat every Interval days
+	TaskRecurrenceDaily TaskRecurrenceFrequency = "daily"
+	// TaskRecurrenceWeekly templates repeat on Weekdays every Interval weeks
+	TaskRecurrenceWeekly TaskRecurrenceFrequency = "weekly"
+	// TaskRecurrenceMonthly templates repeat on the day of the month of StartsAt every Interval months
+	TaskRecurrenceMonthly TaskRecurrenceFrequency = "monthly"
+)
+
+const (
+	// DefaultTaskRecurrenceTimezone is the time zone days are counted in when a template sets none
+	DefaultTaskRecurrenceTimezone = "Asia/Tokyo"
+	// MaxTaskRecurrenceInterval is the largest number of days, weeks or months between tasks
+	MaxTaskRecurrenceInterval = 52
+	// MaxTaskTemplateLeadDays is the furthest ahead of its due date a task can be created
+	MaxTaskTemplateLeadDays = 60
+)
+
+// TaskRecurrence describes when the tasks of a template are due
+type TaskRecurrence struct {
+	Frequency TaskRecurrenceFrequency `bson:"frequency" json:"frequency" validate:"required,oneof=daily weekly monthly"`
+	// Interval repeats every Interval days, weeks or months; 0 is treated as 1
+	Interval int `bson:"interval" json:"interval"`
+	// Weekdays are the days weekly tasks are due, 0 for Sunday; they default to the weekday of StartsAt
+	Weekdays []time.Weekday `bson:"weekdays,omitempty" json:"weekdays,omitempty"`
+	// StartsAt is the first due date; later due dates keep its time of day.
+	// Monthly tasks fall on its day of the month, or the last day of shorter months.
+	StartsAt time.Time `bson:"startsAt" json:"startsAt" validate:"required"`
+	// EndsAt is the last time a task may be due
+	EndsAt *time.Time `bson:"endsAt,omitempty" json:"endsAt,omitempty"`
+	// Timezone is the IANA time zone days are counted in, Asia/Tokyo by default
+	Timezone string `bson:"timezone" json:"timezone"`
+}
+
+// TaskTemplate represents a task that is created again on a recurrence schedule.
+// Each task is a copy of the template when it was created, so editing or deleting
+// a template only changes the tasks created afterwards.
+type TaskTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Title       string             `bson:"title" json:"title"`
+	Description string             `bson:"description" json:"description"`
+	// Checklist holds the text of the checklist items every task starts with
+	Checklist       []string           `bson:"checklist" json:"checklist"`
+	AssigneeType    TaskAssigneeType   `bson:"assigneeType" json:"assigneeType"`
+	AssignedTo      primitive.ObjectID `bson:"assignedTo" json:"assignedTo"`
+	AssignedSection string             `bson:"assignedSection,omitempty" json:"assignedSection,omitempty"`
+	AssignedRole    Role               `bson:"assignedRole,omitempty" json:"assignedRole,omitempty"`
+	Priority        TaskPriority       `bson:"priority" json:"priority"`
+	Labels          []string           `bson:"labels" json:"labels"`
+	Recurrence      TaskRecurrence     `bson:"recurrence" json:"recurrence"`
+	// LeadDays is how many days before its due date each task is created
+	LeadDays int `bson:"leadDays" json:"leadDays"`
+	// Active templates create tasks; inactive ones are paused
+	Active bool `bson:"active" json:"active"`
+	// NextDueAt is the due date of the next task to create; it is unset once the recurrence has ended
+	NextDueAt *time.Time `bson:"nextDueAt,omitempty" json:"nextDueAt,omitempty"`
+	// NextRunAt is when the next task is created, LeadDays before NextDueAt
+	NextRunAt *time.Time `bson:"nextRunAt,omitempty" json:"nextRunAt,omitempty"`
+	// LastDueAt is the due date of the last task created from the template
+	LastDueAt *time.Time         `bson:"lastDueAt,omitempty" json:"lastDueAt,omitempty"`
+	CreatedBy primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// TaskTemplateInput represents data needed to create or replace a task template
+type TaskTemplateInput struct {
+	Title       string   `json:"title" validate:"required"`
+	Description string   `json:"description"`
+	Checklist   []string `json:"checklist"`
+	// AssigneeType defaults to user, which requires AssignedTo
+	AssigneeType    TaskAssigneeType `json:"assigneeType" validate:"omitempty,oneof=user section role everyone"`
+	AssignedTo      string           `json:"assignedTo"`
+	AssignedSection string           `json:"assignedSection"`
+	AssignedRole    Role             `json:"assignedRole" validate:"omitempty,oneof=admin general"`
+	Priority        TaskPriority     `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	Labels          []string         `json:"labels"`
+	Recurrence      TaskRecurrence   `json:"recurrence" validate:"required"`
+	LeadDays        int              `json:"leadDays" validate:"min=0,max=60"`
+	Active          bool             `json:"active"`
+}
+
+// location returns the time zone the recurrence counts days in
+func (r TaskRecurrence) location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.LoadLocation(DefaultTaskRecurrenceTimezone)
+	}
+	return time.LoadLocation(r.Timezone)
+}
+
+// interval returns the number of days, weeks or months between tasks
+func (r TaskRecurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// Validate checks that the recurrence describes a schedule
+func (r TaskRecurrence) Validate() error {
+	switch r.Frequency {
+	case TaskRecurrenceDaily, TaskRecurrenceWeekly, TaskRecurrenceMonthly:
+	default:
+		return fmt.Errorf("unknown frequency: %q", r.Frequency)
+	}
+	if r.Interval < 0 || r.Interval > MaxTaskRecurrenceInterval {
+		return fmt.Errorf("interval must be between 1 and %d", MaxTaskRecurrenceInterval)
+	}
+	for _, day := range r.Weekdays {
+		if day < time.Sunday || day > time.Saturday {
+			return errors.New("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	if r.StartsAt.IsZero() {
+		return errors.New("startsAt is required")
+	}
+	if r.EndsAt != nil && r.EndsAt.Before(r.StartsAt) {
+		return errors.New("endsAt must not be before startsAt")
+	}
+	if _, err := r.location(); err != nil {
+		return fmt.Errorf("unknown timezone: %q", r.Timezone)
+	}
+	return nil
+}
+
+// Next returns the first due date strictly after the given time, or false once the recurrence has ended
+func (r TaskRecurrence) Next(after time.Time) (time.Time, bool) {
+	loc, err := r.location()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	start := r.StartsAt.In(loc)
+	day := start
+	if after.After(start) {
+		day = after.In(loc)
+	}
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+
+	// Every schedule has a due date within a year of intervals
+	for i := 0; i <= 366*r.interval(); i++ {
+		if r.matches(start, day) {
+			due := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, loc)
+			if due.After(after) && !due.Before(start) {
+				if r.EndsAt != nil && due.After(*r.EndsAt) {
+					return time.Time{}, false
+				}
+				return due, true
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
+
+// matches reports whether a task is due on day, a day on or after the first one
+func (r TaskRecurrence) matches(start, day time.Time) bool {
+	switch r.Frequency {
+	case TaskRecurrenceDaily:
+		return daysBetween(start, day)%r.interval() == 0
+	case TaskRecurrenceWeekly:
+		weekdays := r.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{start.Weekday()}
+		}
+		for _, weekday := range weekdays {
+			if day.Weekday() == weekday {
+				// Weeks start on Monday, so Sunday closes the week it belongs to
+				weeks := daysBetween(weekStart(start), weekStart(day)) / 7
+				return weeks%r.interval() == 0
+			}
+		}
+		return false
+	case TaskRecurrenceMonthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		return months%r.interval() == 0 && day.Day() == min(start.Day(), lastDay)
+	default:
+		return false
+	}
+}
+
+// daysBetween counts the calendar days from a to b, ignoring their time zones' offsets
+func daysBetween(a, b time.Time) int {
+	from := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// weekStart returns the Monday of the week t is in
+func weekStart(t time.Time) time.Time {
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+// PrepareCreate sets fields needed for creating a new template
+func (t *TaskTemplate) PrepareCreate(userID primitive.ObjectID) {
+	now := time.Now()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	t.CreatedBy = userID
+}
+
+// Schedule sets the next task to create to the first due date after now.
+// Due dates up to the last task already created are never scheduled again.
+func (t *TaskTemplate) Schedule(now time.Time) {
+	after := now
+	if t.LastDueAt != nil && t.LastDueAt.After(after) {
+		after = *t.LastDueAt
+	}
+
+	next, ok := t.Recurrence.Next(after)
+	if !ok {
+		t.NextDueAt, t.NextRunAt = nil, nil
+		return
+	}
+	runAt := next.AddDate(0, 0, -t.LeadDays)
+	t.NextDueAt, t.NextRunAt = &next, &runAt
+}
+
+// NewTask creates the task that is due at NextDueAt, copying the template as it is now
+func (t *TaskTemplate) NewTask() *Task {
+	dueDate := *t.NextDueAt
+	templateID := t.ID
+	task := &Task{
+		Title:           t.Title,
+		Description:     t.Description,
+		DueDate:         &dueDate,
+		AssigneeType:    t.AssigneeType,
+		AssignedTo:      t.AssignedTo,
+		AssignedSection: t.AssignedSection,
+		AssignedRole:    t.AssignedRole,
+		TemplateID:      &templateID,
+		Priority:        t.Priority,
+		Labels:          append([]string{}, t.Labels...),
+	}
+	task.PrepareCreate(t.CreatedBy)
+	for _, text := range t.Checklist {
+		task.Checklist = append(task.Checklist, NewChecklistItem(text))
+	}
+	return task
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Error loading %s: %v", name, err)
+	}
+	return loc
+}
+
+// dueDates lists the first n due dates of a recurrence after a time
+func dueDates(r TaskRecurrence, after time.Time, n int) []time.Time {
+	var dates []time.Time
+	for len(dates) < n {
+		next, ok := r.Next(after)
+		if !ok {
+			break
+		}
+		dates = append(dates, next)
+		after = next
+	}
+	return dates
+}
+
+func TestTaskRecurrenceNext(t *testing.T) {
+	tokyo := mustLocation(t, "Asia/Tokyo")
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2025, month, day, hour, 0, 0, 0, tokyo)
+	}
+	end := at(6, 2, 8)
+
+	tests := []struct {
+		name       string
+		recurrence TaskRecurrence
+		after      time.Time
+		want       []time.Time
+	}{
+		{
+			name:       "every other day",
+			recurrence: TaskRecurrence{Frequency: TaskRecurrenceDaily, Interval: 2, StartsAt: at(6, 1, 18)},
+			after:      at(6, 3, 19),
+			want:       []time.Time{at(6, 5, 18), at(6, 7, 18)},
+		},
+		{
+			// 07:00 in Tokyo is still Friday in UTC, but the task stays on Saturday mornings
+			name:       "weekly on the start's weekday in its time zone",
+			recurrence: TaskRecurrence{Frequency: TaskRecurrenceWeekly, StartsAt: at(6, 7, 7)},
+			after:      at(6, 1, 0),
+			want:       []time.Time{at(6, 7, 7), at(6, 14, 7), at(6, 21, 7)},
+		},
+		{
+			name:       "every other week on two days",
+			recurrence: TaskRecurrence{Frequency: TaskRecurrenceWeekly, Interval: 2, Weekdays: []time.Weekday{time.Wednesday, time.Sunday}, StartsAt: at(6, 2, 9)},
+			after:      at(6, 2, 9),
+			want:       []time.Time{at(6, 4, 9), at(6, 8, 9), at(6, 18, 9), at(6, 22, 9)},
+		},
+		{
+			name:       "monthly on the last day of shorter months",
+			recurrence: TaskRecurrence{Frequency: TaskRecurrenceMonthly, StartsAt: at(1, 31, 12)},
+			after:      at(1, 31, 12),
+			want:       []time.Time{at(2, 28, 12), at(3, 31, 12), at(4, 30, 12)},
+		},
+		{
+			name:       "until the end date",
+			recurrence: TaskRecurrence{Frequency: TaskRecurrenceDaily, StartsAt: at(6, 1, 8), EndsAt: &end},
+			after:      at(5, 1, 0),
+			want:       []time.Time{at(6, 1, 8), at(6, 2, 8)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.recurrence.Validate(); err != nil {
+				t.Fatalf("Expected a valid recurrence, got %v", err)
+			}
+			got := dueDates(tt.recurrence, tt.after, 4)
+			if len(got) > len(tt.want) {
+				got = got[:len(tt.want)]
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("Due date %d: expected %v, got %v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTaskRecurrenceValidate(t *testing.T) {
+	start := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+	invalid := []TaskRecurrence{
+		{Frequency: "yearly", StartsAt: start},
+		{Frequency: TaskRecurrenceDaily},
+		{Frequency: TaskRecurrenceDaily, StartsAt: start, Interval: MaxTaskRecurrenceInterval + 1},
+		{Frequency: TaskRecurrenceWeekly, StartsAt: start, Weekdays: []time.Weekday{7}},
+		{Frequency: TaskRecurrenceDaily, StartsAt: start, EndsAt: &before},
+		{Frequency: TaskRecurrenceDaily, StartsAt: start, Timezone: "Mars/Olympus"},
+	}
+	for _, r := range invalid {
+		if r.Validate() == nil {
+			t.Errorf("Expected %+v to be rejected", r)
+		}
+	}
+}
+
+func TestTaskTemplateScheduleAndNewTask(t *testing.T) {
+	tokyo := mustLocation(t, "Asia/Tokyo")
+	start := time.Date(2025, 6, 7, 8, 0, 0, 0, tokyo)
+	template := &TaskTemplate{
+		ID:           primitive.NewObjectID(),
+		Title:        "Load the equipment truck",
+		Checklist:    []string{"Timpani", "Podium"},
+		AssigneeType: TaskAssigneeSection, AssignedSection: "Percussion",
+		Recurrence: TaskRecurrence{Frequency: TaskRecurrenceWeekly, StartsAt: start},
+		LeadDays:   2,
+		CreatedBy:  primitive.NewObjectID(),
+	}
+
+	template.Schedule(start.AddDate(0, 0, -10))
+	if !template.NextDueAt.Equal(start) || !template.NextRunAt.Equal(start.AddDate(0, 0, -2)) {
+		t.Fatalf("Expected the first task due %v and created two days ahead, got %v and %v", start, template.NextDueAt, template.NextRunAt)
+	}
+
+	task := template.NewTask()
+	if task.Title != template.Title || !task.DueDate.Equal(start) || *task.TemplateID != template.ID || task.AssignedSection != "Percussion" {
+		t.Errorf("Expected the task to copy the template, got %+v", task)
+	}
+	if task.Status != TaskStatusTodo || len(task.Checklist) != 2 || task.Checklist[1].Text != "Podium" || task.CreatedBy != template.CreatedBy {
+		t.Errorf("Expected an open task with the template's checklist, got %+v", task)
+	}
+
+	// Editing the template does not change the task already created from it
+	template.Title = "Load the truck"
+	template.Checklist[0] = "Marimba"
+	if task.Title != "Load the equipment truck" || task.Checklist[0].Text != "Timpani" {
+		t.Error("Expected the created task to keep the old template values")
+	}
+
+	// A due date that already has a task is not scheduled again, even if now is earlier
+	template.LastDueAt = task.DueDate
+	template.Schedule(start.AddDate(0, 0, -10))
+	if !template.NextDueAt.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("Expected the following week, got %v", template.NextDueAt)
+	}
+
+	ended := start.AddDate(0, 0, 3)
+	template.Recurrence.EndsAt = &ended
+	template.Schedule(start)
+	if template.NextDueAt != nil || template.NextRunAt != nil {
+		t.Errorf("Expected nothing scheduled after the recurrence ended, got %v", template.NextDueAt)
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTaskVisibility(t *testing.T) {
+	creator := &User{ID: primitive.NewObjectID(), Role: GeneralRole}
+	assignee := &User{ID: primitive.NewObjectID(), Role: GeneralRole}
+	other := &User{ID: primitive.NewObjectID(), Role: GeneralRole, Section: "Brass"}
+	admin := &User{ID: primitive.NewObjectID(), Role: AdminRole}
+	task := &Task{CreatedBy: creator.ID, AssignedTo: assignee.ID}
+
+	if !task.VisibleTo(creator) || !task.VisibleTo(assignee) || !task.VisibleTo(admin) {
+		t.Error("Expected the creator, the assignee and admins to see the task")
+	}
+	if task.VisibleTo(other) || task.VisibleTo(&User{Role: GeneralRole}) {
+		t.Error("Expected other members not to see the task")
+	}
+	if task.ManagedBy(assignee.ID, GeneralRole) || !task.ManagedBy(creator.ID, GeneralRole) {
+		t.Error("Expected only the creator among members to manage the task")
+	}
+}
+
+func TestGroupTaskAssignees(t *testing.T) {
+	brass := &User{ID: primitive.NewObjectID(), Role: GeneralRole, Section: "Brass"}
+	guard := &User{ID: primitive.NewObjectID(), Role: GeneralRole, Section: "Color Guard"}
+	admin := &User{ID: primitive.NewObjectID(), Role: AdminRole}
+
+	section := &Task{AssigneeType: TaskAssigneeSection, AssignedSection: "Brass"}
+	if !section.IsAssignee(brass) || section.IsAssignee(guard) || !section.VisibleTo(brass) || section.VisibleTo(guard) {
+		t.Error("Expected a section task to be assigned to and seen by its section only")
+	}
+	if (&Task{AssigneeType: TaskAssigneeSection}).IsAssignee(&User{Role: GeneralRole}) {
+		t.Error("Expected members without a section not to match")
+	}
+
+	role := &Task{AssigneeType: TaskAssigneeRole, AssignedRole: AdminRole}
+	if !role.IsAssignee(admin) || role.IsAssignee(brass) {
+		t.Error("Expected a role task to be assigned to members with the role")
+	}
+
+	everyone := &Task{AssigneeType: TaskAssigneeEveryone}
+	if !everyone.IsAssignee(brass) || !everyone.IsAssignee(guard) || everyone.MemberFilter() != (UserFilter{}) {
+		t.Error("Expected a task for everyone to be assigned to every member")
+	}
+}
+
+func TestTaskProgress(t *testing.T) {
+	done, pending := &User{ID: primitive.NewObjectID(), Username: "hanako"}, &User{ID: primitive.NewObjectID(), Username: "taro"}
+	completedAt := time.Now()
+	task := &Task{
+		AssigneeType: TaskAssigneeSection, AssignedSection: "Brass",
+		Completions: []TaskCompletion{{UserID: done.ID, CompletedAt: completedAt}, {UserID: primitive.NewObjectID(), CompletedAt: completedAt}},
+	}
+
+	// Completions of members who have left the section are not counted
+	progress := task.Progress([]*User{done, pending})
+	if progress.Total != 2 || progress.Completed != 1 {
+		t.Fatalf("Expected 1 of 2 done, got %d of %d", progress.Completed, progress.Total)
+	}
+	if progress.Done[0].Username != "hanako" || !progress.Done[0].CompletedAt.Equal(completedAt) || progress.Pending[0].Username != "taro" {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+
+	// A user task is done by its assignee once it is completed
+	single := &Task{AssignedTo: pending.ID, Status: TaskStatusCompleted, CompletedAt: &completedAt}
+	if single.CompletedBy(pending.ID) == nil || single.CompletedBy(done.ID) != nil {
+		t.Error("Expected a completed user task to count for its assignee only")
+	}
+}
+
+func TestChecklistItemSetDone(t *testing.T) {
+	userID := primitive.NewObjectID()
+	item := NewChecklistItem("Measure jackets")
+
+	item.SetDone(true, userID)
+	if !item.Done || item.DoneBy == nil || *item.DoneBy != userID || item.DoneAt == nil {
+		t.Fatalf("Expected the item to record who checked it, got %+v", item)
+	}
+
+	doneAt := *item.DoneAt
+	item.SetDone(true, primitive.NewObjectID())
+	if *item.DoneBy != userID || !item.DoneAt.Equal(doneAt) {
+		t.Error("Expected checking a done item again to keep who checked it first")
+	}
+
+	item.SetDone(false, userID)
+	if item.Done || item.DoneBy != nil || item.DoneAt != nil {
+		t.Errorf("Expected unchecking to clear the completion, got %+v", item)
+	}
+}
+
+func TestBuildTaskCommentThreads(t *testing.T) {
+	now := time.Now()
+	comment := func(parent *TaskComment) *TaskComment {
+		c := &TaskComment{ID: primitive.NewObjectID(), CreatedAt: now}
+		if parent != nil {
+			c.ParentID = &parent.ID
+		}
+		return c
+	}
+
+	first := comment(nil)
+	reply := comment(first)
+	nested := comment(reply)
+	second := comment(nil)
+	missing := primitive.NewObjectID()
+	orphan := &TaskComment{ID: primitive.NewObjectID(), ParentID: &missing}
+
+	threads := BuildTaskCommentThreads([]*TaskComment{first, reply, second, nested, orphan})
+
+	if len(threads) != 3 || threads[0].ID != first.ID || threads[1].ID != second.ID || threads[2].ID != orphan.ID {
+		t.Fatalf("Expected first, second and the orphaned reply at the top level, got %d threads", len(threads))
+	}
+	if len(threads[0].Replies) != 1 || threads[0].Replies[0].ID != reply.ID {
+		t.Fatal("Expected the reply under the first comment")
+	}
+	if len(threads[0].Replies[0].Replies) != 1 || threads[0].Replies[0].Replies[0].ID != nested.ID {
+		t.Error("Expected the nested reply under the reply")
+	}
+	if threads[1].Replies == nil {
+		t.Error("Expected comments without replies to have an empty list")
+	}
+}
@@ -19,32 +19,43 @@ const (
 
 // User represents a user in the system
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Username  string             `bson:"username" json:"username"`
-	FullName  string             `bson:"fullName" json:"fullName"`
-	Email     string             `bson:"email" json:"email"`
-	Password  string             `bson:"password" json:"-"` // Password is not included in JSON responses
-	Role      Role               `bson:"role" json:"role"`
-	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Username         string             `bson:"username" json:"username"`
+	FullName         string             `bson:"fullName" json:"fullName"`
+	Email            string             `bson:"email" json:"email"`
+	Password         string             `bson:"password" json:"-"` // Password is not included in JSON responses
+	Role             Role               `bson:"role" json:"role"`
+	Section          string             `bson:"section,omitempty" json:"section,omitempty"`
+	Instrument       string             `bson:"instrument,omitempty" json:"instrument,omitempty"`
+	TwoFactorEnabled bool               `bson:"twoFactorEnabled" json:"twoFactorEnabled"` // Set once a TOTP secret has been verified
+	TOTPSecret       string             `bson:"totpSecret,omitempty" json:"-"`
+	TOTPLastStep     int64              `bson:"totpLastStep,omitempty" json:"-"`  // Last accepted TOTP time step, to prevent replay
+	RecoveryCodes    []string           `bson:"recoveryCodes,omitempty" json:"-"` // Hashed recovery codes
+	OIDCSubject      string             `bson:"oidcSubject,omitempty" json:"-"`   // Subject of the linked single sign-on identity
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
 
 // CreateUserInput represents data needed to create a new user
 type CreateUserInput struct {
-	Username string `json:"username" validate:"required"`
-	FullName string `json:"fullName" validate:"required"`
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
-	Role     Role   `json:"role" validate:"required,oneof=admin general"`
+	Username   string `json:"username" validate:"required"`
+	FullName   string `json:"fullName" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=6"`
+	Role       Role   `json:"role" validate:"required,oneof=admin general"`
+	Section    string `json:"section"`
+	Instrument string `json:"instrument"`
 }
 
 // UpdateUserInput represents data needed to update an existing user
 type UpdateUserInput struct {
-	Username string `json:"username"`
-	FullName string `json:"fullName"`
-	Email    string `json:"email" validate:"omitempty,email"`
-	Password string `json:"password" validate:"omitempty,min=6"`
-	Role     Role   `json:"role" validate:"omitempty,oneof=admin general"`
+	Username   string `json:"username"`
+	FullName   string `json:"fullName"`
+	Email      string `json:"email" validate:"omitempty,email"`
+	Password   string `json:"password" validate:"omitempty,min=6"`
+	Role       Role   `json:"role" validate:"omitempty,oneof=admin general"`
+	Section    string `json:"section"`
+	Instrument string `json:"instrument"`
 }
 
 // LoginInput represents data needed for user login
@@ -53,6 +64,25 @@ type LoginInput struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// TwoFactorCodeInput represents a TOTP code or recovery code supplied by a user
+type TwoFactorCodeInput struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// TwoFactorLoginInput represents data needed to complete a login that requires 2FA
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+// TwoFactorEnrollment represents the secret shown to a user while enrolling in 2FA
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
 // HashPassword creates a bcrypt hash of the password
 func (u *User) HashPassword() error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
@@ -79,4 +109,4 @@ func (u *User) PrepareCreate() {
 // PrepareUpdate sets fields needed for updating a user
 func (u *User) PrepareUpdate() {
 	u.UpdatedAt = time.Now()
-}
\ No newline at end of file
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookSecretPrefix starts every webhook signing secret
+const WebhookSecretPrefix = "whsec_"
+
+// WebhookEventType is a kind of change that webhook subscriptions can receive
+type WebhookEventType string
+
+const (
+	WebhookEventCreated        WebhookEventType = "event.created"
+	WebhookEventUpdated        WebhookEventType = "event.updated"
+	WebhookEventDeleted        WebhookEventType = "event.deleted"
+	WebhookAttendanceRecorded  WebhookEventType = "attendance.recorded"
+	WebhookPracticeMenuCreated WebhookEventType = "practice_menu.created"
+	WebhookPracticeMenuUpdated WebhookEventType = "practice_menu.updated"
+)
+
+// WebhookEventTypes lists every event type a subscription can receive. They are reserved for the
+// APIs that will change events, attendance and practice menus; nothing emits them yet.
+var WebhookEventTypes = []WebhookEventType{
+	WebhookEventCreated, WebhookEventUpdated, WebhookEventDeleted,
+	WebhookAttendanceRecorded,
+	WebhookPracticeMenuCreated, WebhookPracticeMenuUpdated,
+}
+
+// WebhookSubscription represents an external endpoint that receives signed notifications of changes
+type WebhookSubscription struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name string             `bson:"name" json:"name"`
+	URL  string             `bson:"url" json:"url"`
+	// Secret signs deliveries. It is stored as is because signing needs it, and only shown when created.
+	Secret    string             `bson:"secret" json:"-"`
+	Events    []WebhookEventType `bson:"events" json:"events"`
+	Active    bool               `bson:"active" json:"active"`
+	CreatedBy primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// CreateWebhookSubscriptionInput represents data needed to create a webhook subscription
+type CreateWebhookSubscriptionInput struct {
+	Name   string             `json:"name" validate:"required"`
+	URL    string             `json:"url" validate:"required,url"`
+	Events []WebhookEventType `json:"events" validate:"required"`
+}
+
+// UpdateWebhookSubscriptionInput represents data needed to update a webhook subscription
+type UpdateWebhookSubscriptionInput struct {
+	Name   string             `json:"name" validate:"required"`
+	URL    string             `json:"url" validate:"required,url"`
+	Events []WebhookEventType `json:"events" validate:"required"`
+	Active bool               `json:"active"`
+}
+
+// WebhookDeliveryStatus is the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries are waiting for their first or next attempt
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded deliveries were accepted with a 2xx response
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryDead deliveries failed every attempt and are only retried by a manual redelivery
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery represents one event sent to one subscription, with the log of its attempts
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	SubscriptionID primitive.ObjectID `bson:"subscriptionId" json:"subscriptionId"`
+	// EventID identifies the change; redeliveries keep it so receivers can discard duplicates
+	EventID   string                `bson:"eventId" json:"eventId"`
+	EventType WebhookEventType      `bson:"eventType" json:"eventType"`
+	Payload   string                `bson:"payload" json:"payload"`
+	Status    WebhookDeliveryStatus `bson:"status" json:"status"`
+	Attempts  []WebhookAttempt      `bson:"attempts" json:"attempts"`
+	// NextAttemptAt is when a pending delivery is tried next
+	NextAttemptAt time.Time `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	// LockedUntil keeps other replicas from sending a delivery that is being attempted
+	LockedUntil time.Time `bson:"lockedUntil" json:"-"`
+	// RedeliveryOf is the delivery this one manually repeats
+	RedeliveryOf *primitive.ObjectID `bson:"redeliveryOf,omitempty" json:"redeliveryOf,omitempty"`
+	CreatedAt    time.Time           `bson:"createdAt" json:"createdAt"`
+	DeliveredAt  *time.Time          `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+}
+
+// WebhookAttempt records one attempt to send a delivery
+type WebhookAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs int64     `bson:"durationMs" json:"durationMs"`
+}
+
+// WebhookDeliveryFilter represents the filters that can be applied to a delivery listing
+type WebhookDeliveryFilter struct {
+	SubscriptionID primitive.ObjectID
+	Status         WebhookDeliveryStatus
+}
+
+// NewWebhookSubscription creates an active subscription with a new signing secret
+func NewWebhookSubscription(name, url string, events []WebhookEventType, createdBy primitive.ObjectID) (*WebhookSubscription, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &WebhookSubscription{
+		Name:      name,
+		URL:       url,
+		Secret:    WebhookSecretPrefix + base64.RawURLEncoding.EncodeToString(buf),
+		Events:    events,
+		Active:    true,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// IsValidWebhookEventType reports whether t is an event type subscriptions can receive
+func IsValidWebhookEventType(t WebhookEventType) bool {
+	for _, known := range WebhookEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWebhookDelivery creates a pending delivery of an event to a subscription, due now
+func NewWebhookDelivery(subscriptionID primitive.ObjectID, eventID string, eventType WebhookEventType, payload string) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         WebhookDeliveryPending,
+		Attempts:       []WebhookAttempt{},
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+}
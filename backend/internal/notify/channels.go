@@ -0,0 +1,276 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+)
+
+// linkURL returns the absolute frontend URL of a notification's link, or "" if it has none
+func linkURL(appURL string, notification *models.Notification) string {
+	if notification.Link == "" {
+		return ""
+	}
+	return strings.TrimSuffix(appURL, "/") + notification.Link
+}
+
+// webhookTimeout bounds a request to a webhook URL, from dialing to reading the response
+const webhookTimeout = 10 * time.Second
+
+// ErrNonPublicAddress is returned when a webhook URL resolves to an address inside a private network
+var ErrNonPublicAddress = errors.New("notify: webhook address is not public")
+
+// publicClient returns an HTTP client for URLs chosen by users. It connects only to public addresses,
+// checked after DNS resolution so a hostname cannot point it into the server's network, and does not
+// follow redirects. No proxy is used, since the check would then apply to the proxy instead.
+func publicClient() *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: refuseNonPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webhookTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refuseNonPublic is a dialer Control hook that refuses loopback, private, link-local,
+// multicast and unspecified addresses
+func refuseNonPublic(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	return nil
+}
+
+// EmailChannel sends notifications by email over SMTP
+type EmailChannel struct {
+	smtp   SMTPConfig
+	appURL string
+}
+
+// NewEmailChannel creates a new EmailChannel
+func NewEmailChannel(cfg Config) *EmailChannel {
+	return &EmailChannel{smtp: cfg.SMTP, appURL: cfg.AppURL}
+}
+
+// Name is the channel users choose in their preferences
+func (ch *EmailChannel) Name() models.NotificationChannel {
+	return models.NotificationEmail
+}
+
+// Send emails a notification to the recipient's address. STARTTLS is used when the server offers it.
+func (ch *EmailChannel) Send(ctx context.Context, to Recipient, notification *models.Notification) error {
+	if to.User.Email == "" {
+		return ErrNoAddress
+	}
+
+	from, err := mail.ParseAddress(ch.smtp.From)
+	if err != nil {
+		return err
+	}
+	msg, err := emailMessage(from, to.User, notification, linkURL(ch.appURL, notification))
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ch.smtp.Host, strconv.Itoa(ch.smtp.Port)))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, ch.smtp.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: ch.smtp.Host}); err != nil {
+			return err
+		}
+	}
+	if ch.smtp.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", ch.smtp.Username, ch.smtp.Password, ch.smtp.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.User.Email); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// emailMessage builds a plain text UTF-8 email of a notification
+func emailMessage(from *mail.Address, to *models.User, notification *models.Notification, link string) ([]byte, error) {
+	var buf bytes.Buffer
+	recipient := mail.Address{Name: to.FullName, Address: to.Email}
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", recipient.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", notification.CreatedAt.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := notification.Body
+	if link != "" {
+		body += "\n\n" + link
+	}
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// webhookPayload is the JSON body the webhook channel posts
+type webhookPayload struct {
+	ID        string                  `json:"id"`
+	Type      models.NotificationType `json:"type"`
+	Title     string                  `json:"title"`
+	Body      string                  `json:"body"`
+	URL       string                  `json:"url,omitempty"`
+	CreatedAt time.Time               `json:"createdAt"`
+}
+
+// WebhookChannel posts notifications as JSON to the recipient's webhook URL
+type WebhookChannel struct {
+	client *http.Client
+	appURL string
+}
+
+// NewWebhookChannel creates a new WebhookChannel
+func NewWebhookChannel(cfg Config) *WebhookChannel {
+	return &WebhookChannel{client: publicClient(), appURL: cfg.AppURL}
+}
+
+// Name is the channel users choose in their preferences
+func (ch *WebhookChannel) Name() models.NotificationChannel {
+	return models.NotificationWebhook
+}
+
+// Send posts a notification to the recipient's webhook URL
+func (ch *WebhookChannel) Send(ctx context.Context, to Recipient, notification *models.Notification) error {
+	if to.Preferences.WebhookURL == "" {
+		return ErrNoAddress
+	}
+	return postJSON(ctx, ch.client, to.Preferences.WebhookURL, webhookPayload{
+		ID:        notification.ID.Hex(),
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		URL:       linkURL(ch.appURL, notification),
+		CreatedAt: notification.CreatedAt,
+	})
+}
+
+// ChatChannel posts notifications to a Slack-compatible incoming webhook
+type ChatChannel struct {
+	client *http.Client
+	appURL string
+}
+
+// NewChatChannel creates a new ChatChannel
+func NewChatChannel(cfg Config) *ChatChannel {
+	return &ChatChannel{client: publicClient(), appURL: cfg.AppURL}
+}
+
+// Name is the channel users choose in their preferences
+func (ch *ChatChannel) Name() models.NotificationChannel {
+	return models.NotificationChat
+}
+
+// Send posts a notification as a chat message
+func (ch *ChatChannel) Send(ctx context.Context, to Recipient, notification *models.Notification) error {
+	if to.Preferences.ChatWebhookURL == "" {
+		return ErrNoAddress
+	}
+
+	text := "*" + notification.Title + "*\n" + notification.Body
+	if link := linkURL(ch.appURL, notification); link != "" {
+		text += "\n" + link
+	}
+	return postJSON(ctx, ch.client, to.Preferences.ChatWebhookURL, map[string]string{"text": text})
+}
+
+// postJSON posts body as JSON and fails unless the response status is 2xx.
+// Redirects are not followed, so a 3xx response fails too.
+func postJSON(ctx context.Context, client *http.Client, target string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// isURL reports whether s is an absolute http or https URL
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
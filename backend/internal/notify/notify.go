@@ -0,0 +1,191 @@
+// Package notify records in-app notifications and delivers them to the channels each member chooses
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+)
+
+const (
+	// queueSize is how many notifications may wait for channel delivery
+	queueSize = 256
+	// deliveryTimeout bounds a single delivery to one channel
+	deliveryTimeout = 15 * time.Second
+)
+
+// ErrNoAddress is returned by a channel when the recipient has not given it an address
+var ErrNoAddress = errors.New("notify: recipient has no address for this channel")
+
+// Config configures notification delivery
+type Config struct {
+	// AppURL is the frontend address that notification links are relative to
+	AppURL string     `yaml:"appUrl"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig configures the email channel; it is disabled when no host is set
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender address, optionally with a display name
+	From string `yaml:"from"`
+}
+
+// Enabled reports whether email delivery is configured
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// Validate checks that the settings are usable
+func (c Config) Validate() error {
+	if !isURL(c.AppURL) {
+		return errors.New("appUrl must be an absolute URL")
+	}
+	if c.SMTP.Enabled() {
+		if c.SMTP.Port <= 0 || c.SMTP.Port > 65535 {
+			return fmt.Errorf("smtp.port must be between 1 and 65535, got %d", c.SMTP.Port)
+		}
+		if _, err := mail.ParseAddress(c.SMTP.From); err != nil {
+			return fmt.Errorf("smtp.from must be an email address: %w", err)
+		}
+	}
+	return nil
+}
+
+// Recipient is the user a notification is delivered to, with where they want it
+type Recipient struct {
+	User        *models.User
+	Preferences *models.NotificationPreferences
+}
+
+// Channel delivers notifications outside the app
+type Channel interface {
+	// Name is the channel users choose in their preferences
+	Name() models.NotificationChannel
+	// Send delivers a notification, returning ErrNoAddress if the recipient cannot be reached on this channel
+	Send(ctx context.Context, to Recipient, notification *models.Notification) error
+}
+
+// Service records notifications and fans them out to channels in the background
+type Service struct {
+	notifications repositories.NotificationRepository
+	preferences   repositories.NotificationPreferencesRepository
+	users         repositories.UserRepository
+	hub           realtime.Hub
+	channels      map[models.NotificationChannel]Channel
+	queue         chan *models.Notification
+}
+
+// NewService creates a new Service that delivers to the given channels
+func NewService(notifications repositories.NotificationRepository, preferences repositories.NotificationPreferencesRepository,
+	users repositories.UserRepository, hub realtime.Hub, channels ...Channel) *Service {
+	s := &Service{
+		notifications: notifications,
+		preferences:   preferences,
+		users:         users,
+		hub:           hub,
+		channels:      map[models.NotificationChannel]Channel{},
+		queue:         make(chan *models.Notification, queueSize),
+	}
+	for _, ch := range channels {
+		s.channels[ch.Name()] = ch
+	}
+	return s
+}
+
+// Notify records a notification in the recipient's inbox, pushes it to their open change
+// streams and queues it for the other channels they chose. Channel delivery is best effort:
+// the inbox keeps the notification if the queue is full or the server stops first.
+func (s *Service) Notify(ctx context.Context, notification *models.Notification) error {
+	notification.PrepareCreate()
+	if _, err := s.notifications.Create(ctx, notification); err != nil {
+		return err
+	}
+
+	logger := logging.FromContext(ctx)
+	if err := s.hub.Publish(ctx, realtime.NotificationChange(notification)); err != nil {
+		logger.WarnContext(ctx, "failed to publish notification", "notification_id", notification.ID.Hex(), "error", err)
+	}
+
+	select {
+	case s.queue <- notification:
+	default:
+		logger.WarnContext(ctx, "notification queue is full, skipping channels", "notification_id", notification.ID.Hex())
+	}
+	return nil
+}
+
+// Run delivers queued notifications until ctx is done. Start it with a worker.Group.
+func (s *Service) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case notification := <-s.queue:
+			s.deliver(ctx, notification)
+		}
+	}
+}
+
+// deliver sends a notification to every channel its recipient chose for its type
+func (s *Service) deliver(ctx context.Context, notification *models.Notification) {
+	logger := slog.With("notification_id", notification.ID.Hex(), "user_id", notification.UserID.Hex())
+
+	preferences, err := s.preferences.FindByUserID(ctx, notification.UserID)
+	if err != nil {
+		logger.Error("failed to load notification preferences", "error", err)
+		return
+	}
+	if preferences == nil {
+		preferences = models.DefaultNotificationPreferences(notification.UserID)
+	}
+
+	names := preferences.Channels[notification.Type]
+	if len(names) == 0 {
+		return
+	}
+
+	user, err := s.users.FindByID(ctx, notification.UserID.Hex())
+	if err != nil {
+		logger.Error("failed to load notification recipient", "error", err)
+		return
+	}
+	if user == nil {
+		return
+	}
+
+	to := Recipient{User: user, Preferences: preferences}
+	for _, name := range names {
+		ch, ok := s.channels[name]
+		if !ok {
+			metrics.NotificationsSent.WithLabelValues(string(name), metrics.NotificationSkipped).Inc()
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		err := ch.Send(sendCtx, to, notification)
+		cancel()
+
+		switch {
+		case errors.Is(err, ErrNoAddress):
+			metrics.NotificationsSent.WithLabelValues(string(name), metrics.NotificationSkipped).Inc()
+		case err != nil:
+			metrics.NotificationsSent.WithLabelValues(string(name), metrics.NotificationFailed).Inc()
+			logger.Warn("failed to deliver notification", "channel", name, "error", err)
+		default:
+			metrics.NotificationsSent.WithLabelValues(string(name), metrics.NotificationDelivered).Inc()
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeNotifications struct {
+	repositories.NotificationRepository
+	created []*models.Notification
+}
+
+func (f *fakeNotifications) Create(ctx context.Context, n *models.Notification) (string, error) {
+	n.ID = primitive.NewObjectID()
+	f.created = append(f.created, n)
+	return n.ID.Hex(), nil
+}
+
+type fakePreferences struct {
+	repositories.NotificationPreferencesRepository
+	preferences *models.NotificationPreferences
+}
+
+func (f *fakePreferences) FindByUserID(ctx context.Context, userID primitive.ObjectID) (*models.NotificationPreferences, error) {
+	return f.preferences, nil
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+	user *models.User
+}
+
+func (f *fakeUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	return f.user, nil
+}
+
+// recordingChannel records the notifications it is asked to send
+type recordingChannel struct {
+	name models.NotificationChannel
+	sent []*models.Notification
+}
+
+func (r *recordingChannel) Name() models.NotificationChannel { return r.name }
+
+func (r *recordingChannel) Send(ctx context.Context, to Recipient, n *models.Notification) error {
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func TestNotifyDeliversToChosenChannels(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: primitive.NewObjectID(), Email: "hanako@example.jp"}
+	notifications := &fakeNotifications{}
+	preferences := &fakePreferences{preferences: &models.NotificationPreferences{
+		UserID: user.ID,
+		Channels: map[models.NotificationType][]models.NotificationChannel{
+			models.NotificationTaskAssigned: {models.NotificationChat},
+		},
+	}}
+	email := &recordingChannel{name: models.NotificationEmail}
+	chat := &recordingChannel{name: models.NotificationChat}
+	hub := realtime.NewMemoryHub()
+	s := NewService(notifications, preferences, &fakeUsers{user: user}, hub, email, chat)
+
+	stream, _ := hub.Subscribe(ctx, realtime.Filter{UserID: user.ID.Hex(), Role: models.GeneralRole})
+
+	task := &models.Task{ID: primitive.NewObjectID(), Title: "Fit uniforms", AssignedTo: user.ID}
+	if err := s.TaskAssigned(ctx, task, []primitive.ObjectID{task.AssignedTo}, primitive.NewObjectID()); err != nil {
+		t.Fatalf("Error notifying: %v", err)
+	}
+	if len(notifications.created) != 1 || notifications.created[0].Link != "/tasks/"+task.ID.Hex() {
+		t.Fatalf("Expected one inbox notification linking to the task, got %+v", notifications.created)
+	}
+	if c := <-stream; c.Topic != realtime.TopicNotifications {
+		t.Errorf("Expected the notification on the change stream, got %s", c.Topic)
+	}
+
+	s.deliver(ctx, <-s.queue)
+	if len(chat.sent) != 1 || len(email.sent) != 0 {
+		t.Errorf("Expected delivery to chat only, got chat %d, email %d", len(chat.sent), len(email.sent))
+	}
+
+	// Event changes are event_changed, which the preferences keep in the inbox only
+	event := &models.Event{ID: primitive.NewObjectID(), Title: "Rehearsal", Attendees: []primitive.ObjectID{user.ID}}
+	s.EventChanged(ctx, event, realtime.ActionUpdated, primitive.NewObjectID())
+	s.deliver(ctx, <-s.queue)
+	if len(chat.sent) != 1 || len(email.sent) != 0 {
+		t.Error("Expected types without channels to stay in the inbox")
+	}
+
+	// Members are not told about their own changes
+	s.TaskAssigned(ctx, task, []primitive.ObjectID{task.AssignedTo}, user.ID)
+	if len(notifications.created) != 2 {
+		t.Error("Expected no notification for a self-assigned task")
+	}
+}
+
+func TestChatAndWebhookChannels(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+	}))
+	defer server.Close()
+
+	cfg := Config{AppURL: "https://dashboard.example.jp/"}
+	to := Recipient{User: &models.User{}, Preferences: &models.NotificationPreferences{WebhookURL: server.URL, ChatWebhookURL: server.URL}}
+	n := &models.Notification{ID: primitive.NewObjectID(), Type: models.NotificationTaskAssigned, Title: "New task", Body: "Fit uniforms", Link: "/tasks/1"}
+
+	// The test server listens on loopback, which the channels' own clients refuse
+	chat, webhook := NewChatChannel(cfg), NewWebhookChannel(cfg)
+	chat.client, webhook.client = server.Client(), server.Client()
+	if err := chat.Send(context.Background(), to, n); err != nil {
+		t.Fatalf("Error sending chat message: %v", err)
+	}
+	if err := webhook.Send(context.Background(), to, n); err != nil {
+		t.Fatalf("Error sending webhook: %v", err)
+	}
+
+	if text := bodies[0]["text"]; text != "*New task*\nFit uniforms\nhttps://dashboard.example.jp/tasks/1" {
+		t.Errorf("Unexpected chat text %q", text)
+	}
+	if bodies[1]["type"] != "task_assigned" || bodies[1]["url"] != "https://dashboard.example.jp/tasks/1" {
+		t.Errorf("Unexpected webhook payload %v", bodies[1])
+	}
+
+	if err := NewChatChannel(cfg).Send(context.Background(), Recipient{Preferences: &models.NotificationPreferences{}}, n); err != ErrNoAddress {
+		t.Errorf("Expected ErrNoAddress without a chat webhook, got %v", err)
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cfg := Config{AppURL: "https://dashboard.example.jp/"}
+	n := &models.Notification{ID: primitive.NewObjectID(), Title: "New task"}
+	for _, target := range []string{server.URL, "http://10.0.0.1/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]/hook", "http://0.0.0.0/hook"} {
+		to := Recipient{Preferences: &models.NotificationPreferences{WebhookURL: target}}
+		if err := NewWebhookChannel(cfg).Send(context.Background(), to, n); !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("Expected %s to be refused, got %v", target, err)
+		}
+	}
+
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		if err := refuseNonPublic("tcp", net.JoinHostPort(ip, "443"), nil); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", ip, err)
+		}
+	}
+}
+
+func TestWebhookClientDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// Reuse the channel's redirect policy with a transport that may reach the test server
+	client := server.Client()
+	client.CheckRedirect = publicClient().CheckRedirect
+	if err := postJSON(context.Background(), client, server.URL, map[string]string{}); err == nil || followed {
+		t.Errorf("Expected the redirect to fail without being followed, got %v", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	from := &mail.Address{Name: "Dashboard", Address: "dashboard@example.jp"}
+	to := &models.User{FullName: "Hanako", Email: "hanako@example.jp"}
+	n := &models.Notification{Title: "練習変更", Body: "明日の練習は18時から", CreatedAt: time.Now()}
+
+	msg, err := emailMessage(from, to, n, "https://dashboard.example.jp/events/1")
+	if err != nil {
+		t.Fatalf("Error building message: %v", err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(msg)))
+	if err != nil {
+		t.Fatalf("Error parsing message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != n.Title {
+		t.Errorf("Expected the subject %q, got %q", n.Title, subject)
+	}
+	if parsed.Header.Get("To") != `"Hanako" <hanako@example.jp>` {
+		t.Errorf("Unexpected recipient %q", parsed.Header.Get("To"))
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		24 * time.Hour:   "1 day",
+		48 * time.Hour:   "2 days",
+		time.Hour:        "1h",
+		90 * time.Minute: "1h30m",
+		30 * time.Minute: "30m",
+	} {
+		if got := humanDuration(d); got != want {
+			t.Errorf("humanDuration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// timeLayout formats times in notification text
+const timeLayout = "2006-01-02 15:04"
+
+// TaskAssigned notifies the assignees of a task, except the member who assigned it
+func (s *Service) TaskAssigned(ctx context.Context, task *models.Task, assignees []primitive.ObjectID, actorID primitive.ObjectID) error {
+	body := task.Title
+	if task.DueDate != nil {
+		body += "\nDue " + task.DueDate.Format(timeLayout)
+	}
+
+	var errs []error
+	for _, userID := range assignees {
+		if userID.IsZero() || userID == actorID {
+			continue
+		}
+		errs = append(errs, s.Notify(ctx, &models.Notification{
+			UserID: userID,
+			Type:   models.NotificationTaskAssigned,
+			Title:  "New task: " + task.Title,
+			Body:   body,
+			Link:   "/tasks/" + task.ID.Hex(),
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// EventChanged notifies the attendees of an event that was created, updated or deleted,
+// except the member who changed it
+func (s *Service) EventChanged(ctx context.Context, event *models.Event, action realtime.Action, actorID primitive.ObjectID) error {
+	var title string
+	switch action {
+	case realtime.ActionCreated:
+		title = "New event: " + event.Title
+	case realtime.ActionDeleted:
+		title = "Cancelled: " + event.Title
+	default:
+		title = "Changed: " + event.Title
+	}
+
+	link := "/events/" + event.ID.Hex()
+	if action == realtime.ActionDeleted {
+		link = ""
+	}
+
+	var errs []error
+	for _, attendee := range event.Attendees {
+		if attendee == actorID {
+			continue
+		}
+		errs = append(errs, s.Notify(ctx, &models.Notification{
+			UserID: attendee,
+			Type:   models.NotificationEventChanged,
+			Title:  title,
+			Body:   event.Title + "\n" + event.StartTime.Format(timeLayout) + " - " + event.EndTime.Format(timeLayout),
+			Link:   link,
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// EventReminder reminds members of an event that starts in about offset
+func (s *Service) EventReminder(ctx context.Context, event *models.Event, recipients []primitive.ObjectID, offset time.Duration) error {
+	var errs []error
+	for _, userID := range recipients {
+		errs = append(errs, s.Notify(ctx, &models.Notification{
+			UserID: userID,
+			Type:   models.NotificationEventReminder,
+			Title:  "Reminder: " + event.Title,
+			Body:   fmt.Sprintf("%s starts in %s, at %s", event.Title, humanDuration(offset), event.StartTime.Format(timeLayout)),
+			Link:   "/events/" + event.ID.Hex(),
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// TaskDueReminder reminds the assignees of an open task that is due in about offset
+func (s *Service) TaskDueReminder(ctx context.Context, task *models.Task, recipients []primitive.ObjectID, offset time.Duration) error {
+	if task.DueDate == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, userID := range recipients {
+		if userID.IsZero() {
+			continue
+		}
+		errs = append(errs, s.Notify(ctx, &models.Notification{
+			UserID: userID,
+			Type:   models.NotificationTaskDue,
+			Title:  "Due soon: " + task.Title,
+			Body:   fmt.Sprintf("%s is due in %s, at %s", task.Title, humanDuration(offset), task.DueDate.Format(timeLayout)),
+			Link:   "/tasks/" + task.ID.Hex(),
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// humanDuration formats a reminder offset such as 24h or 90m as "1 day" or "1h30m"
+func humanDuration(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d == day:
+		return "1 day"
+	case d%day == 0:
+		return fmt.Sprintf("%d days", d/day)
+	}
+
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
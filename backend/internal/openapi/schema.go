@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// File is a binary upload in a multipart form
+type File []byte
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	fileType     = reflect.TypeOf(File(nil))
+)
+
+// schemas derives JSON schemas from Go types and collects named structs as components
+type schemas struct {
+	components map[string]*Schema
+}
+
+// ref returns the schema of a value of type t, referring to named structs by component
+func (s *schemas) ref(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.ref(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.ref(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := componentName(t)
+		if _, ok := s.components[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// object returns the schema of a struct from its json and validate tags
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened, as encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := s.object(indirect(field.Type))
+			for k, v := range embedded.Properties {
+				schema.Properties[k] = v
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := s.ref(field.Type)
+		if field.Type.Kind() == reflect.Pointer && prop.Ref == "" {
+			prop.Nullable = true
+		}
+		if applyValidate(prop, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+	return schema
+}
+
+// applyValidate adds the constraints of validate rules to a property and reports whether it is required.
+// Rules that compare fields or apply to slice elements cannot be expressed and are left out.
+func applyValidate(prop *Schema, rules string) (required bool) {
+	if rules == "" {
+		return false
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "dive" {
+			break
+		}
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "email":
+			prop.Format = "email"
+		case "url":
+			prop.Format = "uri"
+		case "oneof":
+			prop.Enum = strings.Fields(value)
+		case "min", "max", "len":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			setBound(prop, key, n)
+		}
+	}
+	return required
+}
+
+// setBound applies a min, max or len rule as a length, item count or value bound depending on the type
+func setBound(prop *Schema, rule string, n int) {
+	lower, upper := rule != "max", rule != "min"
+	switch prop.Type {
+	case "string":
+		if lower {
+			prop.MinLength = &n
+		}
+		if upper {
+			prop.MaxLength = &n
+		}
+	case "array":
+		if lower {
+			prop.MinItems = &n
+		}
+		if upper {
+			prop.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if lower {
+			prop.Minimum = &f
+		}
+		if upper {
+			prop.Maximum = &f
+		}
+	}
+}
+
+// componentName names a struct's component schema by its type name.
+// Type arguments of generic types are appended, so ListResult[*models.User] becomes ListResultUser.
+func componentName(t reflect.Type) string {
+	name, args, ok := strings.Cut(t.Name(), "[")
+	if !ok {
+		return name
+	}
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		name += strings.TrimLeft(arg[strings.LastIndex(arg, ".")+1:], "*[]")
+	}
+	return name
+}
+
+// indirect returns the type a pointer type points to
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
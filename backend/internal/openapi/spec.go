@@ -0,0 +1,233 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Security scheme names that operations can require
+const (
+	BearerAuth = "bearerAuth"
+	APIKeyAuth = "apiKeyAuth"
+)
+
+// pathParam matches Echo path parameters such as :id
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error" validate:"required"`
+}
+
+// Operation documents a registered route
+type Operation struct {
+	// ID overrides the operation ID, which is derived from the handler's method name by default
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	// Security lists the schemes any of which authenticates the request; public routes leave it empty
+	Security []string
+	Query    []Param
+	// Body is a value of the JSON request body type
+	Body interface{}
+	// Form is a value of a struct whose fields are the multipart form fields
+	Form      interface{}
+	Responses []Response
+}
+
+// Param documents a query parameter
+type Param struct {
+	Name        string
+	Description string
+	// Type is the JSON type of the value; it defaults to string
+	Type string
+	Enum []string
+}
+
+// Response documents one possible response of an operation
+type Response struct {
+	Status      int
+	Description string
+	// Body is a value of the response body type; nil for responses without a body
+	Body         interface{}
+	ContentTypes []string
+	Headers      map[string]string
+}
+
+// JSON documents a response with a JSON body of body's type
+func JSON(status int, description string, body interface{}) Response {
+	return Response{Status: status, Description: description, Body: body, ContentTypes: []string{echo.MIMEApplicationJSON}}
+}
+
+// Empty documents a response without a body
+func Empty(status int, description string) Response {
+	return Response{Status: status, Description: description}
+}
+
+// Binary documents a response with a file body in one of contentTypes
+func Binary(status int, description string, contentTypes ...string) Response {
+	return Response{Status: status, Description: description, Body: File(nil), ContentTypes: contentTypes}
+}
+
+// Error documents an error response
+func Error(status int, description string) Response {
+	return JSON(status, description, ErrorResponse{})
+}
+
+// Spec collects the operations of the registered routes into an OpenAPI document
+type Spec struct {
+	doc     Document
+	schemas schemas
+	ids     map[string]bool
+	hidden  map[string]bool
+
+	once sync.Once
+	json []byte
+}
+
+// New creates an empty spec
+func New(info Info) *Spec {
+	s := &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   map[string]*PathItem{},
+			Components: Components{
+				SecuritySchemes: map[string]*SecurityScheme{
+					BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+					APIKeyAuth: {Type: "apiKey", In: "header", Name: echo.HeaderAuthorization,
+						Description: `An API key sent as "ApiKey <key>"; only routes that allow one of the key's scopes accept it`},
+				},
+			},
+		},
+		schemas: schemas{components: map[string]*Schema{}},
+		ids:     map[string]bool{},
+		hidden:  map[string]bool{},
+	}
+	s.doc.Components.Schemas = s.schemas.components
+	return s
+}
+
+// Add documents a registered route. It panics if the operation ID is already taken,
+// since that can only come from a mistake in the route table.
+func (s *Spec) Add(route *echo.Route, op Operation) {
+	id := op.ID
+	if id == "" {
+		id = operationID(route.Name)
+	}
+	if id == "" || s.ids[id] {
+		panic(fmt.Sprintf("openapi: %s %s needs a unique operation ID, got %q", route.Method, route.Path, id))
+	}
+	s.ids[id] = true
+
+	obj := &OperationObject{
+		OperationID: id,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Responses:   map[string]*ResponseObject{},
+	}
+
+	for _, m := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+		obj.Parameters = append(obj.Parameters, ParameterObject{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, p := range op.Query {
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		obj.Parameters = append(obj.Parameters, ParameterObject{
+			Name: p.Name, In: "query", Description: p.Description, Schema: &Schema{Type: typ, Enum: p.Enum},
+		})
+	}
+
+	switch {
+	case op.Body != nil:
+		obj.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			echo.MIMEApplicationJSON: {Schema: s.schemas.ref(reflect.TypeOf(op.Body))},
+		}}
+	case op.Form != nil:
+		obj.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			echo.MIMEMultipartForm: {Schema: s.schemas.object(indirect(reflect.TypeOf(op.Form)))},
+		}}
+	}
+
+	for _, r := range op.Responses {
+		resp := &ResponseObject{Description: r.Description}
+		if r.Body != nil {
+			resp.Content = map[string]MediaType{}
+			schema := s.schemas.ref(reflect.TypeOf(r.Body))
+			for _, ct := range r.ContentTypes {
+				resp.Content[ct] = MediaType{Schema: schema}
+			}
+		}
+		for name, desc := range r.Headers {
+			if resp.Headers == nil {
+				resp.Headers = map[string]HeaderObject{}
+			}
+			resp.Headers[name] = HeaderObject{Description: desc, Schema: &Schema{Type: "string"}}
+		}
+		obj.Responses[strconv.Itoa(r.Status)] = resp
+	}
+
+	for _, scheme := range op.Security {
+		obj.Security = append(obj.Security, map[string][]string{scheme: {}})
+	}
+
+	path := pathParam.ReplaceAllString(route.Path, "{$1}")
+	item, ok := s.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		s.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(route.Method)] = obj
+}
+
+// Hide marks a registered route as deliberately left out of the document, such as static assets
+func (s *Spec) Hide(route *echo.Route) {
+	s.hidden[route.Method+" "+route.Path] = true
+}
+
+// Covers reports whether a route is documented or hidden
+func (s *Spec) Covers(method, path string) bool {
+	if s.hidden[method+" "+path] {
+		return true
+	}
+	item, ok := s.doc.Paths[pathParam.ReplaceAllString(path, "{$1}")]
+	if !ok {
+		return false
+	}
+	_, ok = (*item)[strings.ToLower(method)]
+	return ok
+}
+
+// Handler serves the document as JSON. It is encoded on the first request,
+// after every route has been registered.
+func (s *Spec) Handler(c echo.Context) error {
+	s.once.Do(func() {
+		s.json, _ = json.Marshal(s.doc)
+	})
+	return c.JSONBlob(http.StatusOK, s.json)
+}
+
+// operationID derives an operation ID from an Echo route name, which is the
+// handler's function name, such as handlers.(*UserHandler).GetMe-fm
+func operationID(handlerName string) string {
+	name := strings.TrimSuffix(handlerName[strings.LastIndex(handlerName, ".")+1:], "-fm")
+	if name == "" || strings.HasPrefix(name, "func") {
+		return ""
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
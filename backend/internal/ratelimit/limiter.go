@@ -0,0 +1,141 @@
+// Package ratelimit throttles repeated failed login attempts
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Policy describes how failed attempts for a key are throttled
+type Policy struct {
+	// FreeAttempts is the number of failures allowed before backoff starts
+	FreeAttempts int `yaml:"freeAttempts"`
+	// BaseDelay is the wait after the first failure beyond FreeAttempts; it doubles with every further failure
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	// MaxDelay caps the backoff delay
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	// MaxFailures is the number of failures that locks the key
+	MaxFailures int `yaml:"maxFailures"`
+	// LockoutDuration is how long a locked key stays locked
+	LockoutDuration time.Duration `yaml:"lockoutDuration"`
+	// Window is how long after the last failure the failure count is forgotten
+	Window time.Duration `yaml:"window"`
+}
+
+// Validate checks that the policy values are usable
+func (p Policy) Validate() error {
+	switch {
+	case p.FreeAttempts < 0:
+		return errors.New("freeAttempts must not be negative")
+	case p.MaxFailures <= p.FreeAttempts:
+		return errors.New("maxFailures must be greater than freeAttempts")
+	case p.BaseDelay <= 0 || p.MaxDelay < p.BaseDelay:
+		return errors.New("baseDelay must be positive and not exceed maxDelay")
+	case p.LockoutDuration <= 0:
+		return errors.New("lockoutDuration must be positive")
+	case p.Window <= 0:
+		return errors.New("window must be positive")
+	}
+	return nil
+}
+
+// DefaultUserPolicy throttles attempts against a single username
+var DefaultUserPolicy = Policy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	MaxFailures:     10,
+	LockoutDuration: 30 * time.Minute,
+	Window:          time.Hour,
+}
+
+// DefaultIPPolicy throttles attempts from a single client address.
+// It is looser than the user policy because a whole school can share one address.
+var DefaultIPPolicy = Policy{
+	FreeAttempts:    20,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	MaxFailures:     100,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// Status is the throttling state of a key after a failure was recorded
+type Status struct {
+	Failures   int
+	RetryAfter time.Duration
+	Locked     bool
+	// NewlyLocked is set when this failure caused the lockout
+	NewlyLocked bool
+}
+
+// Limiter tracks failed attempts per key
+type Limiter interface {
+	// Check returns how long the caller must wait before key may be tried again
+	Check(ctx context.Context, key string) (time.Duration, error)
+	// RecordFailure counts a failed attempt for key
+	RecordFailure(ctx context.Context, key string) (Status, error)
+	// Reset forgets all failures for key, lifting any lockout
+	Reset(ctx context.Context, key string) error
+}
+
+// record is the stored state of a key
+type record struct {
+	Failures     int       `bson:"failures"`
+	LastFailure  time.Time `bson:"lastFailure"`
+	BlockedUntil time.Time `bson:"blockedUntil"`
+	Locked       bool      `bson:"locked"`
+}
+
+// fail applies a failure at now to the record and returns the resulting status.
+// Records start over once a lockout has expired or the window has passed.
+func (p Policy) fail(r *record, now time.Time) Status {
+	if !now.Before(r.BlockedUntil) && (r.Locked || now.Sub(r.LastFailure) > p.Window) {
+		*r = record{}
+	}
+
+	r.Failures++
+	r.LastFailure = now
+	newlyLocked := p.block(r, now)
+
+	s := r.status(now)
+	s.NewlyLocked = newlyLocked
+	return s
+}
+
+// block sets how long the record is blocked given its failure count.
+// It reports whether the record became locked.
+func (p Policy) block(r *record, now time.Time) bool {
+	switch {
+	case r.Failures >= p.MaxFailures:
+		wasLocked := r.Locked
+		r.Locked = true
+		r.BlockedUntil = later(r.BlockedUntil, now.Add(p.LockoutDuration))
+		return !wasLocked
+	case r.Failures > p.FreeAttempts:
+		delay := p.BaseDelay << (r.Failures - p.FreeAttempts - 1)
+		if delay > p.MaxDelay || delay <= 0 {
+			delay = p.MaxDelay
+		}
+		r.BlockedUntil = later(r.BlockedUntil, now.Add(delay))
+	}
+	return false
+}
+
+// status reports the record's state at now
+func (r record) status(now time.Time) Status {
+	s := Status{Failures: r.Failures, Locked: r.Locked && now.Before(r.BlockedUntil)}
+	if now.Before(r.BlockedUntil) {
+		s.RetryAfter = r.BlockedUntil.Sub(now)
+	}
+	return s
+}
+
+// later returns the later of two times
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
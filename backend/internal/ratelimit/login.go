@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+)
+
+// LoginThrottle combines per-username and per-IP limiters for the login endpoint
+type LoginThrottle struct {
+	users Limiter
+	ips   Limiter
+}
+
+// NewLoginThrottle creates a new LoginThrottle
+func NewLoginThrottle(users, ips Limiter) *LoginThrottle {
+	return &LoginThrottle{
+		users: users,
+		ips:   ips,
+	}
+}
+
+// Check returns how long the client must wait before trying this username again
+func (t *LoginThrottle) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	userWait, err := t.users.Check(ctx, userKey(username))
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait, err := t.ips.Check(ctx, ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	if ipWait > userWait {
+		return ipWait, nil
+	}
+	return userWait, nil
+}
+
+// Failure records a failed login for the username and client address
+func (t *LoginThrottle) Failure(ctx context.Context, username, ip string) error {
+	userStatus, err := t.users.RecordFailure(ctx, userKey(username))
+	if err != nil {
+		return err
+	}
+	if userStatus.NewlyLocked {
+		logging.FromContext(ctx).Warn("account locked after failed logins",
+			"username", username, "locked_for", userStatus.RetryAfter.Round(time.Second).String(), "failures", userStatus.Failures, "remote_ip", ip)
+	}
+
+	ipStatus, err := t.ips.RecordFailure(ctx, ipKey(ip))
+	if err != nil {
+		return err
+	}
+	if ipStatus.NewlyLocked {
+		logging.FromContext(ctx).Warn("client locked out of login after failed logins",
+			"remote_ip", ip, "locked_for", ipStatus.RetryAfter.Round(time.Second).String(), "failures", ipStatus.Failures)
+	}
+
+	return nil
+}
+
+// Success clears the failures for a username after a successful login.
+// Failures from the client address are kept so one valid account cannot be
+// used to reset the throttle while guessing others.
+func (t *LoginThrottle) Success(ctx context.Context, username string) error {
+	return t.users.Reset(ctx, userKey(username))
+}
+
+// Unlock lifts a lockout on a username
+func (t *LoginThrottle) Unlock(ctx context.Context, username string) error {
+	if err := t.users.Reset(ctx, userKey(username)); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("account unlocked", "username", username)
+	return nil
+}
+
+// userKey returns the limiter key for a username
+func userKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// ipKey returns the limiter key for a client address
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
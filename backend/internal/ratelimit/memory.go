@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter implements Limiter in process memory.
+// It is suitable for a single replica; use MongoLimiter when running several.
+type MemoryLimiter struct {
+	policy    Policy
+	now       func() time.Time
+	mu        sync.Mutex
+	records   map[string]*record
+	lastPrune time.Time
+}
+
+// NewMemoryLimiter creates a new MemoryLimiter
+func NewMemoryLimiter(policy Policy) *MemoryLimiter {
+	return &MemoryLimiter{
+		policy:  policy,
+		now:     time.Now,
+		records: map[string]*record{},
+	}
+}
+
+// Check returns how long the caller must wait before key may be tried again
+func (l *MemoryLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, ok := l.records[key]
+	if !ok {
+		return 0, nil
+	}
+	return r.status(l.now()).RetryAfter, nil
+}
+
+// RecordFailure counts a failed attempt for key
+func (l *MemoryLimiter) RecordFailure(ctx context.Context, key string) (Status, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	r, ok := l.records[key]
+	if !ok {
+		r = &record{}
+		l.records[key] = r
+	}
+	return l.policy.fail(r, now), nil
+}
+
+// Reset forgets all failures for key, lifting any lockout
+func (l *MemoryLimiter) Reset(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.records, key)
+	return nil
+}
+
+// prune drops records that no longer affect throttling. It runs at most once per window.
+func (l *MemoryLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < l.policy.Window {
+		return
+	}
+	l.lastPrune = now
+
+	for key, r := range l.records {
+		if now.Sub(r.LastFailure) > l.policy.Window && !now.Before(r.BlockedUntil) {
+			delete(l.records, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts:    2,
+	BaseDelay:       time.Second,
+	MaxDelay:        4 * time.Second,
+	MaxFailures:     6,
+	LockoutDuration: 10 * time.Minute,
+	Window:          time.Hour,
+}
+
+func newTestLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.Date(2025, 4, 1, 18, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter(testPolicy)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestMemoryLimiterBackoff(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLimiter()
+
+	// Delays double after the free attempts and are capped at MaxDelay
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+	for i, delay := range want {
+		status, err := l.RecordFailure(ctx, "user:hanako")
+		if err != nil {
+			t.Fatalf("Error recording failure: %v", err)
+		}
+		if status.RetryAfter != delay {
+			t.Errorf("Failure %d: expected delay %s, got %s", i+1, delay, status.RetryAfter)
+		}
+		if status.Locked {
+			t.Errorf("Failure %d: locked too early", i+1)
+		}
+	}
+
+	wait, _ := l.Check(ctx, "user:hanako")
+	if wait != 4*time.Second {
+		t.Errorf("Expected Check to report 4s, got %s", wait)
+	}
+
+	if wait, _ := l.Check(ctx, "user:taro"); wait != 0 {
+		t.Errorf("Other keys should not be throttled, got %s", wait)
+	}
+}
+
+func TestMemoryLimiterLockout(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+
+	var status Status
+	for i := 0; i < testPolicy.MaxFailures; i++ {
+		status, _ = l.RecordFailure(ctx, "user:hanako")
+	}
+
+	if !status.Locked || !status.NewlyLocked {
+		t.Fatalf("Expected key to be newly locked, got %+v", status)
+	}
+	if status.RetryAfter != testPolicy.LockoutDuration {
+		t.Errorf("Expected lockout of %s, got %s", testPolicy.LockoutDuration, status.RetryAfter)
+	}
+
+	// After the lockout expires the key starts over
+	*now = now.Add(testPolicy.LockoutDuration)
+	status, _ = l.RecordFailure(ctx, "user:hanako")
+	if status.Failures != 1 || status.Locked {
+		t.Errorf("Expected a fresh record after lockout, got %+v", status)
+	}
+}
+
+func TestMemoryLimiterReset(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLimiter()
+
+	for i := 0; i < testPolicy.MaxFailures; i++ {
+		l.RecordFailure(ctx, "user:hanako")
+	}
+
+	if err := l.Reset(ctx, "user:hanako"); err != nil {
+		t.Fatalf("Error resetting: %v", err)
+	}
+
+	if wait, _ := l.Check(ctx, "user:hanako"); wait != 0 {
+		t.Errorf("Expected no wait after reset, got %s", wait)
+	}
+}
+
+func TestMemoryLimiterWindow(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+
+	for i := 0; i < testPolicy.FreeAttempts+1; i++ {
+		l.RecordFailure(ctx, "ip:203.0.113.7")
+	}
+
+	*now = now.Add(testPolicy.Window + time.Minute)
+	status, _ := l.RecordFailure(ctx, "ip:203.0.113.7")
+	if status.Failures != 1 {
+		t.Errorf("Expected failures to be forgotten after the window, got %d", status.Failures)
+	}
+}
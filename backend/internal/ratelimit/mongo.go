@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoLimiter implements Limiter on a MongoDB collection so that every
+// replica sees the same failure counts
+type MongoLimiter struct {
+	policy     Policy
+	now        func() time.Time
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewMongoLimiter creates a new MongoLimiter storing its state in the named collection.
+// Documents carry an expiresAt field that a TTL index can use to clean them up.
+func NewMongoLimiter(client *mongo.Client, db, collection string, policy Policy) *MongoLimiter {
+	return &MongoLimiter{
+		policy:     policy,
+		now:        time.Now,
+		db:         db,
+		collection: collection,
+		client:     client,
+	}
+}
+
+// Check returns how long the caller must wait before key may be tried again
+func (l *MongoLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, l.collection, "Check")
+	defer end()
+
+	coll := l.client.Database(l.db).Collection(l.collection)
+
+	var r record
+	err := coll.FindOne(ctx, bson.M{"_id": key}).Decode(&r)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return r.status(l.now()).RetryAfter, nil
+}
+
+// RecordFailure counts a failed attempt for key.
+// The counter is incremented atomically; the resulting block is then applied
+// with $max so concurrent failures on other replicas can only extend it.
+func (l *MongoLimiter) RecordFailure(ctx context.Context, key string) (Status, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, l.collection, "RecordFailure")
+	defer end()
+
+	coll := l.client.Database(l.db).Collection(l.collection)
+	now := l.now()
+	epoch := time.Unix(0, 0)
+
+	// A stale record starts over, mirroring Policy.fail
+	stale := bson.M{"$and": bson.A{
+		bson.M{"$lte": bson.A{bson.M{"$ifNull": bson.A{"$blockedUntil", epoch}}, now}},
+		bson.M{"$or": bson.A{
+			bson.M{"$eq": bson.A{"$locked", true}},
+			bson.M{"$gt": bson.A{bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$lastFailure", epoch}}}}, l.policy.Window.Milliseconds()}},
+		}},
+	}}
+
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures":     bson.M{"$cond": bson.A{stale, 1, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}}}},
+		"locked":       bson.M{"$cond": bson.A{stale, false, bson.M{"$ifNull": bson.A{"$locked", false}}}},
+		"blockedUntil": bson.M{"$cond": bson.A{stale, epoch, bson.M{"$ifNull": bson.A{"$blockedUntil", epoch}}}},
+		"lastFailure":  now,
+	}}}}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var r record
+	if err := coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&r); err != nil {
+		return Status{}, err
+	}
+
+	newlyLocked := l.policy.block(&r, now)
+
+	set := bson.M{}
+	if r.Locked {
+		set["locked"] = true
+	}
+	max := bson.M{
+		"blockedUntil": r.BlockedUntil,
+		"expiresAt":    later(r.BlockedUntil, now.Add(l.policy.Window)),
+	}
+
+	change := bson.M{"$max": max}
+	if len(set) > 0 {
+		change["$set"] = set
+	}
+	if _, err := coll.UpdateOne(ctx, bson.M{"_id": key}, change); err != nil {
+		return Status{}, err
+	}
+
+	status := r.status(now)
+	status.NewlyLocked = newlyLocked
+	return status, nil
+}
+
+// Reset forgets all failures for key, lifting any lockout
+func (l *MongoLimiter) Reset(ctx context.Context, key string) error {
+	ctx, end := tracing.StartMongoOperation(ctx, l.collection, "Reset")
+	defer end()
+
+	coll := l.client.Database(l.db).Collection(l.collection)
+
+	_, err := coll.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
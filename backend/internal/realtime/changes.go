@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskChange describes a change to a task, which is seen by its creator and assignees.
+// Tasks assigned to everyone are seen by everyone; for sections and roles, pass the members.
+func TaskChange(action Action, task *models.Task, members ...primitive.ObjectID) Change {
+	if task.AssigneeType == models.TaskAssigneeEveryone {
+		c := newChange(TopicTasks, action, task.ID, task, nil)
+		c.Public = true
+		return c
+	}
+	return newChange(TopicTasks, action, task.ID, task, ids(append([]primitive.ObjectID{task.AssignedTo, task.CreatedBy}, members...)...))
+}
+
+// NotificationChange describes a new notification, which only its recipient sees, even when others are admins
+func NotificationChange(notification *models.Notification) Change {
+	c := newChange(TopicNotifications, ActionCreated, notification.ID, notification, ids(notification.UserID))
+	c.Private = true
+	return c
+}
+
+// newChange builds a change, leaving the document out of deletions
+func newChange(topic Topic, action Action, id primitive.ObjectID, data interface{}, audience []string) Change {
+	c := Change{Topic: topic, Action: action, ID: id.Hex(), Time: time.Now(), Audience: audience}
+	if action != ActionDeleted {
+		c.Data = data
+	}
+	return c
+}
+
+// ids converts object IDs to the hex strings access tokens carry as subjects
+func ids(objectIDs ...primitive.ObjectID) []string {
+	out := make([]string, 0, len(objectIDs))
+	for _, id := range objectIDs {
+		if !id.IsZero() {
+			out = append(out, id.Hex())
+		}
+	}
+	return out
+}
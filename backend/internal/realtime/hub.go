@@ -0,0 +1,101 @@
+// Package realtime delivers changes to tasks and new notifications to connected clients
+package realtime
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+)
+
+// Topic is a kind of document clients can follow
+type Topic string
+
+// Events, practice menus and attendance have no API that changes them yet, so they have no topics.
+const (
+	TopicTasks Topic = "tasks"
+	// TopicNotifications carries new inbox notifications to their recipient
+	TopicNotifications Topic = "notifications"
+)
+
+// Topics lists every topic, in the order clients see them documented
+var Topics = []Topic{TopicTasks, TopicNotifications}
+
+// Action is what happened to a document
+type Action string
+
+const (
+	ActionCreated Action = "created"
+	ActionUpdated Action = "updated"
+	ActionDeleted Action = "deleted"
+)
+
+// ErrClosed is returned when subscribing to a hub that has been closed
+var ErrClosed = errors.New("realtime: hub closed")
+
+// Change is a change to a document that clients following its topic are told about
+type Change struct {
+	Topic  Topic  `json:"topic"`
+	Action Action `json:"action"`
+	ID     string `json:"id"`
+	// Data is the document after the change; it is omitted for deletions
+	Data interface{} `json:"data,omitempty"`
+	Time time.Time   `json:"time"`
+	// Audience lists the IDs of the users who may see the change. Admins see every change,
+	// so a change with an empty audience is seen by admins only.
+	Audience []string `json:"-"`
+	// Public changes are seen by every user, whatever their audience
+	Public bool `json:"-"`
+	// Private changes are only seen by their audience, admins included
+	Private bool `json:"-"`
+}
+
+// VisibleTo reports whether a user may see the change
+func (c Change) VisibleTo(userID string, role models.Role) bool {
+	if (role == models.AdminRole && !c.Private) || c.Public {
+		return true
+	}
+	for _, id := range c.Audience {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects the changes a subscriber receives
+type Filter struct {
+	UserID string
+	Role   models.Role
+	// Topics limits the subscription to some topics; empty means every topic
+	Topics []Topic
+}
+
+// Matches reports whether a change passes the filter
+func (f Filter) Matches(c Change) bool {
+	if !c.VisibleTo(f.UserID, f.Role) {
+		return false
+	}
+	if len(f.Topics) == 0 {
+		return true
+	}
+	for _, t := range f.Topics {
+		if t == c.Topic {
+			return true
+		}
+	}
+	return false
+}
+
+// Hub fans changes out to subscribers
+type Hub interface {
+	// Publish delivers a change to every matching subscriber without blocking on slow ones
+	Publish(ctx context.Context, change Change) error
+	// Subscribe returns a channel of the changes that match filter. The channel is closed
+	// when ctx is done, when the hub is closed, or when the subscriber falls too far behind,
+	// after which the client should reload what it shows.
+	Subscribe(ctx context.Context, filter Filter) (<-chan Change, error)
+	// Close ends every subscription
+	Close() error
+}
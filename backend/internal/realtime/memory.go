@@ -0,0 +1,88 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriptionBuffer is how many changes a subscriber may fall behind before it is dropped
+const subscriptionBuffer = 64
+
+// subscription is a subscriber of a MemoryHub
+type subscription struct {
+	filter Filter
+	ch     chan Change
+}
+
+// MemoryHub implements Hub in process memory.
+// Changes only reach clients connected to the replica that published them; a hub fed by
+// MongoDB change streams can take its place when running several.
+type MemoryHub struct {
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+// NewMemoryHub creates a new MemoryHub
+func NewMemoryHub() *MemoryHub {
+	return &MemoryHub{subs: map[*subscription]struct{}{}}
+}
+
+// Publish delivers a change to every matching subscriber without blocking on slow ones
+func (h *MemoryHub) Publish(ctx context.Context, change Change) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subs {
+		if !s.filter.Matches(change) {
+			continue
+		}
+		select {
+		case s.ch <- change:
+		default:
+			// The subscriber missed a change, so it has to start over
+			h.remove(s)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel of the changes that match filter
+func (h *MemoryHub) Subscribe(ctx context.Context, filter Filter) (<-chan Change, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	s := &subscription{filter: filter, ch: make(chan Change, subscriptionBuffer)}
+	h.subs[s] = struct{}{}
+	context.AfterFunc(ctx, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(s)
+	})
+	return s.ch, nil
+}
+
+// Close ends every subscription
+func (h *MemoryHub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subs {
+		h.remove(s)
+	}
+	return nil
+}
+
+// remove ends a subscription if it is still active. The caller must hold mu.
+func (h *MemoryHub) remove(s *subscription) {
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+	delete(h.subs, s)
+	close(s.ch)
+}
@@ -0,0 +1,130 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// receive returns the next change on ch, or fails if none arrives
+func receive(t *testing.T, ch <-chan Change) Change {
+	t.Helper()
+	select {
+	case c, ok := <-ch:
+		if !ok {
+			t.Fatal("Subscription closed unexpectedly")
+		}
+		return c
+	case <-time.After(time.Second):
+		t.Fatal("Expected a change")
+	}
+	return Change{}
+}
+
+// assertEmpty fails if a change is waiting on ch
+func assertEmpty(t *testing.T, ch <-chan Change) {
+	t.Helper()
+	select {
+	case c := <-ch:
+		t.Fatalf("Expected no change, got %s %s", c.Topic, c.ID)
+	default:
+	}
+}
+
+func TestMemoryHubFiltersByAudienceAndTopic(t *testing.T) {
+	ctx := context.Background()
+	hub := NewMemoryHub()
+
+	assignee, other := primitive.NewObjectID(), primitive.NewObjectID()
+	assigneeCh, _ := hub.Subscribe(ctx, Filter{UserID: assignee.Hex(), Role: models.GeneralRole})
+	otherCh, _ := hub.Subscribe(ctx, Filter{UserID: other.Hex(), Role: models.GeneralRole})
+	adminCh, _ := hub.Subscribe(ctx, Filter{UserID: primitive.NewObjectID().Hex(), Role: models.AdminRole})
+	notificationsCh, _ := hub.Subscribe(ctx, Filter{UserID: assignee.Hex(), Role: models.GeneralRole, Topics: []Topic{TopicNotifications}})
+
+	task := &models.Task{ID: primitive.NewObjectID(), AssignedTo: assignee, CreatedBy: primitive.NewObjectID()}
+	hub.Publish(ctx, TaskChange(ActionUpdated, task))
+
+	if c := receive(t, assigneeCh); c.Topic != TopicTasks || c.ID != task.ID.Hex() || c.Data == nil {
+		t.Errorf("Expected the task change, got %+v", c)
+	}
+	receive(t, adminCh)
+	assertEmpty(t, otherCh)
+	assertEmpty(t, notificationsCh)
+
+	hub.Publish(ctx, TaskChange(ActionDeleted, &models.Task{ID: primitive.NewObjectID(), AssigneeType: models.TaskAssigneeEveryone}))
+	for _, ch := range []<-chan Change{otherCh, adminCh} {
+		receive(t, ch)
+	}
+	if c := receive(t, assigneeCh); c.Data != nil {
+		t.Error("Expected deletions to leave out the document")
+	}
+	assertEmpty(t, notificationsCh)
+}
+
+func TestGroupTaskChangeAudience(t *testing.T) {
+	member, outsider := primitive.NewObjectID(), primitive.NewObjectID().Hex()
+
+	everyone := TaskChange(ActionCreated, &models.Task{ID: primitive.NewObjectID(), AssigneeType: models.TaskAssigneeEveryone})
+	if !everyone.VisibleTo(outsider, models.GeneralRole) {
+		t.Error("Expected tasks for everyone to be visible to every member")
+	}
+
+	section := TaskChange(ActionCreated, &models.Task{ID: primitive.NewObjectID(), AssigneeType: models.TaskAssigneeSection, AssignedSection: "Brass"}, member)
+	if !section.VisibleTo(member.Hex(), models.GeneralRole) || section.VisibleTo(outsider, models.GeneralRole) {
+		t.Error("Expected section tasks to be visible to the section's members only")
+	}
+}
+
+func TestChangeWithoutAudienceIsForAdminsOnly(t *testing.T) {
+	// A task whose assignee and creator are unknown has nobody in its audience
+	orphan := TaskChange(ActionUpdated, &models.Task{ID: primitive.NewObjectID()})
+	if orphan.VisibleTo(primitive.NewObjectID().Hex(), models.GeneralRole) {
+		t.Error("Expected a change without an audience to be hidden from members")
+	}
+	if !orphan.VisibleTo(primitive.NewObjectID().Hex(), models.AdminRole) {
+		t.Error("Expected admins to see a change without an audience")
+	}
+}
+
+func TestMemoryHubEndsSubscriptions(t *testing.T) {
+	hub := NewMemoryHub()
+
+	// Cancelling the subscriber's context closes its channel
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, _ := hub.Subscribe(ctx, Filter{})
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("Expected the channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the subscription to end with its context")
+	}
+
+	// A subscriber that falls behind is dropped instead of blocking publishers
+	slow, _ := hub.Subscribe(context.Background(), Filter{})
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish(context.Background(), TaskChange(ActionUpdated, &models.Task{AssigneeType: models.TaskAssigneeEveryone}))
+	}
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("Expected %d buffered changes before the subscription ended, got %d", subscriptionBuffer, received)
+	}
+
+	// Closing the hub ends every subscription and refuses new ones
+	active, _ := hub.Subscribe(context.Background(), Filter{})
+	hub.Close()
+	if _, ok := <-active; ok {
+		t.Error("Expected Close to end the subscription")
+	}
+	if _, err := hub.Subscribe(context.Background(), Filter{}); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+// Package recurring creates tasks from recurring task templates
+package recurring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/taskfeed"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Config configures how tasks are created from templates
+type Config struct {
+	// Enabled runs the generator; replicas may all run it since each due date creates one task
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often templates are checked for tasks to create
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Validate checks that the settings are usable
+func (c Config) Validate() error {
+	if c.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	return nil
+}
+
+// Notifier tells members about the tasks created for them
+type Notifier = taskfeed.Notifier
+
+// Generator periodically creates the tasks of templates whose lead time has come.
+// The tasks collection has a unique index on the template and due date, so a task that
+// was created by another replica, or before a crash, is not created twice. When the server
+// was down past several due dates, only the earliest task is created late and the rest are skipped.
+type Generator struct {
+	cfg       Config
+	templates repositories.TaskTemplateRepository
+	tasks     repositories.TaskRepository
+	feed      *taskfeed.Feed
+	now       func() time.Time
+}
+
+// NewGenerator creates a new Generator
+func NewGenerator(cfg Config, templates repositories.TaskTemplateRepository, tasks repositories.TaskRepository,
+	users repositories.UserRepository, hub realtime.Hub, notifier Notifier) *Generator {
+	return &Generator{
+		cfg:       cfg,
+		templates: templates,
+		tasks:     tasks,
+		feed:      taskfeed.New(users, hub, notifier),
+		now:       time.Now,
+	}
+}
+
+// Run creates due tasks every interval until ctx is done. Start it with a worker.Group.
+func (g *Generator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := g.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to create recurring tasks", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce creates the tasks whose templates are due now
+func (g *Generator) RunOnce(ctx context.Context) error {
+	now := g.now()
+	templates, err := g.templates.FindDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, template := range templates {
+		if err := g.generate(ctx, template, now); err != nil {
+			errs = append(errs, fmt.Errorf("template %s: %w", template.ID.Hex(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// generate creates the next task of a template and schedules the one after it
+func (g *Generator) generate(ctx context.Context, template *models.TaskTemplate, now time.Time) error {
+	dueAt := *template.NextDueAt
+	task := template.NewTask()
+	rank, err := repositories.RankAtEnd(ctx, g.tasks, task.Status)
+	if err != nil {
+		return err
+	}
+	task.Rank = rank
+
+	_, err = g.tasks.Create(ctx, task)
+	created := err == nil
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	template.LastDueAt = &dueAt
+	template.Schedule(now)
+	if _, err := g.templates.Advance(ctx, template, dueAt); err != nil {
+		return err
+	}
+
+	if created {
+		metrics.RecurringTasksCreated.Inc()
+		// Nobody acted, so the template's author is notified like any other assignee
+		g.feed.Created(ctx, task, primitive.NilObjectID)
+	}
+	return nil
+}
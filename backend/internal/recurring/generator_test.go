@@ -0,0 +1,145 @@
+package recurring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeTemplates struct {
+	repositories.TaskTemplateRepository
+	templates []*models.TaskTemplate
+}
+
+// FindDue returns copies, like documents read from the database
+func (f *fakeTemplates) FindDue(ctx context.Context, now time.Time) ([]*models.TaskTemplate, error) {
+	var due []*models.TaskTemplate
+	for _, t := range f.templates {
+		if t.Active && t.NextRunAt != nil && !t.NextRunAt.After(now) {
+			copied := *t
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeTemplates) Advance(ctx context.Context, template *models.TaskTemplate, dueAt time.Time) (bool, error) {
+	for _, t := range f.templates {
+		if t.ID == template.ID && t.NextDueAt != nil && t.NextDueAt.Equal(dueAt) {
+			t.NextDueAt, t.NextRunAt, t.LastDueAt = template.NextDueAt, template.NextRunAt, template.LastDueAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeTasks enforces the unique index on the template and due date
+type fakeTasks struct {
+	repositories.TaskRepository
+	tasks []*models.Task
+}
+
+func (f *fakeTasks) Create(ctx context.Context, task *models.Task) (string, error) {
+	for _, t := range f.tasks {
+		if *t.TemplateID == *task.TemplateID && t.DueDate.Equal(*task.DueDate) {
+			return "", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+		}
+	}
+	task.ID = primitive.NewObjectID()
+	f.tasks = append(f.tasks, task)
+	return task.ID.Hex(), nil
+}
+
+func (f *fakeTasks) LastRank(ctx context.Context, status models.TaskStatus) (string, error) {
+	last := ""
+	for _, t := range f.tasks {
+		if t.Status == status && t.Rank > last {
+			last = t.Rank
+		}
+	}
+	return last, nil
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+	users []*models.User
+}
+
+func (f *fakeUsers) List(ctx context.Context, filter models.UserFilter, query models.ListQuery) (*models.ListResult[*models.User], error) {
+	return &models.ListResult[*models.User]{Items: f.users, Total: int64(len(f.users))}, nil
+}
+
+type fakeNotifier struct{ notified int }
+
+func (f *fakeNotifier) TaskAssigned(ctx context.Context, task *models.Task, assignees []primitive.ObjectID, actorID primitive.ObjectID) error {
+	f.notified += len(assignees)
+	return nil
+}
+
+func TestGeneratorCreatesEachDueDateOnce(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 6, 7, 8, 0, 0, 0, time.UTC)
+	template := &models.TaskTemplate{
+		ID:           primitive.NewObjectID(),
+		Title:        "Instrument inventory",
+		AssigneeType: models.TaskAssigneeEveryone,
+		Recurrence:   models.TaskRecurrence{Frequency: models.TaskRecurrenceWeekly, StartsAt: start, Timezone: "UTC"},
+		LeadDays:     1,
+		Active:       true,
+	}
+	now := start.AddDate(0, 0, -3)
+	template.Schedule(now)
+
+	templates := &fakeTemplates{templates: []*models.TaskTemplate{template}}
+	tasks := &fakeTasks{}
+	notifier := &fakeNotifier{}
+	users := &fakeUsers{users: []*models.User{{ID: primitive.NewObjectID()}, {ID: primitive.NewObjectID()}}}
+	g := NewGenerator(Config{Interval: time.Minute}, templates, tasks, users, realtime.NewMemoryHub(), notifier)
+	g.now = func() time.Time { return now }
+
+	// Nothing is created before the lead time
+	if err := g.RunOnce(ctx); err != nil || len(tasks.tasks) != 0 {
+		t.Fatalf("Expected no task three days ahead, got %d (%v)", len(tasks.tasks), err)
+	}
+
+	now = start.Add(-20 * time.Hour)
+	g.RunOnce(ctx)
+	g.RunOnce(ctx)
+	if len(tasks.tasks) != 1 || !tasks.tasks[0].DueDate.Equal(start) || notifier.notified != 2 {
+		t.Fatalf("Expected one task due %v for both members, got %d tasks and %d notifications", start, len(tasks.tasks), notifier.notified)
+	}
+	if next := start.AddDate(0, 0, 7); !template.NextDueAt.Equal(next) || !template.LastDueAt.Equal(start) {
+		t.Errorf("Expected the next task due %v, got %v", next, template.NextDueAt)
+	}
+
+	// A task created before a crash, before the template was advanced, is not created again
+	template.NextDueAt, template.NextRunAt = &start, &now
+	g.RunOnce(ctx)
+	if len(tasks.tasks) != 1 || !template.NextDueAt.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("Expected the duplicate to be skipped and the template advanced, got %d tasks", len(tasks.tasks))
+	}
+
+	// After an outage only the earliest missed task is created
+	now = start.AddDate(0, 0, 30)
+	g.RunOnce(ctx)
+	if len(tasks.tasks) != 2 || !tasks.tasks[1].DueDate.Equal(start.AddDate(0, 0, 7)) || !template.NextDueAt.After(now) {
+		t.Errorf("Expected one late task and the schedule to resume after now, got %d tasks, next %v", len(tasks.tasks), template.NextDueAt)
+	}
+	if tasks.tasks[1].Rank <= tasks.tasks[0].Rank {
+		t.Errorf("Expected the later task at the bottom of the column, got ranks %q and %q", tasks.tasks[0].Rank, tasks.tasks[1].Rank)
+	}
+
+	// Paused templates create nothing
+	template.Active = false
+	now = *template.NextDueAt
+	g.RunOnce(ctx)
+	if len(tasks.tasks) != 2 {
+		t.Errorf("Expected a paused template to create nothing, got %d tasks", len(tasks.tasks))
+	}
+}
@@ -0,0 +1,248 @@
+// Package reminders sends reminders before events start and tasks are due
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Config configures when reminders are sent
+type Config struct {
+	// Enabled runs the scheduler; replicas may all run it since every reminder is claimed once
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often upcoming events and tasks are checked
+	Interval time.Duration `yaml:"interval"`
+	// EventOffsets are how long before an event starts its attendees are reminded
+	EventOffsets []time.Duration `yaml:"eventOffsets"`
+	// TaskOffsets are how long before an open task is due its assignee is reminded
+	TaskOffsets []time.Duration `yaml:"taskOffsets"`
+}
+
+// Validate checks that the settings are usable
+func (c Config) Validate() error {
+	var errs []error
+	if c.Interval <= 0 {
+		errs = append(errs, errors.New("interval must be positive"))
+	}
+	for _, offset := range append(append([]time.Duration{}, c.EventOffsets...), c.TaskOffsets...) {
+		if offset <= 0 {
+			errs = append(errs, fmt.Errorf("offsets must be positive, got %s", offset))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Notifier delivers reminders to members
+type Notifier interface {
+	EventReminder(ctx context.Context, event *models.Event, recipients []primitive.ObjectID, offset time.Duration) error
+	TaskDueReminder(ctx context.Context, task *models.Task, recipients []primitive.ObjectID, offset time.Duration) error
+}
+
+// Scheduler periodically sends the reminders that have come due.
+// Nothing is scheduled ahead of time: every run looks at the events and tasks as they are
+// now, so reschedules and cancellations are picked up without extra bookkeeping, and
+// reminders missed while the server was down are sent late rather than not at all.
+// A reminder is claimed before it is sent, so one that fails to send is not retried.
+type Scheduler struct {
+	cfg       Config
+	events    repositories.EventRepository
+	tasks     repositories.TaskRepository
+	reminders repositories.ReminderRepository
+	users     repositories.UserRepository
+	notifier  Notifier
+	now       func() time.Time
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(cfg Config, events repositories.EventRepository, tasks repositories.TaskRepository,
+	reminders repositories.ReminderRepository, users repositories.UserRepository, notifier Notifier) *Scheduler {
+	return &Scheduler{
+		cfg:       cfg,
+		events:    events,
+		tasks:     tasks,
+		reminders: reminders,
+		users:     users,
+		notifier:  notifier,
+		now:       time.Now,
+	}
+}
+
+// Run sends due reminders every interval until ctx is done. Start it with a worker.Group.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to send reminders", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the reminders that are due now and have not been sent yet
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	now := s.now()
+	return errors.Join(s.remindEvents(ctx, now), s.remindTasks(ctx, now))
+}
+
+// remindEvents reminds the attendees of upcoming events
+func (s *Scheduler) remindEvents(ctx context.Context, now time.Time) error {
+	if len(s.cfg.EventOffsets) == 0 {
+		return nil
+	}
+
+	events, err := s.events.FindStartingBetween(ctx, now, now.Add(maxOffset(s.cfg.EventOffsets)))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, event := range events {
+		offset, ok := dueOffset(event.StartTime, now, s.cfg.EventOffsets)
+		if !ok {
+			continue
+		}
+		claimed, err := s.reminders.Claim(ctx, models.NewReminder(models.ReminderEvent, event.ID, offset, event.StartTime))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		recipients, err := s.eventRecipients(ctx, event)
+		if err == nil {
+			err = s.notifier.EventReminder(ctx, event, recipients, offset)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("event %s: %w", event.ID.Hex(), err))
+			continue
+		}
+		metrics.RemindersSent.WithLabelValues(string(models.ReminderEvent)).Inc()
+	}
+	return errors.Join(errs...)
+}
+
+// remindTasks reminds the assignees of open tasks that are due soon.
+// Members of a group task who already finished their part are not reminded.
+func (s *Scheduler) remindTasks(ctx context.Context, now time.Time) error {
+	if len(s.cfg.TaskOffsets) == 0 {
+		return nil
+	}
+
+	tasks, err := s.tasks.FindOpenDueBetween(ctx, now, now.Add(maxOffset(s.cfg.TaskOffsets)))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, task := range tasks {
+		offset, ok := dueOffset(*task.DueDate, now, s.cfg.TaskOffsets)
+		if !ok {
+			continue
+		}
+		claimed, err := s.reminders.Claim(ctx, models.NewReminder(models.ReminderTask, task.ID, offset, *task.DueDate))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		recipients, err := s.taskRecipients(ctx, task)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", task.ID.Hex(), err))
+			continue
+		}
+		if err := s.notifier.TaskDueReminder(ctx, task, recipients, offset); err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", task.ID.Hex(), err))
+			continue
+		}
+		metrics.RemindersSent.WithLabelValues(string(models.ReminderTask)).Inc()
+	}
+	return errors.Join(errs...)
+}
+
+// eventRecipients returns the attendees of an event, or every user for events without attendees
+func (s *Scheduler) eventRecipients(ctx context.Context, event *models.Event) ([]primitive.ObjectID, error) {
+	if len(event.Attendees) > 0 {
+		return event.Attendees, nil
+	}
+
+	users, err := repositories.ListAllUsers(ctx, s.users, models.UserFilter{})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids, nil
+}
+
+// taskRecipients returns the assignee of a task, or the members of its group who have not finished it
+func (s *Scheduler) taskRecipients(ctx context.Context, task *models.Task) ([]primitive.ObjectID, error) {
+	if !task.IsGroupTask() {
+		return []primitive.ObjectID{task.AssignedTo}, nil
+	}
+
+	users, err := repositories.ListAllUsers(ctx, s.users, task.MemberFilter())
+	if err != nil {
+		return nil, err
+	}
+	var ids []primitive.ObjectID
+	for _, user := range users {
+		if task.CompletedBy(user.ID) == nil {
+			ids = append(ids, user.ID)
+		}
+	}
+	return ids, nil
+}
+
+// dueOffset returns the smallest offset whose reminder time before at has passed by now.
+// Larger offsets that are also past are skipped, so a target created shortly before it is
+// due gets one reminder instead of several at once.
+func dueOffset(at, now time.Time, offsets []time.Duration) (time.Duration, bool) {
+	if !now.Before(at) {
+		return 0, false
+	}
+
+	var best time.Duration
+	found := false
+	for _, offset := range offsets {
+		if now.Before(at.Add(-offset)) {
+			continue
+		}
+		if !found || offset < best {
+			best, found = offset, true
+		}
+	}
+	return best, found
+}
+
+// maxOffset returns the largest offset
+func maxOffset(offsets []time.Duration) time.Duration {
+	var longest time.Duration
+	for _, offset := range offsets {
+		if offset > longest {
+			longest = offset
+		}
+	}
+	return longest
+}
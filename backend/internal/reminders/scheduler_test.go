@@ -0,0 +1,171 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeEvents struct{ events []*models.Event }
+
+func (f *fakeEvents) FindStartingBetween(ctx context.Context, from, to time.Time) ([]*models.Event, error) {
+	var found []*models.Event
+	for _, e := range f.events {
+		if e.StartTime.After(from) && !e.StartTime.After(to) {
+			found = append(found, e)
+		}
+	}
+	return found, nil
+}
+
+type fakeTasks struct {
+	repositories.TaskRepository
+	tasks []*models.Task
+}
+
+func (f *fakeTasks) FindOpenDueBetween(ctx context.Context, from, to time.Time) ([]*models.Task, error) {
+	var found []*models.Task
+	for _, t := range f.tasks {
+		if t.DueDate != nil && t.DueDate.After(from) && !t.DueDate.After(to) && t.Status != models.TaskStatusCompleted {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// fakeReminders claims each key once, like the unique index
+type fakeReminders struct{ claimed map[string]bool }
+
+func (f *fakeReminders) Claim(ctx context.Context, r *models.Reminder) (bool, error) {
+	key := fmt.Sprint(r.Kind, r.TargetID.Hex(), r.Offset, r.DueAt.UnixMilli())
+	if f.claimed[key] {
+		return false, nil
+	}
+	f.claimed[key] = true
+	return true, nil
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+	users []*models.User
+}
+
+func (f *fakeUsers) List(ctx context.Context, filter models.UserFilter, query models.ListQuery) (*models.ListResult[*models.User], error) {
+	return &models.ListResult[*models.User]{Items: f.users, Total: int64(len(f.users))}, nil
+}
+
+// sent is a reminder the fake notifier was asked to deliver
+type sent struct {
+	target     primitive.ObjectID
+	offset     time.Duration
+	recipients int
+}
+
+type fakeNotifier struct{ sent []sent }
+
+func (f *fakeNotifier) EventReminder(ctx context.Context, event *models.Event, recipients []primitive.ObjectID, offset time.Duration) error {
+	f.sent = append(f.sent, sent{event.ID, offset, len(recipients)})
+	return nil
+}
+
+func (f *fakeNotifier) TaskDueReminder(ctx context.Context, task *models.Task, recipients []primitive.ObjectID, offset time.Duration) error {
+	f.sent = append(f.sent, sent{task.ID, offset, len(recipients)})
+	return nil
+}
+
+func newTestScheduler(events []*models.Event, tasks []*models.Task) (*Scheduler, *fakeNotifier, *time.Time) {
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	notifier := &fakeNotifier{}
+	users := &fakeUsers{users: []*models.User{{ID: primitive.NewObjectID()}, {ID: primitive.NewObjectID()}, {ID: primitive.NewObjectID()}}}
+	s := NewScheduler(Config{
+		Interval:     time.Minute,
+		EventOffsets: []time.Duration{24 * time.Hour, time.Hour},
+		TaskOffsets:  []time.Duration{24 * time.Hour},
+	}, &fakeEvents{events}, &fakeTasks{tasks: tasks}, &fakeReminders{claimed: map[string]bool{}}, users, notifier)
+	s.now = func() time.Time { return now }
+	return s, notifier, &now
+}
+
+func TestEventRemindersAtEachOffset(t *testing.T) {
+	ctx := context.Background()
+	event := &models.Event{ID: primitive.NewObjectID(), Attendees: []primitive.ObjectID{primitive.NewObjectID()}}
+	s, notifier, now := newTestScheduler([]*models.Event{event}, nil)
+	event.StartTime = now.Add(30 * time.Hour)
+
+	s.RunOnce(ctx)
+	if len(notifier.sent) != 0 {
+		t.Fatalf("Expected no reminder 30h ahead, got %v", notifier.sent)
+	}
+
+	// The 24h reminder is sent once, however many times the scheduler runs
+	*now = event.StartTime.Add(-23 * time.Hour)
+	s.RunOnce(ctx)
+	s.RunOnce(ctx)
+	if len(notifier.sent) != 1 || notifier.sent[0].offset != 24*time.Hour || notifier.sent[0].recipients != 1 {
+		t.Fatalf("Expected one 24h reminder to the attendee, got %v", notifier.sent)
+	}
+
+	*now = event.StartTime.Add(-30 * time.Minute)
+	s.RunOnce(ctx)
+	if len(notifier.sent) != 2 || notifier.sent[1].offset != time.Hour {
+		t.Fatalf("Expected the 1h reminder, got %v", notifier.sent)
+	}
+
+	// Moving the event reminds attendees again for the new time
+	event.StartTime = now.Add(45 * time.Minute)
+	s.RunOnce(ctx)
+	if len(notifier.sent) != 3 || notifier.sent[2].offset != time.Hour {
+		t.Fatalf("Expected a reminder for the rescheduled event, got %v", notifier.sent)
+	}
+
+	// Events that have started are not reminded about
+	*now = event.StartTime.Add(time.Minute)
+	s.RunOnce(ctx)
+	if len(notifier.sent) != 3 {
+		t.Errorf("Expected no reminder after the start, got %v", notifier.sent)
+	}
+}
+
+func TestLateEventGetsOneReminder(t *testing.T) {
+	event := &models.Event{ID: primitive.NewObjectID()}
+	s, notifier, now := newTestScheduler([]*models.Event{event}, nil)
+	event.StartTime = now.Add(20 * time.Minute)
+
+	s.RunOnce(context.Background())
+	if len(notifier.sent) != 1 || notifier.sent[0].offset != time.Hour {
+		t.Fatalf("Expected only the closest reminder, got %v", notifier.sent)
+	}
+	if notifier.sent[0].recipients != 3 {
+		t.Errorf("Expected events without attendees to remind every user, got %d", notifier.sent[0].recipients)
+	}
+}
+
+func TestTaskReminders(t *testing.T) {
+	due := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	open := &models.Task{ID: primitive.NewObjectID(), DueDate: &due, Status: models.TaskStatusInProgress}
+	done := &models.Task{ID: primitive.NewObjectID(), DueDate: &due, Status: models.TaskStatusCompleted}
+	s, notifier, _ := newTestScheduler(nil, []*models.Task{open, done})
+
+	s.RunOnce(context.Background())
+	if len(notifier.sent) != 1 || notifier.sent[0].target != open.ID {
+		t.Errorf("Expected a reminder for the open task only, got %v", notifier.sent)
+	}
+}
+
+func TestGroupTaskRemindersSkipFinishedMembers(t *testing.T) {
+	due := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	task := &models.Task{ID: primitive.NewObjectID(), DueDate: &due, Status: models.TaskStatusTodo, AssigneeType: models.TaskAssigneeEveryone}
+	s, notifier, _ := newTestScheduler(nil, []*models.Task{task})
+	users := s.users.(*fakeUsers).users
+	task.Completions = []models.TaskCompletion{{UserID: users[0].ID, CompletedAt: due}}
+
+	s.RunOnce(context.Background())
+	if len(notifier.sent) != 1 || notifier.sent[0].recipients != 2 {
+		t.Errorf("Expected a reminder to the two members who have not finished, got %v", notifier.sent)
+	}
+}
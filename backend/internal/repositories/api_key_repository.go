@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyRepository defines the methods for API key data access
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) (string, error)
+	FindAll(ctx context.Context) ([]*models.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+}
+
+// APIKeyMongoRepository implements APIKeyRepository for MongoDB
+type APIKeyMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewAPIKeyMongoRepository creates a new APIKeyMongoRepository
+func NewAPIKeyMongoRepository(client *mongo.Client, db string) APIKeyRepository {
+	return &APIKeyMongoRepository{
+		db:         db,
+		collection: "api_keys",
+		client:     client,
+	}
+}
+
+// Create stores a new API key
+func (r *APIKeyMongoRepository) Create(ctx context.Context, key *models.APIKey) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	key.ID = id
+	return id.Hex(), nil
+}
+
+// FindAll returns all API keys, newest first
+func (r *APIKeyMongoRepository) FindAll(ctx context.Context) ([]*models.APIKey, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindAll")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// FindByHash finds an API key by the hash of the key
+func (r *APIKeyMongoRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByHash")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var key models.APIKey
+	err := coll.FindOne(ctx, bson.M{"keyHash": keyHash}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Revoke disables an API key. It fails with mongo.ErrNoDocuments if there is no active key with the ID.
+func (r *APIKeyMongoRepository) Revoke(ctx context.Context, id string) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Revoke")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	// No key has an ID that is not an object ID, whatever is wrong with it
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return mongo.ErrNoDocuments
+	}
+
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": objectID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *APIKeyMongoRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "TouchLastUsed")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$max": bson.M{"lastUsedAt": usedAt}})
+	return err
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EventRepository defines the methods for event data access
+type EventRepository interface {
+	FindStartingBetween(ctx context.Context, from, to time.Time) ([]*models.Event, error)
+}
+
+// EventMongoRepository implements EventRepository for MongoDB
+type EventMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewEventMongoRepository creates a new EventMongoRepository
+func NewEventMongoRepository(client *mongo.Client, db string) EventRepository {
+	return &EventMongoRepository{
+		db:         db,
+		collection: "events",
+		client:     client,
+	}
+}
+
+// FindStartingBetween finds the events that start after from and no later than to, earliest first
+func (r *EventMongoRepository) FindStartingBetween(ctx context.Context, from, to time.Time) ([]*models.Event, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindStartingBetween")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cur, err := coll.Find(ctx,
+		bson.M{"startTime": bson.M{"$gt": from, "$lte": to}},
+		options.Find().SetSort(bson.D{{Key: "startTime", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*models.Event{}
+	if err := cur.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
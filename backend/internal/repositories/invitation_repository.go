@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InvitationRepository defines the methods for invitation data access
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *models.Invitation) (string, error)
+	CreateMany(ctx context.Context, invitations []*models.Invitation) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	MarkAccepted(ctx context.Context, id primitive.ObjectID) error
+	ReleaseAccepted(ctx context.Context, id primitive.ObjectID) error
+}
+
+// InvitationMongoRepository implements InvitationRepository for MongoDB
+type InvitationMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewInvitationMongoRepository creates a new InvitationMongoRepository
+func NewInvitationMongoRepository(client *mongo.Client, db string) InvitationRepository {
+	return &InvitationMongoRepository{
+		db:         db,
+		collection: "invitations",
+		client:     client,
+	}
+}
+
+// Create stores a new invitation
+func (r *InvitationMongoRepository) Create(ctx context.Context, invitation *models.Invitation) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, invitation)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	invitation.ID = id
+	return id.Hex(), nil
+}
+
+// CreateMany stores new invitations and sets their IDs. Either every invitation is stored or,
+// if one insert fails, the invitations already inserted are removed again and none are.
+func (r *InvitationMongoRepository) CreateMany(ctx context.Context, invitations []*models.Invitation) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "CreateMany")
+	defer end()
+
+	if len(invitations) == 0 {
+		return nil
+	}
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	docs := make([]interface{}, len(invitations))
+	ids := make([]primitive.ObjectID, len(invitations))
+	for i, invitation := range invitations {
+		invitation.ID = primitive.NewObjectID()
+		docs[i] = invitation
+		ids[i] = invitation.ID
+	}
+
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		if _, cleanupErr := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); cleanupErr != nil {
+			err = errors.Join(err, cleanupErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// FindByTokenHash finds an invitation by the hash of its token
+func (r *InvitationMongoRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByTokenHash")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var invitation models.Invitation
+	err := coll.FindOne(ctx, bson.M{"tokenHash": tokenHash}).Decode(&invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// MarkAccepted records that an invitation has been used.
+// It fails with mongo.ErrNoDocuments if the invitation was already accepted.
+func (r *InvitationMongoRepository) MarkAccepted(ctx context.Context, id primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "MarkAccepted")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": id, "acceptedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"acceptedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ReleaseAccepted makes an invitation usable again after MarkAccepted,
+// for when setting the invited user's password failed
+func (r *InvitationMongoRepository) ReleaseAccepted(ctx context.Context, id primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "ReleaseAccepted")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"acceptedAt": ""}})
+	return err
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationPreferencesRepository defines the methods for notification preference data access
+type NotificationPreferencesRepository interface {
+	FindByUserID(ctx context.Context, userID primitive.ObjectID) (*models.NotificationPreferences, error)
+	Save(ctx context.Context, preferences *models.NotificationPreferences) error
+}
+
+// NotificationPreferencesMongoRepository implements NotificationPreferencesRepository for MongoDB
+type NotificationPreferencesMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewNotificationPreferencesMongoRepository creates a new NotificationPreferencesMongoRepository
+func NewNotificationPreferencesMongoRepository(client *mongo.Client, db string) NotificationPreferencesRepository {
+	return &NotificationPreferencesMongoRepository{
+		db:         db,
+		collection: "notification_preferences",
+		client:     client,
+	}
+}
+
+// FindByUserID finds a user's preferences. It returns nil if the user has not saved any.
+func (r *NotificationPreferencesMongoRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) (*models.NotificationPreferences, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByUserID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var preferences models.NotificationPreferences
+	err := coll.FindOne(ctx, bson.M{"_id": userID}).Decode(&preferences)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
+// Save creates or replaces a user's preferences
+func (r *NotificationPreferencesMongoRepository) Save(ctx context.Context, preferences *models.NotificationPreferences) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Save")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.ReplaceOne(ctx, bson.M{"_id": preferences.UserID}, preferences, options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NotificationRepository defines the methods for notification data access
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) (string, error)
+	List(ctx context.Context, userID primitive.ObjectID, unreadOnly bool, query models.ListQuery) (*models.ListResult[*models.Notification], error)
+	CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	MarkRead(ctx context.Context, userID, id primitive.ObjectID) error
+	MarkAllRead(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// NotificationMongoRepository implements NotificationRepository for MongoDB
+type NotificationMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewNotificationMongoRepository creates a new NotificationMongoRepository
+func NewNotificationMongoRepository(client *mongo.Client, db string) NotificationRepository {
+	return &NotificationMongoRepository{
+		db:         db,
+		collection: "notifications",
+		client:     client,
+	}
+}
+
+// notificationListSpec maps the inbox's sort keys onto the notifications collection
+var notificationListSpec = listSpec{
+	sortFields: map[string]string{
+		"createdAt": "createdAt",
+	},
+	searchFields: []string{"title", "body"},
+}
+
+// Create stores a new notification
+func (r *NotificationMongoRepository) Create(ctx context.Context, notification *models.Notification) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, notification)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	notification.ID = id
+	return id.Hex(), nil
+}
+
+// List finds a page of a user's notifications
+func (r *NotificationMongoRepository) List(ctx context.Context, userID primitive.ObjectID, unreadOnly bool, query models.ListQuery) (*models.ListResult[*models.Notification], error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "List")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	match := bson.M{"userId": userID}
+	if unreadOnly {
+		match["readAt"] = bson.M{"$exists": false}
+	}
+
+	return findPage[*models.Notification](ctx, coll, match, query, notificationListSpec)
+}
+
+// CountUnread counts the notifications a user has not read
+func (r *NotificationMongoRepository) CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "CountUnread")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	return coll.CountDocuments(ctx, bson.M{"userId": userID, "readAt": bson.M{"$exists": false}})
+}
+
+// MarkRead marks one of a user's notifications as read.
+// It fails with mongo.ErrNoDocuments if the user has no such notification.
+func (r *NotificationMongoRepository) MarkRead(ctx context.Context, userID, id primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "MarkRead")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": id, "userId": userID},
+		bson.A{bson.M{"$set": bson.M{"readAt": bson.M{"$ifNull": bson.A{"$readAt", time.Now()}}}}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of a user as read
+func (r *NotificationMongoRepository) MarkAllRead(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "MarkAllRead")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.UpdateMany(ctx,
+		bson.M{"userId": userID, "readAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"readAt": time.Now()}},
+	)
+	return err
+}
@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvalidCursor is returned when a list cursor cannot be decoded
+	// or was issued for a different sort order
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort is returned when a list is sorted by an unknown key
+	ErrInvalidSort = errors.New("invalid sort field")
+)
+
+// listSpec describes how a collection maps list query options onto document fields
+type listSpec struct {
+	// sortFields maps public sort keys to bson field names
+	sortFields map[string]string
+	// searchFields are the bson fields matched by a text search
+	searchFields []string
+}
+
+// listCursor is the position of the last item of a page.
+// It is opaque to clients and encoded as base64 extended JSON so the
+// sort value keeps its BSON type (dates stay dates). The sort key and
+// order it was issued for are kept so it cannot be replayed against another ordering.
+type listCursor struct {
+	Value interface{}        `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+	Sort  string             `bson:"s"`
+	Order models.SortOrder   `bson:"o"`
+}
+
+// encodeCursor encodes a sort value and document ID into an opaque cursor for the query's ordering
+func encodeCursor(value interface{}, id primitive.ObjectID, q models.ListQuery) (string, error) {
+	data, err := bson.MarshalExtJSON(listCursor{Value: value, ID: id, Sort: q.Sort, Order: q.Order}, true, false)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes a cursor created by encodeCursor for the same ordering as q
+func decodeCursor(s string, q models.ListQuery) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c listCursor
+	if err := bson.UnmarshalExtJSON(data, true, &c); err != nil || c.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != q.Sort || c.Order != q.Order {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// searchFilter builds a case-insensitive substring match over the given fields
+func searchFilter(search string, fields []string) bson.M {
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search), Options: "i"}
+	or := bson.A{}
+	for _, field := range fields {
+		or = append(or, bson.M{field: pattern})
+	}
+	return bson.M{"$or": or}
+}
+
+// findPage runs a keyset-paginated query against a collection.
+// Documents are ordered by the requested sort field with _id as a tie-breaker,
+// so cursors stay stable while documents are inserted or removed.
+func findPage[T any](ctx context.Context, coll *mongo.Collection, filter bson.M, q models.ListQuery, spec listSpec) (*models.ListResult[T], error) {
+	field, ok := spec.sortFields[q.Sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	if q.Search != "" {
+		filter = bson.M{"$and": bson.A{filter, searchFilter(q.Search, spec.searchFields)}}
+	}
+
+	start := time.Now()
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	pageFilter := filter
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, q)
+		if err != nil {
+			return nil, err
+		}
+
+		op := "$gt"
+		if q.Order == models.SortDesc {
+			op = "$lt"
+		}
+		pageFilter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{op: c.Value}},
+			bson.M{field: c.Value, "_id": bson.M{op: c.ID}},
+		}}}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: int(q.Order)}, {Key: "_id", Value: int(q.Order)}}).
+		SetLimit(int64(q.Limit + 1))
+
+	cur, err := coll.Find(ctx, pageFilter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var raws []bson.Raw
+	for cur.Next(ctx) {
+		raws = append(raws, append(bson.Raw(nil), cur.Current...))
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &models.ListResult[T]{Items: make([]T, 0, len(raws)), Total: total}
+	if len(raws) > q.Limit {
+		result.HasMore = true
+		raws = raws[:q.Limit]
+	}
+
+	for _, raw := range raws {
+		var item T
+		if err := bson.Unmarshal(raw, &item); err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	if result.HasMore {
+		last := raws[len(raws)-1]
+
+		var value interface{}
+		if v, err := last.LookupErr(field); err == nil {
+			if err := v.Unmarshal(&value); err != nil {
+				return nil, err
+			}
+		}
+
+		id, _ := last.Lookup("_id").ObjectIDOK()
+		if result.NextCursor, err = encodeCursor(value, id, q); err != nil {
+			return nil, err
+		}
+	}
+
+	logging.FromContext(ctx).DebugContext(ctx, "list query",
+		"collection", coll.Name(),
+		"sort", q.Sort,
+		"returned", len(result.Items),
+		"total", total,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	query := models.ListQuery{Sort: "fullName", Order: models.SortAsc}
+	createdAt := primitive.NewDateTimeFromTime(time.Date(2025, 4, 1, 9, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "string", value: "Sato Hanako"},
+		{name: "date", value: createdAt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeCursor(tt.value, id, query)
+			if err != nil {
+				t.Fatalf("Error encoding cursor: %v", err)
+			}
+
+			decoded, err := decodeCursor(encoded, query)
+			if err != nil {
+				t.Fatalf("Error decoding cursor: %v", err)
+			}
+
+			if decoded.ID != id {
+				t.Errorf("Expected ID %s, got %s", id.Hex(), decoded.ID.Hex())
+			}
+			if decoded.Value != tt.value {
+				t.Errorf("Expected value %v (%T), got %v (%T)", tt.value, tt.value, decoded.Value, decoded.Value)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, s := range []string{"not-base64!", "e30", "eyJ2IjoxfQ"} {
+		if _, err := decodeCursor(s, models.ListQuery{}); err != ErrInvalidCursor {
+			t.Errorf("Expected ErrInvalidCursor for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestDecodeCursorForAnotherOrdering(t *testing.T) {
+	query := models.ListQuery{Sort: "fullName", Order: models.SortAsc}
+	encoded, err := encodeCursor("Sato Hanako", primitive.NewObjectID(), query)
+	if err != nil {
+		t.Fatalf("Error encoding cursor: %v", err)
+	}
+
+	for _, other := range []models.ListQuery{
+		{Sort: "createdAt", Order: models.SortAsc},
+		{Sort: "fullName", Order: models.SortDesc},
+	} {
+		if _, err := decodeCursor(encoded, other); err != ErrInvalidCursor {
+			t.Errorf("Expected ErrInvalidCursor for sort %q order %d, got %v", other.Sort, other.Order, err)
+		}
+	}
+}
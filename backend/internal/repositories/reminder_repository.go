@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReminderRepository defines the methods for sent reminder data access
+type ReminderRepository interface {
+	Claim(ctx context.Context, reminder *models.Reminder) (bool, error)
+}
+
+// ReminderMongoRepository implements ReminderRepository for MongoDB
+type ReminderMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewReminderMongoRepository creates a new ReminderMongoRepository
+func NewReminderMongoRepository(client *mongo.Client, db string) ReminderRepository {
+	return &ReminderMongoRepository{
+		db:         db,
+		collection: "reminders",
+		client:     client,
+	}
+}
+
+// Claim records a reminder as sent. It reports false if the same reminder was already
+// claimed, by this or another replica, relying on the unique index over its key.
+func (r *ReminderMongoRepository) Claim(ctx context.Context, reminder *models.Reminder) (bool, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Claim")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, reminder)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	reminder.ID = res.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TaskAttachmentRepository defines the methods for task attachment data access
+type TaskAttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.TaskAttachment) (string, error)
+	FindByID(ctx context.Context, id string) (*models.TaskAttachment, error)
+	FindByTask(ctx context.Context, taskID primitive.ObjectID) ([]*models.TaskAttachment, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error
+}
+
+// TaskAttachmentMongoRepository implements TaskAttachmentRepository for MongoDB
+type TaskAttachmentMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewTaskAttachmentMongoRepository creates a new TaskAttachmentMongoRepository
+func NewTaskAttachmentMongoRepository(client *mongo.Client, db string) TaskAttachmentRepository {
+	return &TaskAttachmentMongoRepository{
+		db:         db,
+		collection: "task_attachments",
+		client:     client,
+	}
+}
+
+// Create stores the metadata of a new attachment
+func (r *TaskAttachmentMongoRepository) Create(ctx context.Context, attachment *models.TaskAttachment) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, attachment)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	attachment.ID = id
+	return id.Hex(), nil
+}
+
+// FindByID finds an attachment by ID. It returns nil if the ID is invalid or no attachment has it.
+func (r *TaskAttachmentMongoRepository) FindByID(ctx context.Context, id string) (*models.TaskAttachment, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var attachment models.TaskAttachment
+	err = coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&attachment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// FindByTask finds every attachment of a task, oldest first
+func (r *TaskAttachmentMongoRepository) FindByTask(ctx context.Context, taskID primitive.ObjectID) ([]*models.TaskAttachment, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByTask")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cursor, err := coll.Find(ctx, bson.M{"taskId": taskID},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []*models.TaskAttachment{}
+	if err := cursor.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Delete removes the metadata of an attachment.
+// It fails with mongo.ErrNoDocuments if the attachment does not exist.
+func (r *TaskAttachmentMongoRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Delete")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteByTask removes the metadata of every attachment of a task
+func (r *TaskAttachmentMongoRepository) DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "DeleteByTask")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.DeleteMany(ctx, bson.M{"taskId": taskID})
+	return err
+}
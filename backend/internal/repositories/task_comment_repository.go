@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TaskCommentRepository defines the methods for task comment data access
+type TaskCommentRepository interface {
+	Create(ctx context.Context, comment *models.TaskComment) (string, error)
+	FindByID(ctx context.Context, id string) (*models.TaskComment, error)
+	FindByTask(ctx context.Context, taskID primitive.ObjectID) ([]*models.TaskComment, error)
+	Update(ctx context.Context, comment *models.TaskComment) error
+	DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error
+}
+
+// TaskCommentMongoRepository implements TaskCommentRepository for MongoDB
+type TaskCommentMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewTaskCommentMongoRepository creates a new TaskCommentMongoRepository
+func NewTaskCommentMongoRepository(client *mongo.Client, db string) TaskCommentRepository {
+	return &TaskCommentMongoRepository{
+		db:         db,
+		collection: "task_comments",
+		client:     client,
+	}
+}
+
+// Create stores a new comment
+func (r *TaskCommentMongoRepository) Create(ctx context.Context, comment *models.TaskComment) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, comment)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	comment.ID = id
+	return id.Hex(), nil
+}
+
+// FindByID finds a comment by ID. It returns nil if the ID is invalid or no comment has it.
+func (r *TaskCommentMongoRepository) FindByID(ctx context.Context, id string) (*models.TaskComment, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var comment models.TaskComment
+	err = coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&comment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// FindByTask finds every comment on a task, oldest first
+func (r *TaskCommentMongoRepository) FindByTask(ctx context.Context, taskID primitive.ObjectID) ([]*models.TaskComment, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByTask")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cursor, err := coll.Find(ctx, bson.M{"taskId": taskID},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []*models.TaskComment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// Update saves the body and state of a comment.
+// It fails with mongo.ErrNoDocuments if the comment does not exist.
+func (r *TaskCommentMongoRepository) Update(ctx context.Context, comment *models.TaskComment) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Update")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": comment.ID}, bson.M{"$set": bson.M{
+		"body":      comment.Body,
+		"deleted":   comment.Deleted,
+		"editedAt":  comment.EditedAt,
+		"updatedAt": comment.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteByTask removes every comment on a task
+func (r *TaskCommentMongoRepository) DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "DeleteByTask")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.DeleteMany(ctx, bson.M{"taskId": taskID})
+	return err
+}
@@ -0,0 +1,495 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TaskRepository defines the methods for task data access
+type TaskRepository interface {
+	Create(ctx context.Context, task *models.Task) (string, error)
+	FindByID(ctx context.Context, id string) (*models.Task, error)
+	List(ctx context.Context, filter models.TaskFilter, query models.ListQuery) (*models.ListResult[*models.Task], error)
+	Update(ctx context.Context, task *models.Task) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	AddChecklistItem(ctx context.Context, taskID primitive.ObjectID, item models.ChecklistItem) error
+	UpdateChecklistItem(ctx context.Context, taskID primitive.ObjectID, item models.ChecklistItem) error
+	DeleteChecklistItem(ctx context.Context, taskID, itemID primitive.ObjectID) error
+	AddCompletion(ctx context.Context, taskID primitive.ObjectID, completion models.TaskCompletion) error
+	RemoveCompletion(ctx context.Context, taskID, userID primitive.ObjectID) error
+	FindOpenDueBetween(ctx context.Context, from, to time.Time) ([]*models.Task, error)
+	LastRank(ctx context.Context, status models.TaskStatus) (string, error)
+	HasTaskBetween(ctx context.Context, status models.TaskStatus, before, after string, except primitive.ObjectID, viewer *models.User) (bool, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Task, error)
+	FindBlocking(ctx context.Context, blockerID primitive.ObjectID) ([]*models.Task, error)
+	AddBlocker(ctx context.Context, taskID, blockerID primitive.ObjectID) error
+	RemoveBlocker(ctx context.Context, taskID, blockerID primitive.ObjectID) error
+	RemoveBlockerFromAll(ctx context.Context, blockerID primitive.ObjectID) error
+}
+
+// ErrTooManyBlockers is returned when a task already waits on models.MaxTaskBlockers tasks
+var ErrTooManyBlockers = errors.New("too many blocking tasks")
+
+// TaskMongoRepository implements TaskRepository for MongoDB
+type TaskMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewTaskMongoRepository creates a new TaskMongoRepository
+func NewTaskMongoRepository(client *mongo.Client, db string) TaskRepository {
+	return &TaskMongoRepository{
+		db:         db,
+		collection: "tasks",
+		client:     client,
+	}
+}
+
+// taskListSpec maps the task listing's sort keys onto the tasks collection
+var taskListSpec = listSpec{
+	sortFields: map[string]string{
+		"createdAt": "createdAt",
+		"updatedAt": "updatedAt",
+		"title":     "title",
+		"rank":      "rank",
+	},
+	searchFields: []string{"title", "description", "labels"},
+}
+
+// Create stores a new task
+func (r *TaskMongoRepository) Create(ctx context.Context, task *models.Task) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, task)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	task.ID = id
+	return id.Hex(), nil
+}
+
+// FindByID finds a task by ID. It returns nil if the ID is invalid or no task has it.
+func (r *TaskMongoRepository) FindByID(ctx context.Context, id string) (*models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var task models.Task
+	err = coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// List finds a page of tasks
+func (r *TaskMongoRepository) List(ctx context.Context, filter models.TaskFilter, query models.ListQuery) (*models.ListResult[*models.Task], error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "List")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	match := bson.M{}
+	if filter.Status != "" {
+		match["status"] = filter.Status
+	}
+	if !filter.AssignedTo.IsZero() {
+		match["assignedTo"] = filter.AssignedTo
+	}
+	if !filter.TemplateID.IsZero() {
+		match["templateId"] = filter.TemplateID
+	}
+	if filter.Priority != "" {
+		match["priority"] = filter.Priority
+	}
+	if len(filter.Labels) > 0 {
+		match["labels"] = bson.M{"$all": filter.Labels}
+	}
+	if viewer := filter.VisibleTo; viewer != nil {
+		match["$or"] = visibleTo(viewer)
+	}
+
+	return findPage[*models.Task](ctx, coll, match, query, taskListSpec)
+}
+
+// visibleTo matches the tasks a member can see, as models.Task.VisibleTo does for non-admins
+func visibleTo(viewer *models.User) bson.A {
+	or := bson.A{
+		bson.M{"assignedTo": viewer.ID},
+		bson.M{"createdBy": viewer.ID},
+		bson.M{"assigneeType": models.TaskAssigneeEveryone},
+		bson.M{"assigneeType": models.TaskAssigneeRole, "assignedRole": viewer.Role},
+	}
+	if viewer.Section != "" {
+		or = append(or, bson.M{"assigneeType": models.TaskAssigneeSection, "assignedSection": viewer.Section})
+	}
+	return or
+}
+
+// Update saves the editable fields of a task; the checklist is changed with its own methods.
+// It fails with mongo.ErrNoDocuments if the task does not exist.
+func (r *TaskMongoRepository) Update(ctx context.Context, task *models.Task) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Update")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	set := bson.M{
+		"title":       task.Title,
+		"description": task.Description,
+		"status":      task.Status,
+		"assignedTo":  task.AssignedTo,
+		"updatedAt":   task.UpdatedAt,
+		"priority":    task.Priority,
+		"labels":      task.Labels,
+		"rank":        task.Rank,
+	}
+	unset := bson.M{}
+	for field, value := range map[string]string{
+		"assigneeType":    string(task.AssigneeType),
+		"assignedSection": task.AssignedSection,
+		"assignedRole":    string(task.AssignedRole),
+	} {
+		if value != "" {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+	if task.DueDate != nil {
+		set["dueDate"] = task.DueDate
+	} else {
+		unset["dueDate"] = ""
+	}
+	if task.CompletedAt != nil {
+		set["completedAt"] = task.CompletedAt
+	} else {
+		unset["completedAt"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": task.ID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Delete removes a task.
+// It fails with mongo.ErrNoDocuments if the task does not exist.
+func (r *TaskMongoRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Delete")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// AddChecklistItem appends an item to a task's checklist.
+// It fails with mongo.ErrNoDocuments if the task does not exist.
+func (r *TaskMongoRepository) AddChecklistItem(ctx context.Context, taskID primitive.ObjectID, item models.ChecklistItem) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "AddChecklistItem")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": taskID}, bson.M{
+		"$push": bson.M{"checklist": item},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// UpdateChecklistItem replaces an item of a task's checklist.
+// It fails with mongo.ErrNoDocuments if the task has no such item.
+func (r *TaskMongoRepository) UpdateChecklistItem(ctx context.Context, taskID primitive.ObjectID, item models.ChecklistItem) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "UpdateChecklistItem")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": taskID, "checklist._id": item.ID}, bson.M{
+		"$set": bson.M{"checklist.$": item, "updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteChecklistItem removes an item from a task's checklist.
+// It fails with mongo.ErrNoDocuments if the task has no such item.
+func (r *TaskMongoRepository) DeleteChecklistItem(ctx context.Context, taskID, itemID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "DeleteChecklistItem")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": taskID, "checklist._id": itemID}, bson.M{
+		"$pull": bson.M{"checklist": bson.M{"_id": itemID}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// AddCompletion records that a member finished their part of a group task.
+// A member who already finished it keeps their first completion.
+func (r *TaskMongoRepository) AddCompletion(ctx context.Context, taskID primitive.ObjectID, completion models.TaskCompletion) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "AddCompletion")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.UpdateOne(ctx,
+		bson.M{"_id": taskID, "completions.userId": bson.M{"$ne": completion.UserID}},
+		bson.M{"$push": bson.M{"completions": completion}},
+	)
+	return err
+}
+
+// RemoveCompletion forgets that a member finished their part of a group task
+func (r *TaskMongoRepository) RemoveCompletion(ctx context.Context, taskID, userID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "RemoveCompletion")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": taskID}, bson.M{"$pull": bson.M{"completions": bson.M{"userId": userID}}})
+	return err
+}
+
+// FindOpenDueBetween finds the tasks that are not completed and are due after from and no later than to, earliest first
+func (r *TaskMongoRepository) FindOpenDueBetween(ctx context.Context, from, to time.Time) ([]*models.Task, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindOpenDueBetween")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cur, err := coll.Find(ctx,
+		bson.M{
+			"dueDate": bson.M{"$gt": from, "$lte": to},
+			"status":  bson.M{"$ne": models.TaskStatusCompleted},
+		},
+		options.Find().SetSort(bson.D{{Key: "dueDate", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []*models.Task{}
+	if err := cur.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// LastRank returns the rank of the bottom task in the Kanban column of a status, or "" if the column is empty
+func (r *TaskMongoRepository) LastRank(ctx context.Context, status models.TaskStatus) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "LastRank")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var task models.Task
+	opts := options.FindOne().SetSort(bson.D{{Key: "rank", Value: -1}}).SetProjection(bson.M{"rank": 1})
+	err := coll.FindOne(ctx, bson.M{"status": status}, opts).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return task.Rank, nil
+}
+
+// HasTaskBetween reports whether a task other than except is ranked strictly between before and after
+// in the Kanban column of a status. An empty before is the start of the column and an empty after its end.
+// Only tasks the viewer can see are counted; viewer is nil for admins.
+func (r *TaskMongoRepository) HasTaskBetween(ctx context.Context, status models.TaskStatus, before, after string, except primitive.ObjectID, viewer *models.User) (bool, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "HasTaskBetween")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	rank := bson.M{"$gt": before}
+	if after != "" {
+		rank["$lt"] = after
+	}
+	match := bson.M{"status": status, "rank": rank, "_id": bson.M{"$ne": except}}
+	if viewer != nil {
+		match["$or"] = visibleTo(viewer)
+	}
+	err := coll.FindOne(ctx,
+		match,
+		options.FindOne().SetProjection(bson.M{"_id": 1}),
+	).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RankAtEnd returns a rank that places a task at the bottom of the Kanban column of a status.
+// The rank is unique, so tasks ranked at the same moment do not share it.
+func RankAtEnd(ctx context.Context, tasks TaskRepository, status models.TaskStatus) (string, error) {
+	last, err := tasks.LastRank(ctx, status)
+	if err != nil {
+		return "", err
+	}
+	return models.UniqueRankBetween(last, "")
+}
+
+// FindByIDs finds the tasks with the given IDs; IDs of deleted tasks are skipped
+func (r *TaskMongoRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Task, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByIDs")
+	defer end()
+
+	tasks := []*models.Task{}
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cur, err := coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	if err := cur.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// FindBlocking finds the tasks that wait on a task, oldest first
+func (r *TaskMongoRepository) FindBlocking(ctx context.Context, blockerID primitive.ObjectID) ([]*models.Task, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindBlocking")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cur, err := coll.Find(ctx, bson.M{"blockedBy": blockerID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []*models.Task{}
+	if err := cur.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// AddBlocker makes a task wait on another. Adding a blocker twice has no effect.
+// It fails with mongo.ErrNoDocuments if the task does not exist, or ErrTooManyBlockers if it is full.
+func (r *TaskMongoRepository) AddBlocker(ctx context.Context, taskID, blockerID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "AddBlocker")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	// The size check is part of the filter so concurrent requests cannot overfill the list
+	full := fmt.Sprintf("blockedBy.%d", models.MaxTaskBlockers-1)
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": taskID, "$or": bson.A{bson.M{full: bson.M{"$exists": false}}, bson.M{"blockedBy": blockerID}}},
+		bson.M{"$addToSet": bson.M{"blockedBy": blockerID}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	n, err := coll.CountDocuments(ctx, bson.M{"_id": taskID})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return ErrTooManyBlockers
+}
+
+// RemoveBlocker stops a task waiting on another.
+// It fails with mongo.ErrNoDocuments if the task does not exist.
+func (r *TaskMongoRepository) RemoveBlocker(ctx context.Context, taskID, blockerID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "RemoveBlocker")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": taskID}, bson.M{"$pull": bson.M{"blockedBy": blockerID}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// RemoveBlockerFromAll removes a deleted task from the tasks that waited on it
+func (r *TaskMongoRepository) RemoveBlockerFromAll(ctx context.Context, blockerID primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "RemoveBlockerFromAll")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.UpdateMany(ctx, bson.M{"blockedBy": blockerID}, bson.M{"$pull": bson.M{"blockedBy": blockerID}})
+	return err
+}
@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TaskTemplateRepository defines the methods for task template data access
+type TaskTemplateRepository interface {
+	Create(ctx context.Context, template *models.TaskTemplate) (string, error)
+	FindAll(ctx context.Context) ([]*models.TaskTemplate, error)
+	FindByID(ctx context.Context, id string) (*models.TaskTemplate, error)
+	FindDue(ctx context.Context, now time.Time) ([]*models.TaskTemplate, error)
+	Update(ctx context.Context, template *models.TaskTemplate) error
+	Advance(ctx context.Context, template *models.TaskTemplate, dueAt time.Time) (bool, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// TaskTemplateMongoRepository implements TaskTemplateRepository for MongoDB
+type TaskTemplateMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewTaskTemplateMongoRepository creates a new TaskTemplateMongoRepository
+func NewTaskTemplateMongoRepository(client *mongo.Client, db string) TaskTemplateRepository {
+	return &TaskTemplateMongoRepository{
+		db:         db,
+		collection: "task_templates",
+		client:     client,
+	}
+}
+
+// Create stores a new template
+func (r *TaskTemplateMongoRepository) Create(ctx context.Context, template *models.TaskTemplate) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, template)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	template.ID = id
+	return id.Hex(), nil
+}
+
+// FindAll returns all templates ordered by title
+func (r *TaskTemplateMongoRepository) FindAll(ctx context.Context) ([]*models.TaskTemplate, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindAll")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "title", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []*models.TaskTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// FindByID finds a template by ID. It returns nil if the ID is invalid or no template has it.
+func (r *TaskTemplateMongoRepository) FindByID(ctx context.Context, id string) (*models.TaskTemplate, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var template models.TaskTemplate
+	err = coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&template)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// FindDue finds the active templates whose next task should have been created by now
+func (r *TaskTemplateMongoRepository) FindDue(ctx context.Context, now time.Time) ([]*models.TaskTemplate, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindDue")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cursor, err := coll.Find(ctx, bson.M{"active": true, "nextRunAt": bson.M{"$lte": now}},
+		options.Find().SetSort(bson.D{{Key: "nextRunAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []*models.TaskTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// scheduleUpdate sets or unsets the schedule fields of a template
+func scheduleUpdate(template *models.TaskTemplate, set bson.M) bson.M {
+	unset := bson.M{}
+	for field, value := range map[string]*time.Time{
+		"nextDueAt": template.NextDueAt,
+		"nextRunAt": template.NextRunAt,
+		"lastDueAt": template.LastDueAt,
+	} {
+		if value != nil {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// Update saves the editable fields and schedule of a template.
+// It fails with mongo.ErrNoDocuments if the template does not exist.
+func (r *TaskTemplateMongoRepository) Update(ctx context.Context, template *models.TaskTemplate) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Update")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": template.ID}, scheduleUpdate(template, bson.M{
+		"title":           template.Title,
+		"description":     template.Description,
+		"checklist":       template.Checklist,
+		"assigneeType":    template.AssigneeType,
+		"assignedTo":      template.AssignedTo,
+		"assignedSection": template.AssignedSection,
+		"assignedRole":    template.AssignedRole,
+		"priority":        template.Priority,
+		"labels":          template.Labels,
+		"recurrence":      template.Recurrence,
+		"leadDays":        template.LeadDays,
+		"active":          template.Active,
+		"updatedAt":       template.UpdatedAt,
+	}))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Advance saves the schedule of a template after the task due at dueAt was created.
+// It reports false without changing anything if the template was edited or advanced
+// by another replica since it was read, so each due date is only advanced past once.
+func (r *TaskTemplateMongoRepository) Advance(ctx context.Context, template *models.TaskTemplate, dueAt time.Time) (bool, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Advance")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": template.ID, "nextDueAt": dueAt}, scheduleUpdate(template, bson.M{}))
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// Delete removes a template; the tasks already created from it are kept.
+// It fails with mongo.ErrNoDocuments if the template does not exist.
+func (r *TaskTemplateMongoRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return mongo.ErrNoDocuments
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Delete")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -2,9 +2,15 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UserRepository defines the methods for user data access
@@ -13,9 +19,17 @@ type UserRepository interface {
 	FindByUsername(ctx context.Context, username string) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindAll(ctx context.Context) ([]*models.User, error)
+	List(ctx context.Context, filter models.UserFilter, query models.ListQuery) (*models.ListResult[*models.User], error)
 	Create(ctx context.Context, user *models.User) (string, error)
+	CreateMany(ctx context.Context, users []*models.User) error
+	DeleteMany(ctx context.Context, ids []primitive.ObjectID) error
 	Update(ctx context.Context, id string, user *models.User) error
 	Delete(ctx context.Context, id string) error
+	UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error)
+	RecordTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	StartTwoFactorEnrollment(ctx context.Context, id primitive.ObjectID, secret string) error
+	EnableTwoFactor(ctx context.Context, id primitive.ObjectID, secret string, recoveryCodes []string) error
+	DisableTwoFactor(ctx context.Context, id primitive.ObjectID) error
 }
 
 // UserMongoRepository implements UserRepository for MongoDB
@@ -34,22 +48,69 @@ func NewUserMongoRepository(client *mongo.Client, db string) UserRepository {
 	}
 }
 
-// FindByID finds a user by ID
+// FindByID finds a user by ID. It returns nil if there is no such user.
 func (r *UserMongoRepository) FindByID(ctx context.Context, id string) (*models.User, error) {
-	// Implementation will be added later
-	return nil, nil
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var user models.User
+	err = coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-// FindByUsername finds a user by username
+// FindByUsername finds a user by username. It returns nil if there is no such user.
 func (r *UserMongoRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
-	// Implementation will be added later
-	return nil, nil
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByUsername")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var user models.User
+	err := coll.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-// FindByEmail finds a user by email
+// emailCollation compares email addresses without regard to case. It is the collation of the
+// email_unique index, which the lookup must use to be served by the index.
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// FindByEmail finds a user by email address, ignoring case. It returns nil if there is no such user.
 func (r *UserMongoRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
-	// Implementation will be added later
-	return nil, nil
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByEmail")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var user models.User
+	// The conditions of the partial index are repeated so the query planner can use it
+	filter := bson.M{"email": bson.M{"$eq": email, "$type": "string", "$gt": ""}}
+	err := coll.FindOne(ctx, filter, options.FindOne().SetCollation(emailCollation)).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 // FindAll finds all users
@@ -58,15 +119,241 @@ func (r *UserMongoRepository) FindAll(ctx context.Context) ([]*models.User, erro
 	return nil, nil
 }
 
-// Create creates a new user
+// userListSpec maps the user listing's sort keys and search fields onto the users collection
+var userListSpec = listSpec{
+	sortFields: map[string]string{
+		"name":      "fullName",
+		"username":  "username",
+		"createdAt": "createdAt",
+		"role":      "role",
+	},
+	searchFields: []string{"username", "fullName", "email"},
+}
+
+// List finds a page of users matching the filter
+func (r *UserMongoRepository) List(ctx context.Context, filter models.UserFilter, query models.ListQuery) (*models.ListResult[*models.User], error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "List")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	match := bson.M{}
+	if filter.Role != "" {
+		match["role"] = filter.Role
+	}
+	if filter.Section != "" {
+		match["section"] = filter.Section
+	}
+	if filter.Instrument != "" {
+		match["instrument"] = filter.Instrument
+	}
+
+	return findPage[*models.User](ctx, coll, match, query, userListSpec)
+}
+
+// Create stores a new user and sets its ID
 func (r *UserMongoRepository) Create(ctx context.Context, user *models.User) (string, error) {
-	// Implementation will be added later
-	return "", nil
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	user.ID = id
+	return id.Hex(), nil
+}
+
+// CreateMany stores new users and sets their IDs. Either every user is stored or,
+// if one insert fails, the users already inserted are removed again and none are.
+func (r *UserMongoRepository) CreateMany(ctx context.Context, users []*models.User) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "CreateMany")
+	defer end()
+
+	if len(users) == 0 {
+		return nil
+	}
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	docs := make([]interface{}, len(users))
+	ids := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		user.ID = primitive.NewObjectID()
+		docs[i] = user
+		ids[i] = user.ID
+	}
+
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		if _, cleanupErr := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); cleanupErr != nil {
+			err = errors.Join(err, cleanupErr)
+		}
+		for _, user := range users {
+			user.ID = primitive.NilObjectID
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteMany removes the users with the given IDs
+func (r *UserMongoRepository) DeleteMany(ctx context.Context, ids []primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "DeleteMany")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	_, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
 }
 
-// Update updates an existing user
+// Update saves an existing user, including its password hash. The 2FA state is left alone:
+// it is changed with its own conditional updates, so a stale copy cannot bring back a used code.
+// It fails with mongo.ErrNoDocuments if the user does not exist.
 func (r *UserMongoRepository) Update(ctx context.Context, id string, user *models.User) error {
-	// Implementation will be added later
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return mongo.ErrNoDocuments
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Update")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	set := bson.M{
+		"username":  user.Username,
+		"fullName":  user.FullName,
+		"email":     user.Email,
+		"password":  user.Password,
+		"role":      user.Role,
+		"updatedAt": user.UpdatedAt,
+	}
+	// Empty optional fields are removed, so the partial unique index on oidcSubject ignores them
+	unset := bson.M{}
+	for field, value := range map[string]string{
+		"section":     user.Section,
+		"instrument":  user.Instrument,
+		"oidcSubject": user.OIDCSubject,
+	} {
+		if value != "" {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// UseRecoveryCode removes a hashed recovery code from a user. It reports false if the user
+// does not have the code, so each code is accepted once even by concurrent logins.
+func (r *UserMongoRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "UseRecoveryCode")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": id, "recoveryCodes": hash},
+		bson.M{"$pull": bson.M{"recoveryCodes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// RecordTOTPStep saves the time step of an accepted TOTP code. It reports false if the step
+// or a later one was already used, so each code is accepted once even by concurrent logins.
+func (r *UserMongoRepository) RecordTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "RecordTOTPStep")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"totpLastStep": bson.M{"$exists": false}},
+			bson.M{"totpLastStep": bson.M{"$lt": step}},
+		}},
+		bson.M{"$set": bson.M{"totpLastStep": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// StartTwoFactorEnrollment gives a user a new TOTP secret that is not yet enabled.
+// It fails with mongo.ErrNoDocuments if the user does not exist or already has 2FA enabled.
+func (r *UserMongoRepository) StartTwoFactorEnrollment(ctx context.Context, id primitive.ObjectID, secret string) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "StartTwoFactorEnrollment")
+	defer end()
+
+	return r.updateTwoFactor(ctx,
+		bson.M{"_id": id, "twoFactorEnabled": bson.M{"$ne": true}},
+		bson.M{
+			"$set":   bson.M{"totpSecret": secret, "updatedAt": time.Now()},
+			"$unset": bson.M{"totpLastStep": "", "recoveryCodes": ""},
+		},
+	)
+}
+
+// EnableTwoFactor enables 2FA with the secret being enrolled and stores the hashed recovery codes.
+// It fails with mongo.ErrNoDocuments if 2FA is already enabled or the enrollment was restarted
+// with another secret since it was verified.
+func (r *UserMongoRepository) EnableTwoFactor(ctx context.Context, id primitive.ObjectID, secret string, recoveryCodes []string) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "EnableTwoFactor")
+	defer end()
+
+	return r.updateTwoFactor(ctx,
+		bson.M{"_id": id, "twoFactorEnabled": bson.M{"$ne": true}, "totpSecret": secret},
+		bson.M{"$set": bson.M{"twoFactorEnabled": true, "recoveryCodes": recoveryCodes, "updatedAt": time.Now()}},
+	)
+}
+
+// DisableTwoFactor turns 2FA off and removes the secret and recovery codes.
+// It fails with mongo.ErrNoDocuments if the user does not have 2FA enabled.
+func (r *UserMongoRepository) DisableTwoFactor(ctx context.Context, id primitive.ObjectID) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "DisableTwoFactor")
+	defer end()
+
+	return r.updateTwoFactor(ctx,
+		bson.M{"_id": id, "twoFactorEnabled": true},
+		bson.M{
+			"$set":   bson.M{"twoFactorEnabled": false, "updatedAt": time.Now()},
+			"$unset": bson.M{"totpSecret": "", "totpLastStep": "", "recoveryCodes": ""},
+		},
+	)
+}
+
+// updateTwoFactor applies a 2FA update to the user matching filter, or fails with mongo.ErrNoDocuments
+func (r *UserMongoRepository) updateTwoFactor(ctx context.Context, filter, update bson.M) error {
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
 
@@ -74,4 +361,21 @@ func (r *UserMongoRepository) Update(ctx context.Context, id string, user *model
 func (r *UserMongoRepository) Delete(ctx context.Context, id string) error {
 	// Implementation will be added later
 	return nil
-}
\ No newline at end of file
+}
+
+// ListAllUsers pages through every user matching filter, oldest account first
+func ListAllUsers(ctx context.Context, users UserRepository, filter models.UserFilter) ([]*models.User, error) {
+	var all []*models.User
+	query := models.ListQuery{Sort: "createdAt", Order: models.SortAsc, Limit: models.MaxListLimit}
+	for {
+		page, err := users.List(ctx, filter, query)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+		if !page.HasMore {
+			return all, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookDeliveryRepository defines the methods for webhook delivery data access
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.WebhookDelivery) (string, error)
+	FindByID(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	List(ctx context.Context, filter models.WebhookDeliveryFilter, query models.ListQuery) (*models.ListResult[*models.WebhookDelivery], error)
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt models.WebhookAttempt, status models.WebhookDeliveryStatus, nextAttemptAt time.Time) error
+}
+
+// WebhookDeliveryMongoRepository implements WebhookDeliveryRepository for MongoDB
+type WebhookDeliveryMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewWebhookDeliveryMongoRepository creates a new WebhookDeliveryMongoRepository
+func NewWebhookDeliveryMongoRepository(client *mongo.Client, db string) WebhookDeliveryRepository {
+	return &WebhookDeliveryMongoRepository{
+		db:         db,
+		collection: "webhook_deliveries",
+		client:     client,
+	}
+}
+
+// webhookDeliveryListSpec maps the delivery log's sort keys onto the webhook_deliveries collection
+var webhookDeliveryListSpec = listSpec{
+	sortFields: map[string]string{
+		"createdAt": "createdAt",
+	},
+	searchFields: []string{"eventId", "eventType"},
+}
+
+// Create stores a new delivery
+func (r *WebhookDeliveryMongoRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, delivery)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	delivery.ID = id
+	return id.Hex(), nil
+}
+
+// FindByID finds a delivery by ID. It returns nil if there is no such delivery.
+func (r *WebhookDeliveryMongoRepository) FindByID(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var delivery models.WebhookDelivery
+	err = coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// List finds a page of deliveries matching the filter
+func (r *WebhookDeliveryMongoRepository) List(ctx context.Context, filter models.WebhookDeliveryFilter, query models.ListQuery) (*models.ListResult[*models.WebhookDelivery], error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "List")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	match := bson.M{}
+	if !filter.SubscriptionID.IsZero() {
+		match["subscriptionId"] = filter.SubscriptionID
+	}
+	if filter.Status != "" {
+		match["status"] = filter.Status
+	}
+
+	return findPage[*models.WebhookDelivery](ctx, coll, match, query, webhookDeliveryListSpec)
+}
+
+// ClaimDue locks the pending delivery that has waited longest for its attempt and returns it.
+// The lock expires after lease so a replica that dies mid-attempt does not strand the delivery.
+// It returns nil when no delivery is due.
+func (r *WebhookDeliveryMongoRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "ClaimDue")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var delivery models.WebhookDelivery
+	err := coll.FindOneAndUpdate(ctx,
+		bson.M{
+			"status":        models.WebhookDeliveryPending,
+			"nextAttemptAt": bson.M{"$lte": now},
+			"lockedUntil":   bson.M{"$lte": now},
+		},
+		bson.M{"$set": bson.M{"lockedUntil": now.Add(lease)}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// RecordAttempt appends an attempt to a delivery, sets its resulting status and releases its lock
+func (r *WebhookDeliveryMongoRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt models.WebhookAttempt, status models.WebhookDeliveryStatus, nextAttemptAt time.Time) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "RecordAttempt")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	set := bson.M{
+		"status":        status,
+		"nextAttemptAt": nextAttemptAt,
+		"lockedUntil":   time.Time{},
+	}
+	if status == models.WebhookDeliverySucceeded {
+		set["deliveredAt"] = attempt.At
+	}
+
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":  set,
+		"$push": bson.M{"attempts": attempt},
+	})
+	return err
+}
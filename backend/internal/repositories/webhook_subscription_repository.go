@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookSubscriptionRepository defines the methods for webhook subscription data access
+type WebhookSubscriptionRepository interface {
+	Create(ctx context.Context, subscription *models.WebhookSubscription) (string, error)
+	FindAll(ctx context.Context) ([]*models.WebhookSubscription, error)
+	FindByID(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	FindActiveByEvent(ctx context.Context, eventType models.WebhookEventType) ([]*models.WebhookSubscription, error)
+	Update(ctx context.Context, subscription *models.WebhookSubscription) error
+	Delete(ctx context.Context, id string) error
+}
+
+// WebhookSubscriptionMongoRepository implements WebhookSubscriptionRepository for MongoDB
+type WebhookSubscriptionMongoRepository struct {
+	db         string
+	collection string
+	client     *mongo.Client // MongoDB client
+}
+
+// NewWebhookSubscriptionMongoRepository creates a new WebhookSubscriptionMongoRepository
+func NewWebhookSubscriptionMongoRepository(client *mongo.Client, db string) WebhookSubscriptionRepository {
+	return &WebhookSubscriptionMongoRepository{
+		db:         db,
+		collection: "webhook_subscriptions",
+		client:     client,
+	}
+}
+
+// Create stores a new subscription
+func (r *WebhookSubscriptionMongoRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) (string, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Create")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.InsertOne(ctx, subscription)
+	if err != nil {
+		return "", err
+	}
+
+	id := res.InsertedID.(primitive.ObjectID)
+	subscription.ID = id
+	return id.Hex(), nil
+}
+
+// FindAll returns all subscriptions, newest first
+func (r *WebhookSubscriptionMongoRepository) FindAll(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindAll")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := []*models.WebhookSubscription{}
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// FindByID finds a subscription by ID. It returns nil if there is no such subscription.
+func (r *WebhookSubscriptionMongoRepository) FindByID(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindByID")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	var subscription models.WebhookSubscription
+	err = coll.FindOne(ctx, bson.M{"_id": objectID}).Decode(&subscription)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// FindActiveByEvent finds the active subscriptions that receive an event type
+func (r *WebhookSubscriptionMongoRepository) FindActiveByEvent(ctx context.Context, eventType models.WebhookEventType) ([]*models.WebhookSubscription, error) {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "FindActiveByEvent")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	cursor, err := coll.Find(ctx, bson.M{"active": true, "events": eventType})
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := []*models.WebhookSubscription{}
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Update saves the name, URL, event types and state of a subscription.
+// It fails with mongo.ErrNoDocuments if the subscription does not exist.
+func (r *WebhookSubscriptionMongoRepository) Update(ctx context.Context, subscription *models.WebhookSubscription) error {
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Update")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": subscription.ID}, bson.M{"$set": bson.M{
+		"name":      subscription.Name,
+		"url":       subscription.URL,
+		"events":    subscription.Events,
+		"active":    subscription.Active,
+		"updatedAt": subscription.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Delete removes a subscription.
+// It fails with mongo.ErrNoDocuments if the subscription does not exist.
+func (r *WebhookSubscriptionMongoRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return mongo.ErrNoDocuments
+	}
+
+	ctx, end := tracing.StartMongoOperation(ctx, r.collection, "Delete")
+	defer end()
+
+	coll := r.client.Database(r.db).Collection(r.collection)
+
+	res, err := coll.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
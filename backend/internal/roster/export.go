@@ -0,0 +1,260 @@
+package roster
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is a roster export file format
+type Format string
+
+const (
+	// FormatCSV exports a UTF-8 CSV file
+	FormatCSV Format = "csv"
+	// FormatXLSX exports an Excel workbook
+	FormatXLSX Format = "xlsx"
+	// FormatPDF exports a printable table
+	FormatPDF Format = "pdf"
+)
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatXLSX, FormatPDF:
+		return f, nil
+	case "":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, expected csv, xlsx or pdf", s)
+	}
+}
+
+// Column is an exportable user attribute.
+// The password hash is deliberately not available as a column.
+type Column struct {
+	Key    string
+	Header string
+	Value  func(u *models.User) string
+}
+
+// Columns lists every exportable column in their default order
+var Columns = []Column{
+	{Key: "username", Header: "Username", Value: func(u *models.User) string { return u.Username }},
+	{Key: "fullName", Header: "Full Name", Value: func(u *models.User) string { return u.FullName }},
+	{Key: "email", Header: "Email", Value: func(u *models.User) string { return u.Email }},
+	{Key: "role", Header: "Role", Value: func(u *models.User) string { return string(u.Role) }},
+	{Key: "section", Header: "Section", Value: func(u *models.User) string { return u.Section }},
+	{Key: "instrument", Header: "Instrument", Value: func(u *models.User) string { return u.Instrument }},
+	{Key: "createdAt", Header: "Created At", Value: func(u *models.User) string { return u.CreatedAt.Format(time.DateOnly) }},
+}
+
+// defaultColumns are exported when no columns are requested
+var defaultColumns = []string{"fullName", "section", "instrument", "email"}
+
+// ParseColumns resolves a comma-separated list of column keys.
+// An empty list selects the default columns.
+func ParseColumns(spec string) ([]Column, error) {
+	keys := defaultColumns
+	if strings.TrimSpace(spec) != "" {
+		keys = strings.Split(spec, ",")
+	}
+
+	columns := make([]Column, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		found := false
+		for _, column := range Columns {
+			if column.Key == key {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q", key)
+		}
+	}
+	return columns, nil
+}
+
+// ExportOptions configures a roster export
+type ExportOptions struct {
+	// Title is printed at the top of PDF exports
+	Title string
+	// FontPath is a TrueType font used for PDF exports. Without it the PDF
+	// falls back to a built-in font that cannot render Japanese names.
+	FontPath string
+}
+
+// Export writes users as a table in the given format
+func Export(w io.Writer, format Format, columns []Column, users []*models.User, opts ExportOptions) error {
+	switch format {
+	case FormatXLSX:
+		return exportXLSX(w, columns, users)
+	case FormatPDF:
+		return exportPDF(w, columns, users, opts)
+	default:
+		return exportCSV(w, columns, users)
+	}
+}
+
+// exportCSV writes a CSV file with a UTF-8 BOM so spreadsheet applications detect the encoding
+func exportCSV(w io.Writer, columns []Column, users []*models.User) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(headers(columns)); err != nil {
+		return err
+	}
+	for _, u := range users {
+		row := values(columns, u)
+		for i, v := range row {
+			row[i] = escapeFormula(v)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// escapeFormula prefixes a cell that a spreadsheet application would run as a formula with a quote,
+// so a member cannot put a formula in their profile that runs on the exporting admin's computer
+func escapeFormula(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// exportXLSX writes a single-sheet workbook. Its cells are stored as text, which is never run as a formula.
+func exportXLSX(w io.Writer, columns []Column, users []*models.User) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Roster"
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	writeRow := func(row int, cells []string) error {
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, len(cells))
+		for i, v := range cells {
+			values[i] = v
+		}
+		return f.SetSheetRow(sheet, cell, &values)
+	}
+
+	if err := writeRow(1, headers(columns)); err != nil {
+		return err
+	}
+	for i, u := range users {
+		if err := writeRow(i+2, values(columns, u)); err != nil {
+			return err
+		}
+	}
+
+	return f.Write(w)
+}
+
+// exportPDF writes a landscape A4 table, repeating the header row on each page
+func exportPDF(w io.Writer, columns []Column, users []*models.User, opts ExportOptions) error {
+	const (
+		margin     = 10.0
+		rowHeight  = 7.0
+		fontFamily = "roster"
+	)
+
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, margin)
+
+	family := "Helvetica"
+	text := pdf.UnicodeTranslatorFromDescriptor("")
+	if opts.FontPath != "" {
+		pdf.AddUTF8Font(fontFamily, "", opts.FontPath)
+		family = fontFamily
+		text = func(s string) string { return s }
+	}
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	cellWidth := (pageWidth - 2*margin) / float64(len(columns))
+
+	header := func() {
+		pdf.SetFillColor(230, 230, 230)
+		for _, h := range headers(columns) {
+			pdf.CellFormat(cellWidth, rowHeight, text(h), "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.AddPage()
+	pdf.SetFont(family, "", 14)
+	title := opts.Title
+	if title == "" {
+		title = "Roster"
+	}
+	pdf.CellFormat(0, 10, text(fmt.Sprintf("%s (%d)", title, len(users))), "", 1, "L", false, 0, "")
+	pdf.SetFont(family, "", 9)
+	header()
+
+	for _, u := range users {
+		if pdf.GetY()+rowHeight > pageHeight-margin {
+			pdf.AddPage()
+			header()
+		}
+		for _, v := range values(columns, u) {
+			pdf.CellFormat(cellWidth, rowHeight, text(v), "1", 0, "L", false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+// headers returns the header row for the columns
+func headers(columns []Column) []string {
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = column.Header
+	}
+	return row
+}
+
+// values returns the cells of a user's row
+func values(columns []Column, u *models.User) []string {
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = column.Value(u)
+	}
+	return row
+}
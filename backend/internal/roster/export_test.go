@@ -0,0 +1,114 @@
+package roster
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+)
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns("email, username")
+	if err != nil {
+		t.Fatalf("Error parsing columns: %v", err)
+	}
+	if len(columns) != 2 || columns[0].Key != "email" || columns[1].Key != "username" {
+		t.Errorf("Unexpected columns: %+v", columns)
+	}
+
+	if _, err := ParseColumns("username,password"); err == nil {
+		t.Error("Expected password to be rejected as a column")
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	users := []*models.User{
+		{Username: "hanako", FullName: "Sato Hanako", Email: "hanako@example.com", Password: "$2a$10$secret", Section: "Brass"},
+		{Username: "taro", FullName: "Suzuki, Taro", Email: "taro@example.com", Password: "$2a$10$secret", Section: "Percussion"},
+	}
+	columns, _ := ParseColumns("username,fullName,section")
+
+	for _, tt := range []struct {
+		format   Format
+		filename string
+	}{
+		{FormatCSV, "roster.csv"},
+		{FormatXLSX, "roster.xlsx"},
+	} {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, tt.format, columns, users, ExportOptions{}); err != nil {
+				t.Fatalf("Error exporting: %v", err)
+			}
+
+			if bytes.Contains(buf.Bytes(), []byte("$2a$10$secret")) {
+				t.Error("Export contains a password hash")
+			}
+
+			table, err := ReadTable(tt.filename, &buf)
+			if err != nil {
+				t.Fatalf("Error reading export back: %v", err)
+			}
+
+			want := [][]string{
+				{"Username", "Full Name", "Section"},
+				{"hanako", "Sato Hanako", "Brass"},
+				{"taro", "Suzuki, Taro", "Percussion"},
+			}
+			if len(table) != len(want) {
+				t.Fatalf("Expected %d rows, got %d", len(want), len(table))
+			}
+			for i := range want {
+				if strings.Join(table[i], "|") != strings.Join(want[i], "|") {
+					t.Errorf("Row %d: expected %v, got %v", i, want[i], table[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExportEscapesFormulas(t *testing.T) {
+	users := []*models.User{
+		{FullName: "=HYPERLINK(\"https://evil.example\",\"Sato\")", Section: "+Brass", Instrument: "-1+2"},
+		{FullName: "@SUM(A1:A2)", Section: "\tBrass", Instrument: "\rTuba"},
+		{FullName: "Suzuki Taro", Section: "Percussion", Instrument: "Snare"},
+	}
+	columns, _ := ParseColumns("fullName,section,instrument")
+
+	var buf bytes.Buffer
+	if err := Export(&buf, FormatCSV, columns, users, ExportOptions{}); err != nil {
+		t.Fatalf("Error exporting: %v", err)
+	}
+	table, err := ReadTable("roster.csv", &buf)
+	if err != nil {
+		t.Fatalf("Error reading export back: %v", err)
+	}
+
+	want := [][]string{
+		{"'=HYPERLINK(\"https://evil.example\",\"Sato\")", "'+Brass", "'-1+2"},
+		{"'@SUM(A1:A2)", "'\tBrass", "'\rTuba"},
+		{"Suzuki Taro", "Percussion", "Snare"},
+	}
+	for i := range want {
+		if strings.Join(table[i+1], "|") != strings.Join(want[i], "|") {
+			t.Errorf("Row %d: expected %q, got %q", i+1, want[i], table[i+1])
+		}
+	}
+}
+
+func TestExportPDF(t *testing.T) {
+	users := make([]*models.User, 60)
+	for i := range users {
+		users[i] = &models.User{Username: "member", FullName: "Band Member"}
+	}
+	columns, _ := ParseColumns("")
+
+	var buf bytes.Buffer
+	if err := Export(&buf, FormatPDF, columns, users, ExportOptions{Title: "Bus 1"}); err != nil {
+		t.Fatalf("Error exporting PDF: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Error("Export is not a PDF document")
+	}
+}
@@ -0,0 +1,248 @@
+// Package roster reads and writes member rosters as spreadsheets
+package roster
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"path/filepath"
+	"strings"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// Field names a user field that a roster column can be mapped to
+type Field string
+
+const (
+	// FieldUsername maps to models.User.Username
+	FieldUsername Field = "username"
+	// FieldFullName maps to models.User.FullName
+	FieldFullName Field = "fullName"
+	// FieldEmail maps to models.User.Email
+	FieldEmail Field = "email"
+	// FieldRole maps to models.User.Role
+	FieldRole Field = "role"
+	// FieldSection maps to models.User.Section
+	FieldSection Field = "section"
+	// FieldInstrument maps to models.User.Instrument
+	FieldInstrument Field = "instrument"
+)
+
+// requiredFields must be mapped to a column for an import to run
+var requiredFields = []Field{FieldUsername, FieldFullName, FieldEmail}
+
+// fieldAliases are the normalized header names recognized by DefaultMapping
+var fieldAliases = map[string]Field{
+	"username":     FieldUsername,
+	"user":         FieldUsername,
+	"login":        FieldUsername,
+	"fullname":     FieldFullName,
+	"name":         FieldFullName,
+	"email":        FieldEmail,
+	"mail":         FieldEmail,
+	"emailaddress": FieldEmail,
+	"role":         FieldRole,
+	"section":      FieldSection,
+	"part":         FieldSection,
+	"instrument":   FieldInstrument,
+}
+
+// ErrUnsupportedFormat is returned for uploads that are neither CSV nor XLSX
+var ErrUnsupportedFormat = errors.New("unsupported file format, expected .csv or .xlsx")
+
+// Mapping maps spreadsheet column headers to user fields
+type Mapping map[string]Field
+
+// Row is a single roster line converted into a user
+type Row struct {
+	// Line is the 1-based spreadsheet row number, counting the header
+	Line int
+	User *models.User
+}
+
+// ReadTable reads a CSV or XLSX upload into rows of cells.
+// For XLSX files only the first sheet is read.
+func ReadTable(filename string, r io.Reader) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		// Spreadsheet applications often prefix CSV exports with a UTF-8 BOM
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+		}
+		return rows, nil
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("workbook has no sheets")
+		}
+		return f.GetRows(sheets[0])
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// DefaultMapping guesses a mapping from the header row by matching common column names
+func DefaultMapping(header []string) Mapping {
+	mapping := Mapping{}
+	for _, column := range header {
+		if field, ok := fieldAliases[normalizeHeader(column)]; ok {
+			mapping[column] = field
+		}
+	}
+	return mapping
+}
+
+// Parse converts a table whose first row is the header into roster rows.
+// Cells are trimmed; validation is left to Validate.
+func Parse(table [][]string, mapping Mapping) ([]Row, error) {
+	if len(table) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	columns := map[Field]int{}
+	for i, column := range table[0] {
+		if field, ok := mapping[column]; ok {
+			columns[field] = i
+		}
+	}
+
+	for _, field := range requiredFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("no column mapped to %s", field)
+		}
+	}
+
+	cell := func(record []string, field Field) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []Row
+	for i, record := range table[1:] {
+		if isBlank(record) {
+			continue
+		}
+
+		role := models.Role(strings.ToLower(cell(record, FieldRole)))
+		if role == "" {
+			role = models.GeneralRole
+		}
+
+		rows = append(rows, Row{
+			Line: i + 2,
+			User: &models.User{
+				Username:   cell(record, FieldUsername),
+				FullName:   cell(record, FieldFullName),
+				Email:      strings.ToLower(cell(record, FieldEmail)),
+				Role:       role,
+				Section:    cell(record, FieldSection),
+				Instrument: cell(record, FieldInstrument),
+			},
+		})
+	}
+	return rows, nil
+}
+
+// Validate checks each row for missing or malformed values and for usernames
+// or emails that appear more than once in the file
+func Validate(rows []Row) []models.RosterRowError {
+	errs := []models.RosterRowError{}
+	usernames := map[string]int{}
+	emails := map[string]int{}
+
+	for _, row := range rows {
+		u := row.User
+
+		if u.Username == "" {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldUsername), Message: "Username is required"})
+		} else if first, ok := usernames[strings.ToLower(u.Username)]; ok {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldUsername), Message: fmt.Sprintf("Duplicate username, first seen on row %d", first)})
+		} else {
+			usernames[strings.ToLower(u.Username)] = row.Line
+		}
+
+		if u.FullName == "" {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldFullName), Message: "Full name is required"})
+		}
+
+		if u.Email == "" {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldEmail), Message: "Email is required"})
+		} else if addr, err := mail.ParseAddress(u.Email); err != nil || addr.Address != u.Email {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldEmail), Message: "Invalid email address"})
+		} else if first, ok := emails[strings.ToLower(u.Email)]; ok {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldEmail), Message: fmt.Sprintf("Duplicate email, first seen on row %d", first)})
+		} else {
+			emails[strings.ToLower(u.Email)] = row.Line
+		}
+
+		if u.Role != models.AdminRole && u.Role != models.GeneralRole {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldRole), Message: "Role must be admin or general"})
+		}
+	}
+
+	return errs
+}
+
+// Conflicts reports rows whose username or email, ignoring case, belongs to an existing account
+func Conflicts(rows []Row, existing []*models.User) []models.RosterRowError {
+	usernames := map[string]bool{}
+	emails := map[string]bool{}
+	for _, u := range existing {
+		usernames[strings.ToLower(u.Username)] = true
+		if u.Email != "" {
+			emails[strings.ToLower(u.Email)] = true
+		}
+	}
+
+	errs := []models.RosterRowError{}
+	for _, row := range rows {
+		if row.User.Username != "" && usernames[strings.ToLower(row.User.Username)] {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldUsername), Message: "Username already exists"})
+		}
+		if row.User.Email != "" && emails[strings.ToLower(row.User.Email)] {
+			errs = append(errs, models.RosterRowError{Row: row.Line, Field: string(FieldEmail), Message: "Email already exists"})
+		}
+	}
+	return errs
+}
+
+// normalizeHeader lowercases a header and strips separators so "Full Name" matches "fullname"
+func normalizeHeader(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
+
+// isBlank reports whether every cell of a record is empty
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package roster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+)
+
+func TestParseCSVWithDefaultMapping(t *testing.T) {
+	csv := "\ufeffUsername,Full Name,E-mail,Section,Instrument\n" +
+		"hanako,Sato Hanako,Hanako@Example.com,Brass,Trumpet\n" +
+		",,,,\n" +
+		"taro,Suzuki Taro,taro@example.com,Percussion,Snare\n"
+
+	table, err := ReadTable("roster.csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Error reading table: %v", err)
+	}
+
+	rows, err := Parse(table, DefaultMapping(table[0]))
+	if err != nil {
+		t.Fatalf("Error parsing table: %v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+
+	first := rows[0]
+	if first.Line != 2 || first.User.Username != "hanako" || first.User.Email != "hanako@example.com" {
+		t.Errorf("Unexpected first row: line %d, %+v", first.Line, first.User)
+	}
+	if first.User.Role != models.GeneralRole {
+		t.Errorf("Expected default role %q, got %q", models.GeneralRole, first.User.Role)
+	}
+	if first.User.Section != "Brass" || first.User.Instrument != "Trumpet" {
+		t.Errorf("Section and instrument not mapped: %+v", first.User)
+	}
+
+	// The blank row is skipped but still counted in line numbers
+	if rows[1].Line != 4 {
+		t.Errorf("Expected second row on line 4, got %d", rows[1].Line)
+	}
+}
+
+func TestParseRequiresMappedColumns(t *testing.T) {
+	table := [][]string{{"Name", "Mail"}, {"Sato Hanako", "hanako@example.com"}}
+
+	if _, err := Parse(table, DefaultMapping(table[0])); err == nil {
+		t.Error("Expected an error when no column is mapped to username")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rows := []Row{
+		{Line: 2, User: &models.User{Username: "hanako", FullName: "Sato Hanako", Email: "hanako@example.com", Role: models.GeneralRole}},
+		{Line: 3, User: &models.User{Username: "Hanako", FullName: "Sato Hanako", Email: "other@example.com", Role: models.GeneralRole}},
+		{Line: 4, User: &models.User{Username: "taro", FullName: "", Email: "not-an-email", Role: "director"}},
+		{Line: 5, User: &models.User{Username: "jiro", FullName: "Suzuki Jiro", Email: "hanako@example.com", Role: models.AdminRole}},
+	}
+
+	errs := Validate(rows)
+
+	want := map[int][]string{
+		3: {"username"},
+		4: {"fullName", "email", "role"},
+		5: {"email"},
+	}
+
+	got := map[int][]string{}
+	for _, e := range errs {
+		got[e.Row] = append(got[e.Row], e.Field)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected errors on rows %v, got %v", want, got)
+	}
+	for row, fields := range want {
+		if strings.Join(got[row], ",") != strings.Join(fields, ",") {
+			t.Errorf("Row %d: expected errors on %v, got %v", row, fields, got[row])
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	existing := []*models.User{
+		{Username: "Hanako", Email: "hanako@example.com"},
+		{Username: "taro", Email: ""},
+	}
+	rows := []Row{
+		{Line: 2, User: &models.User{Username: "hanako", Email: "new@example.com"}},
+		{Line: 3, User: &models.User{Username: "jiro", Email: "HANAKO@example.com"}},
+		{Line: 4, User: &models.User{Username: "saburo", Email: "saburo@example.com"}},
+	}
+
+	errs := Conflicts(rows, existing)
+
+	if len(errs) != 2 || errs[0].Row != 2 || errs[0].Field != "username" || errs[1].Row != 3 || errs[1].Field != "email" {
+		t.Errorf("Expected the username on row 2 and the email on row 3 to conflict, got %+v", errs)
+	}
+}
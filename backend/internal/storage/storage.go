@@ -0,0 +1,140 @@
+// Package storage keeps uploaded files, such as task attachments, outside the database
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("file not found")
+
+// ErrInvalidKey is returned for keys that are empty or would escape the storage root
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage stores files under slash-separated keys chosen by the caller
+type Storage interface {
+	// Put stores the contents of r under key, replacing any file already there, and returns its size
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the file stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config configures where uploaded files are kept
+type Config struct {
+	// Dir is the directory files are stored in; it is created if it does not exist
+	Dir string `yaml:"dir"`
+	// MaxFileSizeMB is the largest file that may be uploaded, in megabytes
+	MaxFileSizeMB int `yaml:"maxFileSizeMB"`
+}
+
+// Validate checks that the settings are usable
+func (c Config) Validate() error {
+	var errs []error
+	if c.Dir == "" {
+		errs = append(errs, errors.New("dir is required"))
+	}
+	if c.MaxFileSizeMB <= 0 {
+		errs = append(errs, errors.New("maxFileSizeMB must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// MaxFileSize returns the largest file that may be uploaded, in bytes
+func (c Config) MaxFileSize() int64 {
+	return int64(c.MaxFileSizeMB) << 20
+}
+
+// LocalStorage implements Storage on the local disk
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage creates a LocalStorage rooted at dir, creating the directory if needed
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{dir: dir}, nil
+}
+
+// Put stores the contents of r under key. The file is written to a temporary name
+// and renamed into place, so readers never see a partial file.
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// Open returns the file stored under key
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the file stored under key
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path maps a key to a file below the storage root, rejecting keys that would leave it
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
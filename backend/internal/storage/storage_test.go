@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating storage: %v", err)
+	}
+
+	size, err := s.Put(ctx, "tasks/abc/def", strings.NewReader("uniform sizes"))
+	if err != nil || size != 13 {
+		t.Fatalf("Expected 13 bytes stored, got %d, %v", size, err)
+	}
+
+	f, err := s.Open(ctx, "tasks/abc/def")
+	if err != nil {
+		t.Fatalf("Error opening file: %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "uniform sizes" {
+		t.Errorf("Unexpected contents %q", data)
+	}
+
+	if err := s.Delete(ctx, "tasks/abc/def"); err != nil {
+		t.Fatalf("Error deleting file: %v", err)
+	}
+	if _, err := s.Open(ctx, "tasks/abc/def"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := s.Delete(ctx, "tasks/abc/def"); err != nil {
+		t.Errorf("Expected deleting a missing file to succeed, got %v", err)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	s, _ := NewLocalStorage(t.TempDir())
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "tasks/../../secret", "tasks//x", `tasks\x`} {
+		if _, err := s.Put(context.Background(), key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected %q to be rejected, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package taskfeed tells members about task changes, live over the change stream and as notifications
+package taskfeed
+
+import (
+	"context"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/logging"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notifier tells members about the tasks assigned to them
+type Notifier interface {
+	TaskAssigned(ctx context.Context, task *models.Task, assignees []primitive.ObjectID, actorID primitive.ObjectID) error
+}
+
+// Feed publishes task changes and notifies assignees. Failures are logged rather than returned,
+// since the change itself was already saved.
+type Feed struct {
+	users    repositories.UserRepository
+	hub      realtime.Hub
+	notifier Notifier
+}
+
+// New creates a new Feed
+func New(users repositories.UserRepository, hub realtime.Hub, notifier Notifier) *Feed {
+	return &Feed{users: users, hub: hub, notifier: notifier}
+}
+
+// AssigneeIDs returns the IDs of a task's assignees: its assignee, or every member of its group
+func (f *Feed) AssigneeIDs(ctx context.Context, task *models.Task) ([]primitive.ObjectID, error) {
+	if !task.IsGroupTask() {
+		return []primitive.ObjectID{task.AssignedTo}, nil
+	}
+
+	users, err := repositories.ListAllUsers(ctx, f.users, task.MemberFilter())
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids, nil
+}
+
+// Publish sends a task change to the members who can see the task
+func (f *Feed) Publish(ctx context.Context, action realtime.Action, task *models.Task) {
+	// Tasks for everyone are seen by everyone without listing the band
+	var members []primitive.ObjectID
+	if task.IsGroupTask() && task.AssigneeType != models.TaskAssigneeEveryone {
+		var err error
+		if members, err = f.AssigneeIDs(ctx, task); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "failed to find task members", "task_id", task.ID.Hex(), "error", err)
+			return
+		}
+	}
+	f.publish(ctx, action, task, members)
+}
+
+// NotifyAssignees tells the assignees about a task they were given by actorID.
+// A zero actorID means nobody acted, so every assignee is notified.
+func (f *Feed) NotifyAssignees(ctx context.Context, task *models.Task, actorID primitive.ObjectID) {
+	ids, err := f.AssigneeIDs(ctx, task)
+	if err == nil {
+		err = f.notifier.TaskAssigned(ctx, task, ids, actorID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to notify task assignees", "task_id", task.ID.Hex(), "error", err)
+	}
+}
+
+// Created publishes a new task and notifies its assignees, looking them up once for both
+func (f *Feed) Created(ctx context.Context, task *models.Task, actorID primitive.ObjectID) {
+	logger := logging.FromContext(ctx)
+	assignees, err := f.AssigneeIDs(ctx, task)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to find task members", "task_id", task.ID.Hex(), "error", err)
+		return
+	}
+
+	var members []primitive.ObjectID
+	if task.IsGroupTask() && task.AssigneeType != models.TaskAssigneeEveryone {
+		members = assignees
+	}
+	f.publish(ctx, realtime.ActionCreated, task, members)
+
+	if err := f.notifier.TaskAssigned(ctx, task, assignees, actorID); err != nil {
+		logger.WarnContext(ctx, "failed to notify task assignees", "task_id", task.ID.Hex(), "error", err)
+	}
+}
+
+// publish sends a task change to its creator and assignees, and to members of its group
+func (f *Feed) publish(ctx context.Context, action realtime.Action, task *models.Task, members []primitive.ObjectID) {
+	if err := f.hub.Publish(ctx, realtime.TaskChange(action, task, members...)); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to publish task change", "task_id", task.ID.Hex(), "error", err)
+	}
+}
@@ -0,0 +1,83 @@
+package taskfeed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/realtime"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeUsers struct {
+	repositories.UserRepository
+	users  []*models.User
+	listed int
+}
+
+func (f *fakeUsers) List(ctx context.Context, filter models.UserFilter, query models.ListQuery) (*models.ListResult[*models.User], error) {
+	f.listed++
+	result := &models.ListResult[*models.User]{Items: []*models.User{}}
+	for _, u := range f.users {
+		if filter.Section == "" || u.Section == filter.Section {
+			result.Items = append(result.Items, u)
+		}
+	}
+	return result, nil
+}
+
+type fakeHub struct {
+	realtime.Hub
+	changes []realtime.Change
+}
+
+func (f *fakeHub) Publish(ctx context.Context, change realtime.Change) error {
+	f.changes = append(f.changes, change)
+	return nil
+}
+
+type fakeNotifier struct{ assignees []primitive.ObjectID }
+
+func (f *fakeNotifier) TaskAssigned(ctx context.Context, task *models.Task, assignees []primitive.ObjectID, actorID primitive.ObjectID) error {
+	f.assignees = assignees
+	return nil
+}
+
+func TestCreated(t *testing.T) {
+	ctx := context.Background()
+	trumpet := &models.User{ID: primitive.NewObjectID(), Section: "brass"}
+	snare := &models.User{ID: primitive.NewObjectID(), Section: "percussion"}
+	users := &fakeUsers{users: []*models.User{trumpet, snare}}
+	hub := &fakeHub{}
+	notifier := &fakeNotifier{}
+	feed := New(users, hub, notifier)
+
+	section := &models.Task{ID: primitive.NewObjectID(), AssigneeType: models.TaskAssigneeSection, AssignedSection: "brass"}
+	feed.Created(ctx, section, primitive.NilObjectID)
+	if users.listed != 1 {
+		t.Errorf("Expected the members to be listed once, got %d", users.listed)
+	}
+	if len(notifier.assignees) != 1 || notifier.assignees[0] != trumpet.ID {
+		t.Errorf("Expected the brass section to be notified, got %v", notifier.assignees)
+	}
+	if len(hub.changes) != 1 || !contains(hub.changes[0].Audience, trumpet.ID.Hex()) || contains(hub.changes[0].Audience, snare.ID.Hex()) {
+		t.Errorf("Expected the change to reach the brass section only, got %v", hub.changes)
+	}
+
+	// Tasks for everyone go to everyone without an audience
+	everyone := &models.Task{ID: primitive.NewObjectID(), AssigneeType: models.TaskAssigneeEveryone}
+	feed.Publish(ctx, realtime.ActionUpdated, everyone)
+	if len(hub.changes) != 2 || len(hub.changes[1].Audience) != 0 || users.listed != 1 {
+		t.Errorf("Expected an unrestricted change without listing members, got %v", hub.changes[1])
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
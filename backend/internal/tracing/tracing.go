@@ -0,0 +1,108 @@
+// Package tracing sets up OpenTelemetry tracing and traces repository calls
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans this package creates
+const instrumentationName = "github.com/kynmh69/futo-marching-dashboad/backend/internal/tracing"
+
+// Config configures trace export
+type Config struct {
+	// Enabled turns on tracing; spans are not recorded or exported otherwise
+	Enabled bool `yaml:"enabled"`
+	// Endpoint is the OTLP/HTTP traces URL, such as http://otel-collector:4318/v1/traces.
+	// When empty, the standard OTEL_EXPORTER_OTLP_* variables or the exporter default are used.
+	Endpoint string `yaml:"endpoint"`
+	// ServiceName identifies the backend in the tracing backend
+	ServiceName string `yaml:"serviceName"`
+	// SampleRatio is the fraction of new traces that are recorded, from 0 to 1.
+	// Requests that arrive with a sampled parent are always recorded.
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// Validate reports settings that cannot work
+func (c Config) Validate() error {
+	var errs []error
+	if c.ServiceName == "" {
+		errs = append(errs, errors.New("serviceName is required"))
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("sampleRatio must be between 0 and 1, got %v", c.SampleRatio))
+	}
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("endpoint must be an http or https URL, got %q", c.Endpoint))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Setup installs the global tracer provider and W3C trace context propagation.
+// It returns a function that flushes buffered spans and stops the exporter.
+// When tracing is disabled the global no-op provider is kept and shutdown does nothing.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// StartMongoOperation starts a span and a timer for a repository method.
+// Database calls made with the returned context appear as children of the span.
+// Call the returned function when the method finishes.
+func StartMongoOperation(ctx context.Context, repository, method string) (context.Context, func()) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, repository+"."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("repository", repository),
+			attribute.String("repository.method", method),
+		),
+	)
+	observe := metrics.ObserveMongo(repository, method)
+
+	return ctx, func() {
+		observe()
+		span.End()
+	}
+}
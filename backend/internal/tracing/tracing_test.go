@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartMongoOperation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "GET /api/admin/users")
+	opCtx, end := StartMongoOperation(ctx, "users", "List")
+	_, command := provider.Tracer("test").Start(opCtx, "users.aggregate")
+	command.End()
+	end()
+	request.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	op := spans[1]
+	if op.Name() != "users.List" {
+		t.Errorf("Expected span users.List, got %s", op.Name())
+	}
+	if op.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("Expected the repository span to be a child of the request span")
+	}
+	if spans[0].Parent().SpanID() != op.SpanContext().SpanID() {
+		t.Error("Expected database calls to be children of the repository span")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"defaults", Config{ServiceName: "backend", SampleRatio: 1}, true},
+		{"endpoint", Config{ServiceName: "backend", SampleRatio: 0.5, Endpoint: "https://collector.example.jp/v1/traces"}, true},
+		{"endpoint without scheme", Config{ServiceName: "backend", SampleRatio: 1, Endpoint: "collector:4318"}, false},
+		{"ratio above one", Config{ServiceName: "backend", SampleRatio: 2}, false},
+		{"no service name", Config{SampleRatio: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
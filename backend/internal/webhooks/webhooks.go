@@ -0,0 +1,282 @@
+// Package webhooks sends signed notifications of changes to the endpoints admins subscribe
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/metrics"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// signatureVersion prefixes signatures so the scheme can change without breaking receivers
+const signatureVersion = "v1="
+
+// Config configures delivery attempts
+type Config struct {
+	// MaxAttempts is how many times a delivery is tried before it is dead-lettered
+	MaxAttempts int `yaml:"maxAttempts"`
+	// BaseDelay is the wait after the first failed attempt; it doubles with every further failure
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	// MaxDelay caps the wait between attempts
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	// Timeout bounds a single attempt
+	Timeout time.Duration `yaml:"timeout"`
+	// PollInterval is how often due retries are looked for
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+// Validate checks that the settings are usable
+func (c Config) Validate() error {
+	var errs []error
+	if c.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("maxAttempts must be positive"))
+	}
+	if c.BaseDelay <= 0 || c.MaxDelay < c.BaseDelay {
+		errs = append(errs, errors.New("baseDelay must be positive and not exceed maxDelay"))
+	}
+	if c.Timeout <= 0 || c.PollInterval <= 0 {
+		errs = append(errs, errors.New("timeout and pollInterval must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// backoff returns the wait before the next attempt after failures failed attempts
+func (c Config) backoff(failures int) time.Duration {
+	delay := c.BaseDelay << (failures - 1)
+	if delay > c.MaxDelay || delay <= 0 {
+		delay = c.MaxDelay
+	}
+	return delay
+}
+
+// Envelope is the JSON body of every delivery
+type Envelope struct {
+	ID        string                  `json:"id"`
+	Type      models.WebhookEventType `json:"type"`
+	CreatedAt time.Time               `json:"createdAt"`
+	Data      interface{}             `json:"data"`
+}
+
+// Sign returns the signature of a delivery body sent at timestamp, a Unix time in seconds:
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature and that its timestamp is within tolerance of now,
+// which keeps captured deliveries from being replayed later
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature))
+}
+
+// Service records deliveries of changes to the matching subscriptions and sends them in the background
+type Service struct {
+	cfg           Config
+	subscriptions repositories.WebhookSubscriptionRepository
+	deliveries    repositories.WebhookDeliveryRepository
+	client        *http.Client
+	wake          chan struct{}
+	now           func() time.Time
+}
+
+// NewService creates a new Service
+func NewService(cfg Config, subscriptions repositories.WebhookSubscriptionRepository, deliveries repositories.WebhookDeliveryRepository) *Service {
+	return &Service{
+		cfg:           cfg,
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		client:        &http.Client{Timeout: cfg.Timeout},
+		wake:          make(chan struct{}, 1),
+		now:           time.Now,
+	}
+}
+
+// Emit queues a delivery of a change to every active subscription that receives its type
+func (s *Service) Emit(ctx context.Context, eventType models.WebhookEventType, data interface{}) error {
+	subscriptions, err := s.subscriptions.FindActiveByEvent(ctx, eventType)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	eventID := primitive.NewObjectID().Hex()
+	payload, err := json.Marshal(Envelope{ID: eventID, Type: eventType, CreatedAt: s.now(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, subscription := range subscriptions {
+		_, err := s.deliveries.Create(ctx, models.NewWebhookDelivery(subscription.ID, eventID, eventType, string(payload)))
+		errs = append(errs, err)
+	}
+	s.signal()
+	return errors.Join(errs...)
+}
+
+// Redeliver queues a new delivery with the same event and payload as an earlier one.
+// It returns nil if there is no such delivery.
+func (s *Service) Redeliver(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	original, err := s.deliveries.FindByID(ctx, id)
+	if err != nil || original == nil {
+		return nil, err
+	}
+
+	delivery := models.NewWebhookDelivery(original.SubscriptionID, original.EventID, original.EventType, original.Payload)
+	delivery.RedeliveryOf = &original.ID
+	if _, err := s.deliveries.Create(ctx, delivery); err != nil {
+		return nil, err
+	}
+	s.signal()
+	return delivery, nil
+}
+
+// signal wakes Run to send new deliveries without waiting for the next poll
+func (s *Service) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries until ctx is done. Start it with a worker.Group; replicas may all
+// run it since each delivery is locked by the replica attempting it.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// sendDue attempts deliveries until none is due
+func (s *Service) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := s.deliveries.ClaimDue(ctx, s.now(), 2*s.cfg.Timeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to claim webhook delivery", "error", err)
+			}
+			return
+		}
+		if delivery == nil {
+			return
+		}
+		s.attempt(ctx, delivery)
+	}
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a retry or dead-lettering it on failure
+func (s *Service) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	logger := slog.With("delivery_id", delivery.ID.Hex(), "subscription_id", delivery.SubscriptionID.Hex())
+
+	subscription, err := s.subscriptions.FindByID(ctx, delivery.SubscriptionID.Hex())
+	if err != nil {
+		// The lock expires and the delivery is tried again
+		logger.Error("failed to load webhook subscription", "error", err)
+		return
+	}
+
+	now := s.now()
+	attempt := models.WebhookAttempt{At: now}
+	status := models.WebhookDeliveryDead
+	next := now
+
+	if subscription == nil || !subscription.Active {
+		attempt.Error = "subscription was deleted or deactivated"
+	} else {
+		attempt.StatusCode, err = s.send(ctx, subscription, delivery, now)
+		attempt.DurationMs = s.now().Sub(now).Milliseconds()
+
+		switch {
+		case err == nil:
+			status = models.WebhookDeliverySucceeded
+		case len(delivery.Attempts)+1 < s.cfg.MaxAttempts:
+			attempt.Error = err.Error()
+			status = models.WebhookDeliveryPending
+			next = now.Add(s.cfg.backoff(len(delivery.Attempts) + 1))
+		default:
+			attempt.Error = err.Error()
+		}
+	}
+
+	metrics.WebhookDeliveries.WithLabelValues(string(status)).Inc()
+	if status == models.WebhookDeliveryDead {
+		logger.Warn("webhook delivery dead-lettered", "attempts", len(delivery.Attempts)+1, "error", attempt.Error)
+	}
+
+	if err := s.deliveries.RecordAttempt(ctx, delivery.ID, attempt, status, next); err != nil {
+		logger.Error("failed to record webhook attempt", "error", err)
+	}
+}
+
+// send posts a delivery to its subscription and returns the response status.
+// Any status other than 2xx is an error.
+func (s *Service) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := now.Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FUTO-Marching-Dashboard-Webhooks/1.0")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,208 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/models"
+	"github.com/kynmh69/futo-marching-dashboad/backend/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testConfig = Config{
+	MaxAttempts:  3,
+	BaseDelay:    time.Minute,
+	MaxDelay:     90 * time.Second,
+	Timeout:      time.Second,
+	PollInterval: time.Second,
+}
+
+type fakeSubscriptions struct {
+	repositories.WebhookSubscriptionRepository
+	subscriptions []*models.WebhookSubscription
+}
+
+func (f *fakeSubscriptions) FindByID(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	for _, s := range f.subscriptions {
+		if s.ID.Hex() == id {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeSubscriptions) FindActiveByEvent(ctx context.Context, eventType models.WebhookEventType) ([]*models.WebhookSubscription, error) {
+	var found []*models.WebhookSubscription
+	for _, s := range f.subscriptions {
+		for _, e := range s.Events {
+			if s.Active && e == eventType {
+				found = append(found, s)
+			}
+		}
+	}
+	return found, nil
+}
+
+// fakeDeliveries hands out due deliveries in creation order and applies recorded attempts
+type fakeDeliveries struct {
+	repositories.WebhookDeliveryRepository
+	deliveries []*models.WebhookDelivery
+}
+
+func (f *fakeDeliveries) Create(ctx context.Context, d *models.WebhookDelivery) (string, error) {
+	d.ID = primitive.NewObjectID()
+	f.deliveries = append(f.deliveries, d)
+	return d.ID.Hex(), nil
+}
+
+func (f *fakeDeliveries) FindByID(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	for _, d := range f.deliveries {
+		if d.ID.Hex() == id {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDeliveries) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error) {
+	for _, d := range f.deliveries {
+		if d.Status == models.WebhookDeliveryPending && !d.NextAttemptAt.After(now) && !d.LockedUntil.After(now) {
+			d.LockedUntil = now.Add(lease)
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDeliveries) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt models.WebhookAttempt, status models.WebhookDeliveryStatus, next time.Time) error {
+	d, _ := f.FindByID(ctx, id.Hex())
+	d.Attempts = append(d.Attempts, attempt)
+	d.Status = status
+	d.NextAttemptAt = next
+	d.LockedUntil = time.Time{}
+	return nil
+}
+
+func newTestService(url string) (*Service, *fakeDeliveries, *time.Time) {
+	// Deliveries are created due at the wall clock time, so the fake clock starts just after it
+	now := time.Now().Add(time.Second)
+	subscription := &models.WebhookSubscription{
+		ID: primitive.NewObjectID(), URL: url, Secret: "whsec_test", Active: true,
+		Events: []models.WebhookEventType{models.WebhookEventCreated},
+	}
+	deliveries := &fakeDeliveries{}
+	s := NewService(testConfig, &fakeSubscriptions{subscriptions: []*models.WebhookSubscription{subscription}}, deliveries)
+	s.now = func() time.Time { return now }
+	return s, deliveries, &now
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1748772000, 0)
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("whsec_test", now.Unix(), body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	if !Verify("whsec_test", timestamp, signature, body, 5*time.Minute, now.Add(time.Minute)) {
+		t.Error("Expected a fresh signature to verify")
+	}
+	if Verify("whsec_other", timestamp, signature, body, 5*time.Minute, now) {
+		t.Error("Expected another secret to be rejected")
+	}
+	if Verify("whsec_test", timestamp, signature, []byte(`{"id":"2"}`), 5*time.Minute, now) {
+		t.Error("Expected a changed body to be rejected")
+	}
+	if Verify("whsec_test", timestamp, signature, body, 5*time.Minute, now.Add(10*time.Minute)) {
+		t.Error("Expected an old timestamp to be rejected")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{time.Minute, 90 * time.Second, 90 * time.Second}
+	for i, delay := range want {
+		if got := testConfig.backoff(i + 1); got != delay {
+			t.Errorf("Failure %d: expected %s, got %s", i+1, delay, got)
+		}
+	}
+	// Shifting past the width of a Duration must not wrap around
+	if got := testConfig.backoff(80); got != testConfig.MaxDelay {
+		t.Errorf("Expected the cap for many failures, got %s", got)
+	}
+}
+
+func TestDeliveryIsSignedAndRetried(t *testing.T) {
+	status := http.StatusInternalServerError
+	var received []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	s, deliveries, now := newTestService(server.URL)
+
+	if err := s.Emit(ctx, models.WebhookEventCreated, map[string]string{"title": "Rehearsal"}); err != nil {
+		t.Fatalf("Error emitting: %v", err)
+	}
+	if err := s.Emit(ctx, models.WebhookEventDeleted, nil); err != nil {
+		t.Fatalf("Error emitting: %v", err)
+	}
+	if len(deliveries.deliveries) != 1 {
+		t.Fatalf("Expected one delivery for the subscribed type, got %d", len(deliveries.deliveries))
+	}
+	delivery := deliveries.deliveries[0]
+
+	s.sendDue(ctx)
+	if len(received) != 1 {
+		t.Fatalf("Expected one request, got %d", len(received))
+	}
+	req := received[0]
+	if !Verify("whsec_test", req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), bodies[0], time.Minute, *now) {
+		t.Error("Expected the request to carry a valid signature")
+	}
+	if req.Header.Get(HeaderEvent) != "event.created" || req.Header.Get(HeaderEventID) != delivery.EventID {
+		t.Errorf("Unexpected headers %v", req.Header)
+	}
+	if delivery.Status != models.WebhookDeliveryPending || !delivery.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected a retry in a minute, got %s at %s", delivery.Status, delivery.NextAttemptAt)
+	}
+
+	// Nothing is sent before the retry is due
+	s.sendDue(ctx)
+	if len(received) != 1 {
+		t.Errorf("Expected no request before the backoff, got %d", len(received))
+	}
+
+	*now = now.Add(time.Minute)
+	s.sendDue(ctx)
+	*now = now.Add(90 * time.Second)
+	s.sendDue(ctx)
+	if delivery.Status != models.WebhookDeliveryDead || len(delivery.Attempts) != testConfig.MaxAttempts {
+		t.Fatalf("Expected the delivery to be dead after %d attempts, got %s after %d", testConfig.MaxAttempts, delivery.Status, len(delivery.Attempts))
+	}
+	if delivery.Attempts[0].StatusCode != http.StatusInternalServerError || delivery.Attempts[0].Error == "" {
+		t.Errorf("Expected the failure to be logged, got %+v", delivery.Attempts[0])
+	}
+
+	// A manual redelivery repeats the event and can succeed
+	status = http.StatusNoContent
+	redelivery, err := s.Redeliver(ctx, delivery.ID.Hex())
+	if err != nil || redelivery == nil {
+		t.Fatalf("Error redelivering: %v", err)
+	}
+	s.sendDue(ctx)
+	if redelivery.Status != models.WebhookDeliverySucceeded || redelivery.EventID != delivery.EventID || *redelivery.RedeliveryOf != delivery.ID {
+		t.Errorf("Unexpected redelivery %+v", redelivery)
+	}
+	if string(bodies[len(bodies)-1]) != string(bodies[0]) {
+		t.Error("Expected the redelivery to send the original payload")
+	}
+}
@@ -0,0 +1,52 @@
+// Package worker runs background jobs that stop together on shutdown
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// Group runs background workers with a shared context
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewGroup creates a Group whose workers run until Stop is called or parent is done
+func NewGroup(parent context.Context) *Group {
+	ctx, cancel := context.WithCancel(parent)
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Go starts a worker. fn must return once its context is done.
+// Errors other than cancellation are logged with the worker's name.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(g.ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.Error("worker stopped", "worker", name, "error", err)
+		}
+	}()
+}
+
+// Stop cancels all workers and waits for them to return, or for ctx to be done
+func (g *Group) Stop(ctx context.Context) error {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupStopWaitsForWorkers(t *testing.T) {
+	g := NewGroup(context.Background())
+
+	stopped := make(chan struct{})
+	g.Go("drain", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		close(stopped)
+		return ctx.Err()
+	})
+
+	if err := g.Stop(context.Background()); err != nil {
+		t.Fatalf("Error stopping workers: %v", err)
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected Stop to wait for the worker to return")
+	}
+}
+
+func TestGroupStopTimesOut(t *testing.T) {
+	g := NewGroup(context.Background())
+
+	release := make(chan struct{})
+	defer close(release)
+	g.Go("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := g.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}